@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// Run outcomes
+const (
+	OutcomeCommitted  = "committed"
+	OutcomeRolledBack = "rolled_back"
+	OutcomePartial    = "partial"
+	OutcomeFailed     = "failed"
+)
+
+// Recorder collects the audit record of one run that modifies data
+type Recorder struct {
+	record  models.AuditRecord
+	started time.Time
+}
+
+// NewRecorder starts the audit record of a run, noting who runs it, where, and with which
+// command line
+func NewRecorder(command, connection, database, action string) *Recorder {
+	started := time.Now().UTC()
+	host, _ := os.Hostname()
+	return &Recorder{
+		started: started,
+		record: models.AuditRecord{
+			StartedAt:   started.Format(time.RFC3339),
+			User:        currentUser(),
+			Host:        host,
+			Connection:  connection,
+			Database:    database,
+			Command:     command,
+			CommandLine: strings.Join(os.Args, " "),
+			Action:      action,
+		},
+	}
+}
+
+// currentUser returns the name of the operating system user running the tool
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// SetRunID records the ID of the run
+func (r *Recorder) SetRunID(runID string) {
+	r.record.RunID = runID
+}
+
+// SetPlanID records the run ID of the fix plan the run applies
+func (r *Recorder) SetPlanID(planID string) {
+	r.record.PlanID = planID
+}
+
+// AddStatement records an executed statement
+func (r *Recorder) AddStatement(stmt models.AuditStatement) {
+	r.record.Statements = append(r.record.Statements, stmt)
+}
+
+// Finish completes the record with the outcome of the run and the number of rows it changed
+func (r *Recorder) Finish(outcome string, rowsAffected int64, err error) *models.AuditRecord {
+	finished := time.Now().UTC()
+	r.record.FinishedAt = finished.Format(time.RFC3339)
+	r.record.DurationMs = finished.Sub(r.started).Milliseconds()
+	r.record.Outcome = outcome
+	r.record.RowsAffected = rowsAffected
+	if err != nil {
+		r.record.Error = err.Error()
+	}
+	return &r.record
+}
+
+// FixOutcome derives the outcome of a fix run from its results
+func FixOutcome(results models.FixResults, err error) (string, int64) {
+	var rowsAffected int64
+	committed, rolledBack := false, false
+	for _, result := range results {
+		if result.RolledBack {
+			rolledBack = true
+			continue
+		}
+		rowsAffected += int64(result.RecordsAffected)
+		if result.RecordsAffected > 0 {
+			committed = true
+			if !result.Success {
+				// The table's fix failed after committing some batches
+				rolledBack = true
+			}
+		}
+	}
+
+	switch {
+	case rolledBack && committed:
+		return OutcomePartial, rowsAffected
+	case rolledBack:
+		return OutcomeRolledBack, rowsAffected
+	case err != nil:
+		return OutcomeFailed, rowsAffected
+	default:
+		return OutcomeCommitted, rowsAffected
+	}
+}
+
+// Append adds a record to the JSONL audit file, creating the file and its directory if
+// needed
+func Append(filePath string, record *models.AuditRecord) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return file.Sync()
+}
+
+// ReadRecords reads every record of a JSONL audit file, oldest first. A missing file has
+// no records.
+func ReadRecords(filePath string) ([]models.AuditRecord, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer file.Close()
+
+	var records []models.AuditRecord
+	scanner := bufio.NewScanner(file)
+	// Records carry their SQL and can be long
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var record models.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse audit file line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
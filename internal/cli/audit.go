@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nkamuo/go-db-migration/internal/audit"
+	"github.com/nkamuo/go-db-migration/internal/config"
+	"github.com/nkamuo/go-db-migration/internal/database"
+	"github.com/nkamuo/go-db-migration/internal/models"
+	"github.com/nkamuo/go-db-migration/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// History command options
+var (
+	historyLimit     int
+	historyFromTable bool
+)
+
+// startAudit begins the audit record of a run that modifies data
+func startAudit(command, action string, dbConfig *config.DBConfig) *audit.Recorder {
+	return audit.NewRecorder(command, connectionName, dbConfig.Database, action)
+}
+
+// saveAudit appends an audit record to the audit file and, if configured, to the audit
+// table of the target database
+func saveAudit(cfg *config.Config, db *database.DB, record *models.AuditRecord) error {
+	auditConfig := cfg.GetAuditConfig()
+	if err := audit.Append(auditConfig.File, record); err != nil {
+		return newInternalError("failed to write audit log", err)
+	}
+	if auditConfig.Table != "" {
+		if err := db.SaveAuditRecord(auditConfig.Table, record); err != nil {
+			return newInternalError("failed to write audit table", err)
+		}
+	}
+	return nil
+}
+
+// startFixAudit begins the audit record of a fix that modifies data and has opts report
+// every executed statement to it. Dry runs, plans and SQL scripts change nothing and are
+// not audited.
+func startFixAudit(command, action string, dbConfig *config.DBConfig, opts *database.FixOptions) *audit.Recorder {
+	if opts.DryRun || opts.Script != nil || opts.Plan != nil {
+		return nil
+	}
+	recorder := startAudit(command, action, dbConfig)
+	recorder.SetRunID(opts.RunID)
+	recorder.SetPlanID(opts.PlanID)
+	opts.Audit = recorder.AddStatement
+	return recorder
+}
+
+// finishFixAudit records the outcome of a fix run in the audit log, if it is audited, and
+// returns resultErr
+func finishFixAudit(cfg *config.Config, db *database.DB, recorder *audit.Recorder, results models.FixResults, fixErr, resultErr error) error {
+	outcome, rowsAffected := audit.FixOutcome(results, fixErr)
+	return finishAudit(cfg, db, recorder, outcome, rowsAffected, fixErr, resultErr)
+}
+
+// finishAudit records the outcome of a run in the audit log, if it is audited, and returns
+// resultErr. The result error takes precedence over a failure to write the audit log.
+func finishAudit(cfg *config.Config, db *database.DB, recorder *audit.Recorder, outcome string, rowsAffected int64, runErr, resultErr error) error {
+	if recorder == nil {
+		return resultErr
+	}
+	auditErr := saveAudit(cfg, db, recorder.Finish(outcome, rowsAffected, runErr))
+	if resultErr != nil {
+		if auditErr != nil {
+			fmt.Printf("⚠️  %v\n", auditErr)
+		}
+		return resultErr
+	}
+	return auditErr
+}
+
+// newFixHistoryCmd creates the fix history command
+func newFixHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history [run-id]",
+		Short: "Browse the audit log of runs that modified data",
+		Long: `Lists the runs recorded in the audit log, newest first: who ran them, from
+which host, against which connection, how many rows they changed, how long they
+took and whether they were committed. With a run ID, shows that run in detail,
+including every SQL statement it executed.
+
+Runs are read from the audit file (audit.file in the configuration, by default
+.migrator/audit.jsonl), or with --from-table from the audit table (audit.table)
+of the --connection database.
+
+Examples:
+  migrator fix history
+  migrator fix history --limit 50 --format json
+  migrator fix history 20240101T120000Z-a1b2c3`,
+		Args: cobra.MaximumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			cfg, err := getConfigFromCmd(cmd)
+			if err != nil {
+				return newConfigError("failed to load configuration", err)
+			}
+			auditConfig := cfg.GetAuditConfig()
+
+			var records []models.AuditRecord
+			if historyFromTable {
+				if auditConfig.Table == "" {
+					return newConfigError("--from-table requires audit.table in the configuration", nil)
+				}
+				dbConfig, err := cfg.GetConnectionConfig(connectionName)
+				if err != nil {
+					return newConfigError("failed to get connection config", err)
+				}
+				db, err := database.NewConnection(dbConfig)
+				if err != nil {
+					return newConnectionError("failed to connect to database", err)
+				}
+				defer db.Close()
+
+				records, err = db.ListAuditRecords(auditConfig.Table)
+				if err != nil {
+					return newInternalError("failed to read audit table", err)
+				}
+			} else {
+				records, err = audit.ReadRecords(auditConfig.File)
+				if err != nil {
+					return newInternalError("failed to read audit log", err)
+				}
+			}
+
+			formatter := output.NewFormatter(outputFormat)
+			if len(args) == 1 {
+				for i := len(records) - 1; i >= 0; i-- {
+					if records[i].RunID == args[0] {
+						content, err := formatter.FormatAuditRecord(&records[i])
+						if err != nil {
+							return newConfigError("failed to format audit record", err)
+						}
+						return saveOutput(content, cmd)
+					}
+				}
+				return newConfigError(fmt.Sprintf("run %s not found in the audit log", args[0]), nil)
+			}
+
+			// Newest first
+			sort.SliceStable(records, func(i, j int) bool { return records[i].StartedAt > records[j].StartedAt })
+			if historyLimit > 0 && len(records) > historyLimit {
+				records = records[:historyLimit]
+			}
+
+			content, err := formatter.FormatAuditRecords(records)
+			if err != nil {
+				return newConfigError("failed to format audit records", err)
+			}
+			return saveOutput(content, cmd)
+		},
+	}
+
+	cmd.Flags().IntVar(&historyLimit, "limit", 20, "Show at most this many runs (0 = all)")
+	cmd.Flags().BoolVar(&historyFromTable, "from-table", false, "Read the audit table of the target database instead of the audit file")
+
+	return cmd
+}
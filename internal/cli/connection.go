@@ -62,7 +62,7 @@ basic connectivity checks.`,
 			db, err := database.NewConnection(dbConfig)
 			if err != nil {
 				fmt.Printf("❌ Connection failed: %v\n", err)
-				return fmt.Errorf("connection test failed")
+				return newConnectionError("connection test failed", nil)
 			}
 			defer db.Close()
 
@@ -70,7 +70,7 @@ basic connectivity checks.`,
 			err = db.Ping()
 			if err != nil {
 				fmt.Printf("❌ Connection ping failed: %v\n", err)
-				return fmt.Errorf("connection test failed")
+				return newConnectionError("connection test failed", nil)
 			}
 
 			fmt.Printf("✅ Connection successful!\n")
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/nkamuo/go-db-migration/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// Process exit codes returned by the migrator binary
+const (
+	ExitOK              = 0 // Command completed and no issues crossed the --fail-on threshold
+	ExitIssuesFound     = 1 // Validation issues at or above the --fail-on threshold were found
+	ExitConfigError     = 2 // Configuration, schema file or command line error
+	ExitConnectionError = 3 // The database could not be reached
+	ExitInternalError   = 4 // Any other failure (query errors, formatting errors, ...)
+)
+
+// Error kinds used in the JSON error envelope
+const (
+	ErrorKindIssuesFound = "issues_found"
+	ErrorKindConfig      = "configuration_error"
+	ErrorKindConnection  = "connection_error"
+	ErrorKindInternal    = "internal_error"
+)
+
+// CommandError is an error that carries the process exit code it maps to
+type CommandError struct {
+	Code    int
+	Kind    string
+	Message string
+	Err     error
+	Details map[string]interface{}
+}
+
+// Error implements the error interface
+func (e *CommandError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorEnvelope is the machine-readable error document written when --format json or
+// ndjson is used. In NDJSON output it is a record of its own.
+type ErrorEnvelope struct {
+	Record string    `json:"record,omitempty"`
+	Error  ErrorBody `json:"error"`
+}
+
+// ErrorBody describes a command failure inside an ErrorEnvelope
+type ErrorBody struct {
+	Kind     string                 `json:"kind"`
+	ExitCode int                    `json:"exit_code"`
+	Message  string                 `json:"message"`
+	Cause    string                 `json:"cause,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+}
+
+// newConfigError creates a configuration error
+func newConfigError(message string, err error) *CommandError {
+	return &CommandError{Code: ExitConfigError, Kind: ErrorKindConfig, Message: message, Err: err}
+}
+
+// newConnectionError creates a database connection error
+func newConnectionError(message string, err error) *CommandError {
+	return &CommandError{Code: ExitConnectionError, Kind: ErrorKindConnection, Message: message, Err: err}
+}
+
+// newInternalError creates an internal error
+func newInternalError(message string, err error) *CommandError {
+	return &CommandError{Code: ExitInternalError, Kind: ErrorKindInternal, Message: message, Err: err}
+}
+
+// newIssuesFoundError creates the error returned when validation issues cross the --fail-on threshold
+func newIssuesFoundError(message string) *CommandError {
+	return &CommandError{Code: ExitIssuesFound, Kind: ErrorKindIssuesFound, Message: message}
+}
+
+// exitCodeForError maps an error returned by a command to a process exit code
+func exitCodeForError(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code
+	}
+
+	return ExitInternalError
+}
+
+// isJSONOutput reports whether machine-readable JSON output was requested
+func isJSONOutput() bool {
+	return outputFormat == "json" || outputFormat == "ndjson"
+}
+
+// failCommand reports a command failure and returns it for cobra to propagate.
+// Human-readable diagnostics are expected to be printed by the caller; in JSON
+// mode a structured error envelope is written instead.
+func failCommand(cmd *cobra.Command, cmdErr *CommandError) error {
+	// The diagnostics (or the envelope) already describe the failure
+	cmd.SilenceErrors = true
+
+	if isJSONOutput() {
+		envelope := ErrorEnvelope{
+			Error: ErrorBody{
+				Kind:     cmdErr.Kind,
+				ExitCode: cmdErr.Code,
+				Message:  cmdErr.Message,
+				Details:  cmdErr.Details,
+			},
+		}
+		if cmdErr.Err != nil {
+			envelope.Error.Cause = cmdErr.Err.Error()
+		}
+
+		if outputFormat == "ndjson" {
+			// The error ends the stream of issues, on a line of its own
+			envelope.Record = output.NDJSONRecordError
+			if issueStream != nil {
+				if err := issueStream.WriteRecord(envelope); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", cmdErr)
+				}
+				return cmdErr
+			}
+			data, err := json.Marshal(envelope)
+			if err == nil {
+				err = saveOutput(string(data)+"\n", cmd)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", cmdErr)
+			}
+			return cmdErr
+		}
+
+		data, err := json.MarshalIndent(envelope, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", cmdErr)
+			return cmdErr
+		}
+		if err := saveOutput(string(data)+"\n", cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", cmdErr)
+		}
+	}
+
+	return cmdErr
+}
@@ -1,9 +1,18 @@
 package cli
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/nkamuo/go-db-migration/internal/audit"
+	"github.com/nkamuo/go-db-migration/internal/config"
 	"github.com/nkamuo/go-db-migration/internal/database"
+	"github.com/nkamuo/go-db-migration/internal/models"
 	"github.com/nkamuo/go-db-migration/internal/schema"
 	"github.com/spf13/cobra"
 )
@@ -14,6 +23,19 @@ var (
 	fixAction      string
 	defaultValue   string
 	confirmChanges bool
+	atomicCommit   bool
+	perTableCommit bool
+	backupDir      string
+	noBackup       bool
+	batchSize      int
+	batchSleep     time.Duration
+	maxReplLag     time.Duration
+	emitSQLFile    string
+	parentDefaults map[string]string
+	reassignTo     string
+	mappingFile    string
+	fkConstraint   string
+	fixPlanFile    string
 )
 
 // newFixCmd creates the fix command group
@@ -26,85 +48,337 @@ successful migration. This includes cleaning up foreign key violations and
 null value issues.
 
 ⚠️  WARNING: These commands modify your database. Always run with --dry-run first
-and backup your data before running actual fixes.`,
+and backup your data before running actual fixes.
+
+Changes run inside transactions with a savepoint per constraint. With --atomic
+(the default) the whole invocation is one transaction and any failure undoes
+every change; with --per-table each table is committed on its own and a failure
+only undoes that table. Undone statements are listed in the results.
+
+Before rows are changed they are copied to JSONL files under --backup-dir,
+together with a manifest of the run. Use 'fix undo <run-id>' to restore them.
+
+For large tables use --batch-size to split every fix into batches that walk the
+primary key and commit one at a time, optionally pausing between batches
+(--batch-sleep) and while replicas lag behind (--max-replication-lag).
+
+With --emit-sql nothing is executed: the statements are written to a SQL script
+for review, in dependency order, in one transaction, with inlined values and a
+row count assertion per statement.
+
+'fix plan fk|null -o plan.json' records the operations and the rows they change
+for review; 'fix apply plan.json' executes exactly those operations unless the
+database has drifted from the plan.
+
+Every run that changes data is recorded in the audit log (audit.file and
+optionally audit.table in the configuration); browse it with 'fix history'.`,
 	}
 
 	cmd.AddCommand(newFixFKCmd())
 	cmd.AddCommand(newFixNullCmd())
+	cmd.AddCommand(newFixUndoCmd())
+	cmd.AddCommand(newFixPlanCmd())
+	cmd.AddCommand(newFixApplyCmd())
+	cmd.AddCommand(newFixHistoryCmd())
 
 	// Add persistent flags
 	cmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Show what would be changed without making actual changes")
 	cmd.PersistentFlags().BoolVar(&confirmChanges, "confirm", false, "Confirm that you want to make actual changes (required for non-dry-run)")
+	cmd.PersistentFlags().BoolVar(&atomicCommit, "atomic", false, "Apply all fixes in a single transaction (default)")
+	cmd.PersistentFlags().BoolVar(&perTableCommit, "per-table", false, "Commit fixes table by table")
+	cmd.MarkFlagsMutuallyExclusive("atomic", "per-table")
+	cmd.PersistentFlags().StringVar(&backupDir, "backup-dir", database.DefaultBackupDir, "Directory for row backups and fix run manifests")
+	cmd.PersistentFlags().BoolVar(&noBackup, "no-backup", false, "Do not back up rows before changing them (fix undo will not be possible)")
+	cmd.PersistentFlags().IntVar(&batchSize, "batch-size", 0, "Fix at most this many rows per batch, committing each batch (0 = single statement)")
+	cmd.PersistentFlags().DurationVar(&batchSleep, "batch-sleep", 0, "Pause between batches (e.g. 500ms)")
+	cmd.PersistentFlags().DurationVar(&maxReplLag, "max-replication-lag", 0, "Pause batching while replicas lag behind by more than this (e.g. 10s)")
+	cmd.PersistentFlags().StringVar(&emitSQLFile, "emit-sql", "", "Write the fix statements to this SQL script instead of executing them")
 
 	return cmd
 }
 
+// getFixOptionsFromFlags builds fix options from the command line flags
+func getFixOptionsFromFlags() database.FixOptions {
+	opts := database.FixOptions{
+		DryRun:         dryRun,
+		CommitPolicy:   database.CommitAtomic,
+		ConnectionName: connectionName,
+		BackupDir:      backupDir,
+		NoBackup:       noBackup,
+	}
+	if perTableCommit {
+		opts.CommitPolicy = database.CommitPerTable
+	}
+	if batchSize > 0 {
+		opts.CommitPolicy = database.CommitPerBatch
+		opts.BatchSize = batchSize
+		opts.BatchSleep = batchSleep
+		opts.MaxReplicationLag = maxReplLag
+		opts.Progress = printFixProgress
+	}
+	return opts
+}
+
+// resolveFixDryRun decides between a dry run and applying changes. Without --dry-run or
+// --confirm, fixes default to a dry run for safety; writing a SQL script or a plan executes
+// nothing and needs neither.
+func resolveFixDryRun(cmd *cobra.Command) error {
+	if isFixPlanCmd(cmd) {
+		if cmd.Flags().Changed("dry-run") || emitSQLFile != "" {
+			return newConfigError("fix plan only records the fix and cannot be combined with --dry-run or --emit-sql", nil)
+		}
+		if outputFile == "" {
+			return newConfigError("fix plan requires --output (-o) for the plan file", nil)
+		}
+		dryRun = false
+		return nil
+	}
+
+	if emitSQLFile != "" {
+		if cmd.Flags().Changed("dry-run") {
+			return newConfigError("--emit-sql writes the statements without executing them and cannot be combined with --dry-run", nil)
+		}
+		dryRun = false
+		return nil
+	}
+
+	// Handle dry-run defaults: if neither --dry-run nor --confirm is explicitly set,
+	// default to dry-run for safety
+	if !cmd.Flags().Changed("dry-run") && !cmd.Flags().Changed("confirm") {
+		dryRun = true
+	}
+
+	// If --confirm is set, disable dry-run (unless --dry-run is explicitly set)
+	if confirmChanges && !cmd.Flags().Changed("dry-run") {
+		dryRun = false
+	}
+
+	if !dryRun && !confirmChanges {
+		return newConfigError("must use --confirm flag when not in dry-run mode", nil)
+	}
+	return nil
+}
+
+// validateFixFlags checks flag combinations shared by the fix commands
+func validateFixFlags() error {
+	if batchSize < 0 {
+		return newConfigError("--batch-size must not be negative", nil)
+	}
+	if batchSize > 0 && (atomicCommit || perTableCommit) {
+		return newConfigError("--batch-size commits every batch and cannot be combined with --atomic or --per-table", nil)
+	}
+	if batchSize == 0 && (batchSleep > 0 || maxReplLag > 0) {
+		return newConfigError("--batch-sleep and --max-replication-lag require --batch-size", nil)
+	}
+	if emitSQLFile != "" && batchSize > 0 {
+		return newConfigError("--emit-sql writes a single transaction and cannot be combined with --batch-size", nil)
+	}
+	return nil
+}
+
+// printFixProgress prints the progress of a batched fix
+func printFixProgress(p database.FixProgress) {
+	switch {
+	case p.Message != "":
+		fmt.Printf("   ℹ️  %s: %s\n", p.Description, p.Message)
+	case p.ReplicationLag > 0:
+		fmt.Printf("   💤 %s: replication lag %s exceeds %s, waiting...\n", p.Table, p.ReplicationLag.Round(time.Millisecond), maxReplLag)
+	case p.RowsTotal > 0:
+		fmt.Printf("   ⏳ %s: batch %d, %d/%d rows (%.1f%%)\n", p.Table, p.Batch, p.RowsDone, p.RowsTotal, float64(p.RowsDone)*100/float64(p.RowsTotal))
+	default:
+		fmt.Printf("   ⏳ %s: batch %d, %d rows\n", p.Table, p.Batch, p.RowsDone)
+	}
+}
+
+// describeCascadeImpact summarizes the rows of one table affected by a fix's referential actions
+func describeCascadeImpact(impact models.CascadeImpact) string {
+	var parts []string
+	if impact.Deleted > 0 {
+		parts = append(parts, fmt.Sprintf("%d deleted", impact.Deleted))
+	}
+	if impact.Nulled > 0 {
+		parts = append(parts, fmt.Sprintf("%d nulled", impact.Nulled))
+	}
+	if impact.Updated > 0 {
+		parts = append(parts, fmt.Sprintf("%d updated", impact.Updated))
+	}
+	if impact.Blocked > 0 {
+		parts = append(parts, fmt.Sprintf("%d blocked by %s", impact.Blocked, strings.Join(impact.BlockedBy, ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printFixResults displays fix results and returns an error when changes were rolled back
+func printFixResults(results models.FixResults, opts database.FixOptions) error {
+	rolledBack := false
+	partial := false
+	changed := false
+	blocked := false
+
+	fmt.Printf("\n📊 Fix Results:\n")
+	for tableName, result := range results {
+		if result.RecordsAffected > 0 || result.RolledBack {
+			changed = true
+		}
+		fmt.Printf("  Table: %s\n", tableName)
+		fmt.Printf("    Issues found: %d\n", result.IssuesFound)
+		fmt.Printf("    Records affected: %d\n", result.RecordsAffected)
+		if !dryRun && opts.Script == nil && opts.Plan == nil {
+			fmt.Printf("    Changes applied: %v\n", result.Success)
+		}
+		if result.Error != "" {
+			fmt.Printf("    ❌ Error: %s\n", result.Error)
+		}
+		if len(result.Impact) > 0 {
+			fmt.Printf("    Cascade impact:\n")
+			for _, impact := range result.Impact {
+				fmt.Printf("      • %s: %s\n", impact.Table, describeCascadeImpact(impact))
+				if impact.Blocked > 0 {
+					blocked = true
+				}
+			}
+		}
+		if result.RolledBack {
+			rolledBack = true
+			fmt.Printf("    ↩️  Rolled back\n")
+			for _, stmt := range result.UndoneStatements {
+				fmt.Printf("       • undone: %s\n", stmt)
+			}
+		} else if result.Error != "" && result.RecordsAffected > 0 && !dryRun && opts.Script == nil && opts.Plan == nil {
+			partial = true
+			fmt.Printf("    ⚠️  Failed after committing %d rows, which stay applied\n", result.RecordsAffected)
+		}
+	}
+
+	if opts.Plan != nil {
+		fmt.Printf("\n📋 Plan with %d operations written to %s; nothing was executed\n", len(opts.Plan.Operations()), outputFile)
+		if rolledBack {
+			return newInternalError("some fixes could not be planned", nil)
+		}
+		fmt.Printf("💡 After review, run: migrator fix apply %s --confirm\n", outputFile)
+		return nil
+	}
+
+	if opts.Script != nil {
+		if err := os.WriteFile(emitSQLFile, []byte(opts.Script.String()), 0644); err != nil {
+			return newInternalError("failed to write SQL script", err)
+		}
+		fmt.Printf("\n📝 SQL script with %d statements written to %s; nothing was executed\n", opts.Script.Len(), emitSQLFile)
+		if rolledBack {
+			return newInternalError("some fixes could not be scripted", nil)
+		}
+		return nil
+	}
+
+	if blocked {
+		fmt.Printf("\n⚠️  Rows in other tables reference rows this fix changes through RESTRICT / NO ACTION\n")
+		fmt.Printf("   foreign keys; the fix will fail unless those rows are dealt with first\n")
+	}
+
+	if rolledBack {
+		fmt.Printf("\n❌ Fix failed; the changes listed above were rolled back\n")
+	} else if partial {
+		fmt.Printf("\n❌ Fix failed; the rows committed before the failure stay applied\n")
+	} else if dryRun {
+		fmt.Printf("\n💡 To apply these changes, run with --confirm flag and without --dry-run\n")
+	} else {
+		fmt.Printf("\n✅ Fix operation completed!\n")
+	}
+
+	if !dryRun && !opts.NoBackup && changed {
+		fmt.Printf("🗄️  Run ID: %s (backups in %s)\n", opts.RunID, opts.BackupDir)
+		fmt.Printf("💡 To revert the committed changes, run: migrator fix undo %s --confirm\n", opts.RunID)
+	}
+	if !dryRun && fixAction == "quarantine" && changed {
+		fmt.Printf("💡 To review the quarantined rows, run: migrator quarantine list --run-id %s\n", opts.RunID)
+	}
+
+	if rolledBack {
+		return newInternalError("fix failed and changes were rolled back", nil)
+	}
+	if partial {
+		return newInternalError("fix failed after committing some batches", nil)
+	}
+	return nil
+}
+
 // newFixFKCmd creates the fix foreign key command
 func newFixFKCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "fk",
 		Short: "Fix foreign key constraint violations",
-		Long: `Fixes foreign key constraint violations by either removing orphaned records
-or setting foreign key columns to NULL.
+		Long: `Fixes foreign key constraint violations by removing orphaned records, setting
+foreign key columns to NULL, creating the missing parent rows or pointing the
+orphans at existing parents.
 
 Available actions:
   - remove: Delete records that violate foreign key constraints
   - set-null: Set foreign key columns to NULL for violating records
+  - quarantine: Move violating records into <table>__quarantine for review
+    (see 'migrator quarantine')
+  - create-parent: Insert a stub row into the referenced table for each missing
+    key value; other columns are filled from --parent-defaults
+  - reassign: Point orphaned records at an existing parent (--reassign-to) or at
+    the parents given by a value mapping file (--mapping-file, JSON object or CSV
+    with old,new columns). Requires --constraint.
 
 Examples:
   migrator fix fk --action remove --dry-run
-  migrator fix fk --action set-null --confirm`,
+  migrator fix fk --action set-null --confirm
+  migrator fix fk --action quarantine --confirm
+  migrator fix fk --action create-parent --parent-defaults name=Unknown,active=false --confirm
+  migrator fix fk --action reassign --constraint fk_orders_customer --reassign-to 1 --confirm
+  migrator fix fk --action reassign --constraint fk_orders_customer --mapping-file customers.csv --dry-run`,
 		Aliases: []string{"foreign-key", "foreign-keys"},
 
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cmd.SilenceUsage = true
 
 			if fixAction == "" {
-				return fmt.Errorf("--action is required (remove|set-null)")
+				return newConfigError("--action is required (remove|set-null|quarantine|create-parent|reassign)", nil)
 			}
 
-			if fixAction != "remove" && fixAction != "set-null" {
-				return fmt.Errorf("invalid action: %s (must be 'remove' or 'set-null')", fixAction)
+			fkFix, err := getForeignKeyFixFromFlags()
+			if err != nil {
+				return err
 			}
 
-			// Handle dry-run defaults: if neither --dry-run nor --confirm is explicitly set,
-			// default to dry-run for safety
-			if !cmd.Flags().Changed("dry-run") && !cmd.Flags().Changed("confirm") {
-				dryRun = true
+			if err := resolveFixDryRun(cmd); err != nil {
+				return err
 			}
 
-			// If --confirm is set, disable dry-run (unless --dry-run is explicitly set)
-			if confirmChanges && !cmd.Flags().Changed("dry-run") {
-				dryRun = false
-			}
-
-			if !dryRun && !confirmChanges {
-				return fmt.Errorf("must use --confirm flag when not in dry-run mode")
+			if err := validateFixFlags(); err != nil {
+				return err
 			}
 
 			// Load configuration
 			cfg, err := getConfigFromCmd(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to load configuration: %w", err)
+				return newConfigError("failed to load configuration", err)
 			}
 
 			// Get connection config
 			dbConfig, err := cfg.GetConnectionConfig(connectionName)
 			if err != nil {
-				return fmt.Errorf("failed to get connection config: %w", err)
+				return newConfigError("failed to get connection config", err)
 			}
 
 			// Connect to database
 			db, err := database.NewConnection(dbConfig)
 			if err != nil {
-				return fmt.Errorf("failed to connect to database: %w", err)
+				return newConnectionError("failed to connect to database", err)
 			}
 			defer db.Close()
 
 			// Load target schema
 			targetSchema, err := schema.LoadSchema(getSchemaFilePath())
 			if err != nil {
-				return fmt.Errorf("failed to load target schema: %w", err)
+				return newConfigError("failed to load target schema", err)
+			}
+
+			// Only fix rows that are in scope of the configured row filters
+			if err := schema.ApplyRowFilters(targetSchema, cfg.Validation.RowFilters); err != nil {
+				return newConfigError("invalid row filter configuration", err)
 			}
 
 			// Get validation config
@@ -113,10 +387,18 @@ Examples:
 			fmt.Printf("🔧 Foreign Key Constraint Fix\n")
 			fmt.Printf("   Database: %s\n", dbConfig.Database)
 			fmt.Printf("   Action: %s\n", fixAction)
+			if fkFix.Constraint != "" {
+				fmt.Printf("   Constraint: %s\n", fkFix.Constraint)
+			}
 			fmt.Printf("   Dry Run: %v\n", dryRun)
+			fmt.Printf("   Commit: %s\n", getFixOptionsFromFlags().CommitPolicy)
 			fmt.Printf("\n")
 
-			if dryRun {
+			if isFixPlanCmd(cmd) {
+				fmt.Printf("📋 Planning foreign key fixes to %s (nothing is executed)...\n", outputFile)
+			} else if emitSQLFile != "" {
+				fmt.Printf("📝 Scripting foreign key fixes to %s (nothing is executed)...\n", emitSQLFile)
+			} else if dryRun {
 				fmt.Printf("🔍 Analyzing foreign key violations (dry-run mode)...\n")
 			} else {
 				fmt.Printf("⚠️  MAKING ACTUAL CHANGES TO DATABASE!\n")
@@ -124,37 +406,126 @@ Examples:
 			}
 
 			// Fix foreign key issues
-			results, err := db.FixForeignKeyViolations(targetSchema, fixAction, dryRun, &validationConfig)
+			opts := getFixOptionsFromFlags()
+			opts.RunID = database.NewFixRunID()
+			if emitSQLFile != "" {
+				opts.Script = db.NewSQLScript(
+					fmt.Sprintf("migrator fix fk --action %s", fixAction),
+					fmt.Sprintf("Database: %s", dbConfig.Database),
+				)
+			}
+			if isFixPlanCmd(cmd) {
+				opts.Plan = db.NewFixPlanRecorder()
+			}
+			recorder := startFixAudit("fix fk", fixAction, dbConfig, &opts)
+			results, err := db.FixForeignKeyViolations(targetSchema, fkFix, opts, &validationConfig)
+			if err != nil && results == nil {
+				return finishFixAudit(cfg, db, recorder, nil, err, newInternalError("failed to fix foreign key violations", err))
+			}
 			if err != nil {
-				return fmt.Errorf("failed to fix foreign key violations: %w", err)
+				fmt.Printf("❌ %v\n", err)
 			}
-
-			// Display results
-			fmt.Printf("\n📊 Fix Results:\n")
-			for tableName, result := range results {
-				fmt.Printf("  Table: %s\n", tableName)
-				fmt.Printf("    Issues found: %d\n", result.IssuesFound)
-				fmt.Printf("    Records affected: %d\n", result.RecordsAffected)
-				if !dryRun {
-					fmt.Printf("    Changes applied: %v\n", result.Success)
+			if opts.Plan != nil {
+				if err := saveFixPlan(db, cfg, dbConfig, "fk", fixAction, opts, results); err != nil {
+					return err
 				}
 			}
 
-			if dryRun {
-				fmt.Printf("\n💡 To apply these changes, run with --confirm flag and without --dry-run\n")
-			} else {
-				fmt.Printf("\n✅ Fix operation completed!\n")
-			}
-
-			return nil
+			// Display results
+			return finishFixAudit(cfg, db, recorder, results, err, printFixResults(results, opts))
 		},
 	}
 
-	cmd.Flags().StringVar(&fixAction, "action", "", "Fix action: remove|set-null")
+	cmd.Flags().StringVar(&fixAction, "action", "", "Fix action: remove|set-null|quarantine|create-parent|reassign")
+	cmd.Flags().StringVar(&fkConstraint, "constraint", "", "Only fix violations of this foreign key constraint")
+	cmd.Flags().StringToStringVar(&parentDefaults, "parent-defaults", nil, "Column values for parent rows created by create-parent (col=value,...)")
+	cmd.Flags().StringVar(&reassignTo, "reassign-to", "", "Existing parent key that reassign points orphaned records at")
+	cmd.Flags().StringVar(&mappingFile, "mapping-file", "", "JSON or CSV file mapping orphaned key values to existing parent keys for reassign")
+	cmd.MarkFlagsMutuallyExclusive("reassign-to", "mapping-file")
 
 	return cmd
 }
 
+// getForeignKeyFixFromFlags builds and validates the foreign key fix from the command line flags
+func getForeignKeyFixFromFlags() (database.ForeignKeyFix, error) {
+	fix := database.ForeignKeyFix{
+		Action:     fixAction,
+		Constraint: fkConstraint,
+	}
+
+	switch fixAction {
+	case "remove", "set-null", "quarantine":
+	case "create-parent":
+		fix.ParentDefaults = parentDefaults
+	case "reassign":
+		if fkConstraint == "" {
+			return fix, newConfigError("reassign requires --constraint so orphans are only pointed at parents of one table", nil)
+		}
+		if reassignTo == "" && mappingFile == "" {
+			return fix, newConfigError("reassign requires --reassign-to or --mapping-file", nil)
+		}
+		fix.ReassignTo = reassignTo
+		if mappingFile != "" {
+			mapping, err := loadValueMapping(mappingFile)
+			if err != nil {
+				return fix, newConfigError("failed to load mapping file", err)
+			}
+			fix.ValueMapping = mapping
+		}
+	default:
+		return fix, newConfigError(fmt.Sprintf("invalid action: %s (must be 'remove', 'set-null', 'quarantine', 'create-parent' or 'reassign')", fixAction), nil)
+	}
+
+	if len(parentDefaults) > 0 && fixAction != "create-parent" {
+		return fix, newConfigError("--parent-defaults can only be used with --action create-parent", nil)
+	}
+	if (reassignTo != "" || mappingFile != "") && fixAction != "reassign" {
+		return fix, newConfigError("--reassign-to and --mapping-file can only be used with --action reassign", nil)
+	}
+	return fix, nil
+}
+
+// loadValueMapping reads an old value -> new value mapping from a JSON object or a CSV file
+// whose first row is a header
+func loadValueMapping(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := make(map[string]string)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var raw map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON mapping: %w", err)
+		}
+		for oldValue, newValue := range raw {
+			mapping[oldValue] = fmt.Sprint(newValue)
+		}
+	} else {
+		records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV mapping: %w", err)
+		}
+		for i, record := range records {
+			if i == 0 {
+				continue
+			}
+			if len(record) < 2 {
+				return nil, fmt.Errorf("line %d: expected old and new value", i+1)
+			}
+			mapping[strings.TrimSpace(record[0])] = strings.TrimSpace(record[1])
+		}
+	}
+
+	if len(mapping) == 0 {
+		return nil, fmt.Errorf("mapping file %s contains no values", path)
+	}
+	return mapping, nil
+}
+
 // newFixNullCmd creates the fix null values command
 func newFixNullCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -164,66 +535,83 @@ func newFixNullCmd() *cobra.Command {
 
 Available actions:
   - remove: Delete records with NULL values in target columns
+  - quarantine: Move records with NULL values into <table>__quarantine for review
   - set-default: Set NULL values to specified default value
+  - schema-default: Set NULL values to the column's DefaultValue from the schema
+  - zero-value: Set NULL values to the empty value of the column's type (0, '', false, ...)
+
+With --plan, the strategy is chosen per table and column from a fix plan YAML file
+instead. Plans can also assign SQL expressions, copy another column of the row or
+look the value up in another table:
+
+  default:
+    action: zero-value
+  tables:
+    users:
+      email: {action: set-value, value: "unknown@example.com"}
+      display_name: {action: from-column, column: username}
+      created_at: {action: expression, expression: "CURRENT_TIMESTAMP"}
+      country_id:
+        action: lookup
+        lookup: {table: countries, column: id, where: "code = 'US'"}
+      legacy_code: {action: skip}
 
 Examples:
   migrator fix null --action remove --dry-run
-  migrator fix null --action set-default --default-value "unknown" --confirm`,
+  migrator fix null --action set-default --default-value "unknown" --confirm
+  migrator fix null --action zero-value --dry-run
+  migrator fix null --action quarantine --confirm
+  migrator fix null --plan fix-plan.yaml --confirm`,
 		Aliases: []string{"not-null", "nulls"},
 
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cmd.SilenceUsage = true
 
-			if fixAction == "" {
-				return fmt.Errorf("--action is required (remove|set-default)")
-			}
-
-			if fixAction != "remove" && fixAction != "set-default" {
-				return fmt.Errorf("invalid action: %s (must be 'remove' or 'set-default')", fixAction)
+			plan, err := getFixPlanFromFlags()
+			if err != nil {
+				return err
 			}
 
-			if fixAction == "set-default" && defaultValue == "" {
-				return fmt.Errorf("--default-value is required when using 'set-default' action")
+			if err := resolveFixDryRun(cmd); err != nil {
+				return err
 			}
 
-			// Handle dry-run defaults: if neither --dry-run nor --confirm is explicitly set,
-			// default to dry-run for safety
-			if !cmd.Flags().Changed("dry-run") && !cmd.Flags().Changed("confirm") {
-				dryRun = true
-			}
-
-			// If --confirm is set, disable dry-run (unless --dry-run is explicitly set)
-			if confirmChanges && !cmd.Flags().Changed("dry-run") {
-				dryRun = false
-			}
-
-			if !dryRun && !confirmChanges {
-				return fmt.Errorf("must use --confirm flag when not in dry-run mode")
+			if err := validateFixFlags(); err != nil {
+				return err
 			}
 
 			// Load configuration
 			cfg, err := getConfigFromCmd(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to load configuration: %w", err)
+				return newConfigError("failed to load configuration", err)
 			}
 
 			// Get connection config
 			dbConfig, err := cfg.GetConnectionConfig(connectionName)
 			if err != nil {
-				return fmt.Errorf("failed to get connection config: %w", err)
+				return newConfigError("failed to get connection config", err)
 			}
 
 			// Connect to database
 			db, err := database.NewConnection(dbConfig)
 			if err != nil {
-				return fmt.Errorf("failed to connect to database: %w", err)
+				return newConnectionError("failed to connect to database", err)
 			}
 			defer db.Close()
 
 			// Load target schema
 			targetSchema, err := schema.LoadSchema(getSchemaFilePath())
 			if err != nil {
-				return fmt.Errorf("failed to load target schema: %w", err)
+				return newConfigError("failed to load target schema", err)
+			}
+
+			// Only fix rows that are in scope of the configured row filters
+			if err := schema.ApplyRowFilters(targetSchema, cfg.Validation.RowFilters); err != nil {
+				return newConfigError("invalid row filter configuration", err)
+			}
+
+			if err := checkFixPlanColumns(plan, targetSchema); err != nil {
+				return newConfigError("invalid fix plan", err)
 			}
 
 			// Get validation config
@@ -231,14 +619,23 @@ Examples:
 
 			fmt.Printf("🔧 NULL Value Fix\n")
 			fmt.Printf("   Database: %s\n", dbConfig.Database)
-			fmt.Printf("   Action: %s\n", fixAction)
+			if fixPlanFile != "" {
+				fmt.Printf("   Plan: %s\n", fixPlanFile)
+			} else {
+				fmt.Printf("   Action: %s\n", fixAction)
+			}
 			if fixAction == "set-default" {
 				fmt.Printf("   Default Value: %s\n", defaultValue)
 			}
 			fmt.Printf("   Dry Run: %v\n", dryRun)
+			fmt.Printf("   Commit: %s\n", getFixOptionsFromFlags().CommitPolicy)
 			fmt.Printf("\n")
 
-			if dryRun {
+			if isFixPlanCmd(cmd) {
+				fmt.Printf("📋 Planning NULL value fixes to %s (nothing is executed)...\n", outputFile)
+			} else if emitSQLFile != "" {
+				fmt.Printf("📝 Scripting NULL value fixes to %s (nothing is executed)...\n", emitSQLFile)
+			} else if dryRun {
 				fmt.Printf("🔍 Analyzing NULL value violations (dry-run mode)...\n")
 			} else {
 				fmt.Printf("⚠️  MAKING ACTUAL CHANGES TO DATABASE!\n")
@@ -246,34 +643,207 @@ Examples:
 			}
 
 			// Fix null value issues
-			results, err := db.FixNullValueViolations(targetSchema, fixAction, defaultValue, dryRun, &validationConfig)
+			opts := getFixOptionsFromFlags()
+			opts.RunID = database.NewFixRunID()
+			if emitSQLFile != "" {
+				opts.Script = db.NewSQLScript(
+					fmt.Sprintf("migrator fix null, plan: %s", plan.Name),
+					fmt.Sprintf("Database: %s", dbConfig.Database),
+				)
+			}
+			if isFixPlanCmd(cmd) {
+				opts.Plan = db.NewFixPlanRecorder()
+			}
+			recorder := startFixAudit("fix null", plan.Name, dbConfig, &opts)
+			results, err := db.FixNullValueViolations(targetSchema, plan, opts, &validationConfig)
+			if err != nil && results == nil {
+				return finishFixAudit(cfg, db, recorder, nil, err, newInternalError("failed to fix null value violations", err))
+			}
 			if err != nil {
-				return fmt.Errorf("failed to fix null value violations: %w", err)
+				fmt.Printf("❌ %v\n", err)
+			}
+			if opts.Plan != nil {
+				if err := saveFixPlan(db, cfg, dbConfig, "null", plan.Name, opts, results); err != nil {
+					return err
+				}
 			}
 
 			// Display results
-			fmt.Printf("\n📊 Fix Results:\n")
-			for tableName, result := range results {
-				fmt.Printf("  Table: %s\n", tableName)
-				fmt.Printf("    Issues found: %d\n", result.IssuesFound)
-				fmt.Printf("    Records affected: %d\n", result.RecordsAffected)
-				if !dryRun {
-					fmt.Printf("    Changes applied: %v\n", result.Success)
+			return finishFixAudit(cfg, db, recorder, results, err, printFixResults(results, opts))
+		},
+	}
+
+	cmd.Flags().StringVar(&fixAction, "action", "", "Fix action: remove|quarantine|set-default|schema-default|zero-value")
+	cmd.Flags().StringVar(&defaultValue, "default-value", "", "Default value to use when action is set-default")
+	cmd.Flags().StringVar(&fixPlanFile, "plan", "", "Fix plan YAML file giving the strategy per table and column")
+	cmd.MarkFlagsMutuallyExclusive("action", "plan")
+
+	return cmd
+}
+
+// getFixPlanFromFlags loads the fix plan file, or builds a plan applying --action to every column
+func getFixPlanFromFlags() (*config.FixPlan, error) {
+	if fixPlanFile != "" {
+		plan, err := config.LoadFixPlan(fixPlanFile)
+		if err != nil {
+			return nil, newConfigError("failed to load fix plan", err)
+		}
+		return plan, nil
+	}
+
+	switch fixAction {
+	case "":
+		return nil, newConfigError("--action or --plan is required (remove|quarantine|set-default|schema-default|zero-value)", nil)
+	case "set-default":
+		if defaultValue == "" {
+			return nil, newConfigError("--default-value is required when using 'set-default' action", nil)
+		}
+		plan := config.NewUniformFixPlan(config.ColumnFixStrategy{Action: config.FixActionSetValue, Value: defaultValue})
+		plan.Name = fixAction
+		return plan, nil
+	case config.FixActionRemove, config.FixActionQuarantine, config.FixActionSchemaDefault, config.FixActionZeroValue:
+		return config.NewUniformFixPlan(config.ColumnFixStrategy{Action: fixAction}), nil
+	default:
+		return nil, newConfigError(fmt.Sprintf("invalid action: %s (must be 'remove', 'quarantine', 'set-default', 'schema-default' or 'zero-value')", fixAction), nil)
+	}
+}
+
+// checkFixPlanColumns checks that the tables and columns named by a fix plan exist in the
+// target schema
+func checkFixPlanColumns(plan *config.FixPlan, targetSchema models.Schema) error {
+	for tableName, columns := range plan.Tables {
+		table := targetSchema.GetTable(tableName)
+		if table == nil {
+			return fmt.Errorf("table %s is not in the target schema", tableName)
+		}
+		for columnName, strategy := range columns {
+			if columnName != "*" && table.GetColumn(columnName) == nil {
+				return fmt.Errorf("column %s.%s is not in the target schema", tableName, columnName)
+			}
+			if strategy.Action == config.FixActionFromColumn && table.GetColumn(strategy.Column) == nil {
+				return fmt.Errorf("%s.%s: source column %s is not in the target schema", tableName, columnName, strategy.Column)
+			}
+		}
+	}
+	return nil
+}
+
+// newFixUndoCmd creates the fix undo command
+func newFixUndoCmd() *cobra.Command {
+	var skipConflicts bool
+
+	cmd := &cobra.Command{
+		Use:   "undo <run-id>",
+		Short: "Revert a previous fix run from its backups",
+		Long: `Restores the rows changed by a fix run from the backups written before the fix:
+deleted rows are re-inserted and updated columns are set back to their old values.
+
+Rows whose data has changed since the fix (a deleted key has been re-used, or an
+updated column no longer holds the value the fix assigned) are reported as conflicts.
+Any conflict aborts the undo unless --skip-conflicts is given, in which case only the
+conflicting rows are left alone. All rows are restored in a single transaction.
+
+Examples:
+  migrator fix undo 20240101T120000Z-a1b2c3 --dry-run
+  migrator fix undo 20240101T120000Z-a1b2c3 --confirm`,
+		Aliases: []string{"revert", "rollback"},
+		Args:    cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			runID := args[0]
+
+			// Default to dry-run for safety, as with the other fix commands
+			if !cmd.Flags().Changed("dry-run") && !cmd.Flags().Changed("confirm") {
+				dryRun = true
+			}
+			if confirmChanges && !cmd.Flags().Changed("dry-run") {
+				dryRun = false
+			}
+			if !dryRun && !confirmChanges {
+				return newConfigError("must use --confirm flag when not in dry-run mode", nil)
+			}
+
+			// Load configuration
+			cfg, err := getConfigFromCmd(cmd)
+			if err != nil {
+				return newConfigError("failed to load configuration", err)
+			}
+
+			// Get connection config
+			dbConfig, err := cfg.GetConnectionConfig(connectionName)
+			if err != nil {
+				return newConfigError("failed to get connection config", err)
+			}
+
+			// Connect to database
+			db, err := database.NewConnection(dbConfig)
+			if err != nil {
+				return newConnectionError("failed to connect to database", err)
+			}
+			defer db.Close()
+
+			fmt.Printf("↩️  Undo Fix Run\n")
+			fmt.Printf("   Run ID: %s\n", runID)
+			fmt.Printf("   Database: %s\n", dbConfig.Database)
+			fmt.Printf("   Dry Run: %v\n", dryRun)
+			fmt.Printf("\n")
+
+			var recorder *audit.Recorder
+			if !dryRun {
+				recorder = startAudit("fix undo", "undo", dbConfig)
+				recorder.SetRunID(runID)
+			}
+
+			result, err := db.UndoFixRun(backupDir, runID, dryRun, skipConflicts)
+			if err != nil {
+				return finishAudit(cfg, db, recorder, audit.OutcomeFailed, 0, err, newInternalError("failed to undo fix run", err))
+			}
+
+			if len(result.Conflicts) > 0 {
+				fmt.Printf("⚠️  Conflicts (%d):\n", len(result.Conflicts))
+				for _, conflict := range result.Conflicts {
+					if conflict.Column != "" {
+						fmt.Printf("   • %s [%s] %s: %s\n", conflict.Table, conflict.Key, conflict.Column, conflict.Reason)
+					} else {
+						fmt.Printf("   • %s [%s]: %s\n", conflict.Table, conflict.Key, conflict.Reason)
+					}
 				}
+				fmt.Printf("\n")
 			}
 
-			if dryRun {
-				fmt.Printf("\n💡 To apply these changes, run with --confirm flag and without --dry-run\n")
-			} else {
-				fmt.Printf("\n✅ Fix operation completed!\n")
+			fmt.Printf("📊 Rows to restore:\n")
+			for tableName, count := range result.Restored {
+				fmt.Printf("  Table: %s\n", tableName)
+				fmt.Printf("    Rows: %d\n", count)
 			}
 
-			return nil
+			if len(result.Conflicts) > 0 && !skipConflicts {
+				fmt.Printf("\n❌ Nothing was restored because of conflicts; use --skip-conflicts to restore the other rows\n")
+				abortErr := newIssuesFoundError("undo aborted because of conflicts")
+				return finishAudit(cfg, db, recorder, audit.OutcomeRolledBack, 0, abortErr, abortErr)
+			}
+
+			if dryRun {
+				fmt.Printf("\n💡 To restore these rows, run with --confirm flag and without --dry-run\n")
+				return nil
+			}
+			fmt.Printf("\n✅ Fix run %s has been undone\n", runID)
+
+			var restored int64
+			for tableName, count := range result.Restored {
+				restored += int64(count)
+				recorder.AddStatement(models.AuditStatement{
+					Table:       tableName,
+					Description: fmt.Sprintf("restore rows backed up by fix run %s", runID),
+					Rows:        int64(count),
+				})
+			}
+			return finishAudit(cfg, db, recorder, audit.OutcomeCommitted, restored, nil, nil)
 		},
 	}
 
-	cmd.Flags().StringVar(&fixAction, "action", "", "Fix action: remove|set-default")
-	cmd.Flags().StringVar(&defaultValue, "default-value", "", "Default value to use when action is set-default")
+	cmd.Flags().BoolVar(&skipConflicts, "skip-conflicts", false, "Restore the rows that do not conflict and leave the others alone")
 
 	return cmd
 }
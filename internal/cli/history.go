@@ -0,0 +1,319 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nkamuo/go-db-migration/internal/config"
+	"github.com/nkamuo/go-db-migration/internal/history"
+	"github.com/nkamuo/go-db-migration/internal/models"
+	"github.com/nkamuo/go-db-migration/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// recordValidationHistory records a validation report in the run history. A failure to
+// record is only a warning: the validation itself succeeded.
+func recordValidationHistory(cmd *cobra.Command, vctx *validationContext, report *models.ValidationReport) {
+	historyConfig := vctx.cfg.GetHistoryConfig()
+	if historyConfig.Disabled {
+		return
+	}
+	store := history.NewStore(historyConfig.Dir)
+	if _, err := store.RecordValidation(cmd.CommandPath(), connectionName, history.HashSchema(vctx.targetSchema), report); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to record run history: %v\n", err)
+		return
+	}
+	applyHistoryRetention(store, historyConfig)
+}
+
+// recordComparisonHistory records a schema comparison in the run history
+func recordComparisonHistory(cmd *cobra.Command, cfg *config.Config, targetSchema models.Schema, comparison *models.SchemaComparison) {
+	historyConfig := cfg.GetHistoryConfig()
+	if historyConfig.Disabled {
+		return
+	}
+	store := history.NewStore(historyConfig.Dir)
+	if _, err := store.RecordComparison(cmd.CommandPath(), connectionName, history.HashSchema(targetSchema), comparison); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to record run history: %v\n", err)
+		return
+	}
+	applyHistoryRetention(store, historyConfig)
+}
+
+// applyHistoryRetention removes the runs beyond history.max_runs after a run is recorded
+func applyHistoryRetention(store *history.Store, historyConfig config.HistoryConfig) {
+	if historyConfig.MaxRuns <= 0 {
+		return
+	}
+	if _, err := store.Prune(historyConfig.MaxRuns, time.Time{}, false); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to prune run history: %v\n", err)
+	}
+}
+
+// openHistoryStore loads the configuration and opens the run history store
+func openHistoryStore(cmd *cobra.Command) (*history.Store, error) {
+	cfg, err := getConfigFromCmd(cmd)
+	if err != nil {
+		return nil, newConfigError("failed to load configuration", err)
+	}
+	return history.NewStore(cfg.GetHistoryConfig().Dir), nil
+}
+
+// validateHistoryKind checks the --kind flag of the history commands
+func validateHistoryKind(kind string, allowAll bool) error {
+	switch kind {
+	case history.KindValidation, history.KindComparison:
+		return nil
+	case "":
+		if allowAll {
+			return nil
+		}
+	}
+	return newConfigError(fmt.Sprintf("invalid --kind %q: expected %s or %s", kind, history.KindValidation, history.KindComparison), nil)
+}
+
+// newHistoryCmd creates the history command group
+func newHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Browse the history of validation and comparison runs",
+		Long: `Every validation and schema compare run is recorded in a local history store
+(history.dir in the configuration, by default ~/.migrator/history) with its
+connection, a hash of the target schema and a timestamp.
+These commands list the recorded runs, show the full result of one, plot issue
+counts over time and prune old runs.
+
+Set history.max_runs in the configuration to keep only the most recent runs, or
+history.disabled to true to stop recording runs.`,
+	}
+
+	cmd.AddCommand(newHistoryListCmd())
+	cmd.AddCommand(newHistoryShowCmd())
+	cmd.AddCommand(newHistoryTrendCmd())
+	cmd.AddCommand(newHistoryPruneCmd())
+
+	return cmd
+}
+
+// newHistoryListCmd creates the history list command
+func newHistoryListCmd() *cobra.Command {
+	var kind string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List recorded runs",
+		Long: `Lists the recorded validation and comparison runs, newest first. With
+--connection, only runs against that connection are listed.
+
+Examples:
+  migrator history list
+  migrator history list --connection prod --kind validation
+  migrator history list --limit 0 --format csv -o runs.csv`,
+		Aliases: []string{"ls"},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			if err := validateHistoryKind(kind, true); err != nil {
+				return err
+			}
+			store, err := openHistoryStore(cmd)
+			if err != nil {
+				return err
+			}
+			entries, err := store.List()
+			if err != nil {
+				return newInternalError("failed to read run history", err)
+			}
+
+			// Newest first
+			var filtered []models.HistoryEntry
+			for i := len(entries) - 1; i >= 0; i-- {
+				entry := entries[i]
+				if kind != "" && entry.Kind != kind {
+					continue
+				}
+				if connectionName != "" && entry.Connection != connectionName {
+					continue
+				}
+				filtered = append(filtered, entry)
+			}
+			if limit > 0 && len(filtered) > limit {
+				filtered = filtered[:limit]
+			}
+
+			formatter := output.NewFormatter(outputFormat)
+			content, err := formatter.FormatHistoryEntries(filtered)
+			if err != nil {
+				return newConfigError("failed to format run history", err)
+			}
+			return saveOutput(content, cmd)
+		},
+	}
+
+	cmd.Flags().StringVar(&kind, "kind", "", "Only list runs of this kind (validation or comparison)")
+	cmd.Flags().IntVar(&limit, "limit", 20, "Show at most this many runs (0 = all)")
+
+	return cmd
+}
+
+// newHistoryShowCmd creates the history show command
+func newHistoryShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <run-id>",
+		Short: "Show the full result of a recorded run",
+		Long: `Shows the validation report or schema comparison of a recorded run, in any
+format the original command supports. A unique prefix of the run ID is enough.
+
+Examples:
+  migrator history show 20240101T120000Z-a1b2c3
+  migrator history show 20240101T12 --format json -o report.json`,
+		Args: cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			store, err := openHistoryStore(cmd)
+			if err != nil {
+				return err
+			}
+			run, err := store.Get(args[0])
+			if err != nil {
+				return newConfigError("failed to read recorded run", err)
+			}
+
+			formatter := output.NewFormatter(outputFormat)
+			var content string
+			switch {
+			case run.Report != nil:
+				content, err = formatter.FormatValidationReport(run.Report)
+			case run.Comparison != nil:
+				content, err = formatter.FormatSchemaComparison(run.Comparison)
+			default:
+				return newInternalError(fmt.Sprintf("run %s has no recorded result", run.ID), nil)
+			}
+			if err != nil {
+				return newConfigError("failed to format recorded run", err)
+			}
+			return saveOutput(content, cmd)
+		},
+	}
+}
+
+// newHistoryTrendCmd creates the history trend command
+func newHistoryTrendCmd() *cobra.Command {
+	var kind string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "trend",
+		Short: "Plot issue counts of recorded runs over time",
+		Long: `Plots the counts of the recorded runs against one connection over time: the
+number of issues, errors, warnings and issues of every type for validation runs,
+or the number of missing, extra and changed tables for comparison runs.
+
+The table format draws each count as a text sparkline; the CSV format has one
+row per run, ready to plot in a spreadsheet. Runs without --connection are
+recorded against the default connection.
+
+Examples:
+  migrator history trend --connection prod
+  migrator history trend --connection prod --kind comparison --limit 0
+  migrator history trend --connection prod --format csv -o trend.csv`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			if err := validateHistoryKind(kind, false); err != nil {
+				return err
+			}
+			store, err := openHistoryStore(cmd)
+			if err != nil {
+				return err
+			}
+			entries, err := store.List()
+			if err != nil {
+				return newInternalError("failed to read run history", err)
+			}
+
+			// Keep the most recent runs, oldest first
+			entries = history.Filter(entries, kind, connectionName)
+			if limit > 0 && len(entries) > limit {
+				entries = entries[len(entries)-limit:]
+			}
+
+			formatter := output.NewFormatter(outputFormat)
+			content, err := formatter.FormatHistoryTrend(history.Trend(kind, connectionName, entries))
+			if err != nil {
+				return newConfigError("failed to format trend", err)
+			}
+			return saveOutput(content, cmd)
+		},
+	}
+
+	cmd.Flags().StringVar(&kind, "kind", history.KindValidation, "Kind of runs to plot (validation or comparison)")
+	cmd.Flags().IntVar(&limit, "limit", 30, "Plot at most this many of the most recent runs (0 = all)")
+
+	return cmd
+}
+
+// newHistoryPruneCmd creates the history prune command
+func newHistoryPruneCmd() *cobra.Command {
+	var keep int
+	var olderThan time.Duration
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove old runs from the history",
+		Long: `Removes recorded runs with their full results: every run beyond the --keep most
+recent ones and every run recorded more than --older-than ago. At least one of
+the two is required.
+
+Examples:
+  migrator history prune --keep 100
+  migrator history prune --older-than 720h --dry-run`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			if keep < 0 || olderThan < 0 {
+				return newConfigError("--keep and --older-than cannot be negative", nil)
+			}
+			if keep == 0 && olderThan == 0 {
+				return newConfigError("--keep or --older-than is required", nil)
+			}
+			store, err := openHistoryStore(cmd)
+			if err != nil {
+				return err
+			}
+
+			var before time.Time
+			if olderThan > 0 {
+				before = time.Now().Add(-olderThan)
+			}
+			removed, err := store.Prune(keep, before, dryRun)
+			if err != nil {
+				return newInternalError("failed to prune run history", err)
+			}
+
+			if dryRun {
+				fmt.Printf("📊 Runs to remove: %d\n", len(removed))
+				if len(removed) > 0 {
+					fmt.Printf("\n💡 To remove these runs, run without --dry-run\n")
+				}
+				return nil
+			}
+			fmt.Printf("✅ Removed %d runs\n", len(removed))
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&keep, "keep", 0, "Keep only this many of the most recent runs (0 = no limit)")
+	cmd.Flags().DurationVar(&olderThan, "older-than", 0, "Remove runs recorded at least this long ago (e.g. 720h)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show how many runs would be removed without removing them")
+
+	return cmd
+}
@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+	"github.com/nkamuo/go-db-migration/internal/output"
+	"github.com/nkamuo/go-db-migration/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+// collectRunMetrics gathers the metrics of a validation run, comparing the database with
+// the target schema for the drift counts
+func collectRunMetrics(vctx *validationContext, checks []models.CheckDuration, summary models.ReportSummary, tables []models.TableValidationSummary) (*models.RunMetrics, error) {
+	start := time.Now()
+	currentSchema, err := vctx.db.GetCurrentSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current schema: %w", err)
+	}
+	comparison := schema.CompareSchemas(currentSchema, vctx.targetSchema)
+	checks = append(checks, models.CheckDuration{Check: "schema_drift", Seconds: time.Since(start).Seconds()})
+
+	return &models.RunMetrics{
+		Connection: connectionName,
+		Timestamp:  time.Now().Format(time.RFC3339),
+		Summary:    summary,
+		Checks:     checks,
+		Tables:     tables,
+		Comparison: comparison,
+	}, nil
+}
+
+// writeValidationMetrics writes the --metrics-file of a validation run, if one was requested
+func writeValidationMetrics(cmd *cobra.Command, vctx *validationContext, checks []models.CheckDuration, summary models.ReportSummary, tables []models.TableValidationSummary) error {
+	if metricsFile == "" {
+		return nil
+	}
+
+	metrics, err := collectRunMetrics(vctx, checks, summary, tables)
+	if err == nil {
+		err = output.WriteMetricsFile(metricsFile, metrics)
+	}
+	if err != nil {
+		if !isJSONOutput() {
+			fmt.Printf("❌ Metrics Export Failed\n\n")
+			fmt.Printf("Error: %v\n\n", err)
+		}
+		return failCommand(cmd, newInternalError("failed to write metrics file", err))
+	}
+	return nil
+}
+
+// metricsServer serves the metrics of the most recent successful validation run
+type metricsServer struct {
+	mu      sync.RWMutex
+	content string
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (s *metricsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	content := s.content
+	s.mu.RUnlock()
+
+	if content == "" {
+		http.Error(w, "no validation run has completed yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprint(w, content)
+}
+
+// run runs validate all once and publishes its metrics. A failed run keeps the metrics of
+// the previous one, so their timestamp shows how stale they are.
+func (s *metricsServer) run(cmd *cobra.Command) {
+	vctx, err := openValidationContext(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Validation run failed: %v\n", err)
+		return
+	}
+	defer vctx.db.Close()
+
+	sink := &issueSink{}
+	if err := runAllChecks(cmd, vctx, sink); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Validation run failed: %v\n", err)
+		return
+	}
+	report := output.CreateValidationReport(connectionName, sink.issues)
+	report.Tables = vctx.tableSummaries(&sink.tables)
+	recordValidationHistory(cmd, vctx, report)
+
+	metrics, err := collectRunMetrics(vctx, sink.checks, report.Summary, report.Tables)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Validation run failed: %v\n", err)
+		return
+	}
+	if metricsFile != "" {
+		if err := output.WriteMetricsFile(metricsFile, metrics); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.content = output.FormatMetrics(metrics)
+	s.mu.Unlock()
+	fmt.Fprintf(os.Stderr, "✅ Validation run finished: %d issue(s), %d error(s), %d warning(s)\n",
+		report.Summary.TotalIssues, report.Summary.ErrorCount, report.Summary.WarningCount)
+}
+
+// newValidateServeMetricsCmd creates the validate serve-metrics command
+func newValidateServeMetricsCmd() *cobra.Command {
+	var listen string
+	var interval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "serve-metrics",
+		Short: "Run validate all periodically and serve Prometheus metrics",
+		Long: `Runs the checks of validate all every --interval and serves the metrics of the
+most recent run over HTTP at /metrics, for Prometheus to scrape.
+
+The metrics are the same as those written by --metrics-file:
+- migrator_validation_issues: issues by connection, type, severity and table, with
+  zero for every issue type a check can report on a table it checked
+- migrator_validation_run_issues, _run_errors and _run_warnings: run totals
+- migrator_validation_check_duration_seconds: duration of every check
+- migrator_validation_last_run_timestamp_seconds: time of the last successful run
+- migrator_schema_drift_tables and _columns: missing, extra and changed tables and
+  columns compared with the target schema
+
+A failed run keeps the metrics of the previous one; alert on the age of the last
+run timestamp to catch runs that keep failing.
+
+Examples:
+  migrator validate serve-metrics --connection prod
+  migrator validate serve-metrics --listen :9187 --interval 15m
+  migrator validate serve-metrics --interval 1h --metrics-file /var/lib/node_exporter/migrator.prom`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			if interval <= 0 {
+				return newConfigError(fmt.Sprintf("invalid --interval %s: must be positive", interval), nil)
+			}
+			listener, err := net.Listen("tcp", listen)
+			if err != nil {
+				return newConfigError(fmt.Sprintf("failed to listen on %s", listen), err)
+			}
+
+			server := &metricsServer{}
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", server)
+
+			go func() {
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					server.run(cmd)
+					<-ticker.C
+				}
+			}()
+
+			fmt.Fprintf(os.Stderr, "📡 Serving metrics on http://%s/metrics, validating every %s\n", listener.Addr(), interval)
+			if err := http.Serve(listener, mux); err != nil {
+				return newInternalError("metrics server stopped", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&listen, "listen", ":9187", "Address to serve metrics on")
+	cmd.Flags().DurationVar(&interval, "interval", time.Hour, "Time between validation runs")
+
+	return cmd
+}
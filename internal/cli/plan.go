@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nkamuo/go-db-migration/internal/config"
+	"github.com/nkamuo/go-db-migration/internal/database"
+	"github.com/nkamuo/go-db-migration/internal/models"
+	"github.com/spf13/cobra"
+)
+
+// newFixPlanCmd creates the fix plan command group
+func newFixPlanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Record the operations of a fix in a plan file for review",
+		Long: `Records what 'fix fk' or 'fix null' would do in a plan file, without changing
+anything: every operation with its exact statement, the number and keys of the
+rows it changes, and hashes of the target schema and validation configuration.
+
+Review the plan, then run it with 'fix apply', which refuses to run if the
+database has drifted from the plan and otherwise executes exactly the planned
+operations.
+
+Examples:
+  migrator fix plan fk --action remove -o plan.json
+  migrator fix plan null --plan null-fixes.yaml -o plan.json
+  migrator fix apply plan.json --confirm`,
+	}
+
+	cmd.AddCommand(newFixFKCmd())
+	cmd.AddCommand(newFixNullCmd())
+
+	return cmd
+}
+
+// isFixPlanCmd reports whether a fix command runs under 'fix plan'
+func isFixPlanCmd(cmd *cobra.Command) bool {
+	return cmd.Parent() != nil && cmd.Parent().Name() == "plan"
+}
+
+// hashFile returns the SHA-256 hash of a file
+func hashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// hashValidationConfig returns the SHA-256 hash of the validation configuration, which
+// includes the row filters that scope every fix
+func hashValidationConfig(cfg *config.Config) (string, error) {
+	data, err := json.Marshal(cfg.Validation)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// saveFixPlan writes the operations recorded by a planned fix to the --output file
+func saveFixPlan(db *database.DB, cfg *config.Config, dbConfig *config.DBConfig, command, action string, opts database.FixOptions, results models.FixResults) error {
+	schemaHash, err := hashFile(getSchemaFilePath())
+	if err != nil {
+		return newConfigError("failed to hash target schema", err)
+	}
+	configHash, err := hashValidationConfig(cfg)
+	if err != nil {
+		return newInternalError("failed to hash validation configuration", err)
+	}
+
+	plan := &models.FixRunPlan{
+		Version:      database.FixPlanVersion,
+		RunID:        opts.RunID,
+		CreatedAt:    time.Now().UTC().Format(time.RFC3339),
+		Connection:   connectionName,
+		Database:     dbConfig.Database,
+		DatabaseType: string(db.GetDatabaseType()),
+		Command:      command,
+		Action:       action,
+		SchemaHash:   schemaHash,
+		ConfigHash:   configHash,
+		Operations:   opts.Plan.Operations(),
+		Results:      results,
+	}
+	if err := database.SaveFixRunPlan(outputFile, plan); err != nil {
+		return newInternalError("failed to write fix plan", err)
+	}
+	return nil
+}
+
+// newFixApplyCmd creates the fix apply command
+func newFixApplyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "apply <plan-file>",
+		Short: "Execute exactly the operations of a reviewed fix plan",
+		Long: `Executes a plan written by 'fix plan'. Before anything is changed the plan is
+checked for drift: the target schema file and validation configuration must
+hash as they did, and every operation must match exactly the rows recorded in
+the plan. Any difference aborts the apply; make a new plan instead.
+
+Deletes and updates are restricted to the planned rows, and an operation that
+changes a different number of rows than planned fails and is rolled back like
+any other fix. Backups, commit policies and batching work as for 'fix fk'.
+
+Examples:
+  migrator fix apply plan.json --dry-run
+  migrator fix apply plan.json --confirm`,
+		Args: cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			planFile := args[0]
+
+			if emitSQLFile != "" {
+				return newConfigError("--emit-sql cannot be combined with fix apply; the plan already lists every statement", nil)
+			}
+			if err := resolveFixDryRun(cmd); err != nil {
+				return err
+			}
+			if err := validateFixFlags(); err != nil {
+				return err
+			}
+
+			plan, err := database.LoadFixRunPlan(planFile)
+			if err != nil {
+				return newConfigError("failed to load fix plan", err)
+			}
+
+			cfg, err := getConfigFromCmd(cmd)
+			if err != nil {
+				return newConfigError("failed to load configuration", err)
+			}
+
+			dbConfig, err := cfg.GetConnectionConfig(connectionName)
+			if err != nil {
+				return newConfigError("failed to get connection config", err)
+			}
+
+			db, err := database.NewConnection(dbConfig)
+			if err != nil {
+				return newConnectionError("failed to connect to database", err)
+			}
+			defer db.Close()
+
+			fmt.Printf("🔧 Apply Fix Plan\n")
+			fmt.Printf("   Plan: %s (created %s)\n", planFile, plan.CreatedAt)
+			fmt.Printf("   Database: %s\n", dbConfig.Database)
+			fmt.Printf("   Fix: %s, action %s\n", plan.Command, plan.Action)
+			fmt.Printf("   Operations: %d\n", len(plan.Operations))
+			fmt.Printf("   Dry Run: %v\n", dryRun)
+			fmt.Printf("   Commit: %s\n", getFixOptionsFromFlags().CommitPolicy)
+			fmt.Printf("\n")
+
+			// The plan only holds for the database, schema and configuration it was made with
+			var drift []string
+			if plan.Database != dbConfig.Database {
+				drift = append(drift, fmt.Sprintf("the plan was made for database %s, not %s", plan.Database, dbConfig.Database))
+			}
+			schemaHash, err := hashFile(getSchemaFilePath())
+			if err != nil {
+				return newConfigError("failed to hash target schema", err)
+			}
+			if schemaHash != plan.SchemaHash {
+				drift = append(drift, fmt.Sprintf("the target schema %s has changed since the plan was made", getSchemaFilePath()))
+			}
+			configHash, err := hashValidationConfig(cfg)
+			if err != nil {
+				return newInternalError("failed to hash validation configuration", err)
+			}
+			if configHash != plan.ConfigHash {
+				drift = append(drift, "the validation configuration (row filters) has changed since the plan was made")
+			}
+
+			fmt.Printf("🔍 Checking the database for drift from the plan...\n")
+			rowDrift, err := db.CheckFixPlanDrift(plan)
+			if err != nil {
+				return newInternalError("failed to check fix plan for drift", err)
+			}
+			drift = append(drift, rowDrift...)
+
+			if len(drift) > 0 {
+				fmt.Printf("\n❌ The database has drifted from the plan (%d differences):\n", len(drift))
+				for _, difference := range drift {
+					fmt.Printf("   • %s\n", difference)
+				}
+				fmt.Printf("\n💡 Nothing was changed; run 'fix plan' again and review the new plan\n")
+				return newIssuesFoundError("database has drifted from the fix plan")
+			}
+			fmt.Printf("✅ No drift: the database matches the plan\n")
+
+			if dryRun {
+				fmt.Printf("\n💡 To execute the plan, run with --confirm flag and without --dry-run\n")
+				return nil
+			}
+
+			fmt.Printf("⚠️  MAKING ACTUAL CHANGES TO DATABASE!\n")
+
+			opts := getFixOptionsFromFlags()
+			// Every attempt gets its own manifest, backups and audit record
+			opts.RunID = database.NewFixRunID()
+			opts.PlanID = plan.RunID
+			recorder := startFixAudit("fix apply", plan.Action, dbConfig, &opts)
+			results, err := db.ApplyFixPlan(plan, opts)
+			if err != nil && results == nil {
+				return finishFixAudit(cfg, db, recorder, nil, err, newInternalError("failed to apply fix plan", err))
+			}
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+			}
+
+			return finishFixAudit(cfg, db, recorder, results, err, printFixResults(results, opts))
+		},
+	}
+}
@@ -0,0 +1,308 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nkamuo/go-db-migration/internal/audit"
+	"github.com/nkamuo/go-db-migration/internal/config"
+	"github.com/nkamuo/go-db-migration/internal/database"
+	"github.com/nkamuo/go-db-migration/internal/models"
+	"github.com/nkamuo/go-db-migration/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// Quarantine command options
+var (
+	quarantineRunID      string
+	quarantineConstraint string
+	quarantineOlderThan  time.Duration
+	quarantineDrop       bool
+)
+
+// newQuarantineCmd creates the quarantine command group
+func newQuarantineCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "quarantine",
+		Short: "Review and manage rows moved into quarantine tables",
+		Long: `Commands to manage the rows that 'fix fk --action quarantine' and
+'fix null --action quarantine' moved out of their tables.
+
+Quarantined rows live in <table>__quarantine, a copy of the table's columns plus
+quarantine_reason, quarantine_constraint, quarantine_run_id and quarantined_at.
+After review they can be restored into the original table or purged for good.`,
+		Aliases: []string{"q"},
+	}
+
+	cmd.AddCommand(newQuarantineListCmd())
+	cmd.AddCommand(newQuarantineRestoreCmd())
+	cmd.AddCommand(newQuarantinePurgeCmd())
+
+	cmd.PersistentFlags().StringVar(&quarantineRunID, "run-id", "", "Only rows quarantined by this fix run")
+	cmd.PersistentFlags().StringVar(&quarantineConstraint, "constraint", "", "Only rows quarantined for this constraint")
+
+	return cmd
+}
+
+// getQuarantineFilterFromFlags builds the quarantine row filter from the command line flags
+func getQuarantineFilterFromFlags() database.QuarantineFilter {
+	return database.QuarantineFilter{
+		RunID:      quarantineRunID,
+		Constraint: quarantineConstraint,
+		OlderThan:  quarantineOlderThan,
+	}
+}
+
+// connectQuarantineDatabase loads the configuration and connects to the database selected
+// by --connection
+func connectQuarantineDatabase(cmd *cobra.Command) (*database.DB, *config.Config, *config.DBConfig, error) {
+	cfg, err := getConfigFromCmd(cmd)
+	if err != nil {
+		return nil, nil, nil, newConfigError("failed to load configuration", err)
+	}
+
+	dbConfig, err := cfg.GetConnectionConfig(connectionName)
+	if err != nil {
+		return nil, nil, nil, newConfigError("failed to get connection config", err)
+	}
+
+	db, err := database.NewConnection(dbConfig)
+	if err != nil {
+		return nil, nil, nil, newConnectionError("failed to connect to database", err)
+	}
+	return db, cfg, dbConfig, nil
+}
+
+// newQuarantineListCmd creates the quarantine list command
+func newQuarantineListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list [table]",
+		Short: "List quarantined rows per table, fix run and constraint",
+		Long: `Summarizes the quarantine tables: how many rows each fix run moved there for
+each constraint, and when.
+
+Examples:
+  migrator quarantine list
+  migrator quarantine list orders --format json`,
+		Aliases: []string{"ls"},
+		Args:    cobra.MaximumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			tableName := ""
+			if len(args) == 1 {
+				tableName = args[0]
+			}
+
+			db, _, _, err := connectQuarantineDatabase(cmd)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			entries, err := db.ListQuarantine(tableName)
+			if err != nil {
+				return newInternalError("failed to list quarantine tables", err)
+			}
+
+			// Apply --run-id and --constraint to the summary as well
+			filtered := entries[:0]
+			for _, entry := range entries {
+				if quarantineRunID != "" && entry.RunID != quarantineRunID {
+					continue
+				}
+				if quarantineConstraint != "" && entry.Constraint != quarantineConstraint {
+					continue
+				}
+				filtered = append(filtered, entry)
+			}
+
+			formatter := output.NewFormatter(outputFormat)
+			content, err := formatter.FormatQuarantineEntries(filtered)
+			if err != nil {
+				return newConfigError("failed to format quarantine entries", err)
+			}
+			return saveOutput(content, cmd)
+		},
+	}
+}
+
+// newQuarantineRestoreCmd creates the quarantine restore command
+func newQuarantineRestoreCmd() *cobra.Command {
+	var skipConflicts bool
+
+	cmd := &cobra.Command{
+		Use:   "restore <table>",
+		Short: "Move quarantined rows back into their table",
+		Long: `Moves quarantined rows back into the original table and removes them from the
+quarantine table, in a single transaction. Use --run-id and --constraint to
+restore only some of them.
+
+Rows whose key has been re-used in the table since they were quarantined are
+conflicts. Any conflict aborts the restore unless --skip-conflicts is given, in
+which case the conflicting rows stay in quarantine.
+
+Examples:
+  migrator quarantine restore orders --dry-run
+  migrator quarantine restore orders --run-id 20240101T120000Z-a1b2c3 --confirm`,
+		Args: cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			tableName := args[0]
+
+			// Default to dry-run for safety, as with the fix commands
+			if !cmd.Flags().Changed("dry-run") && !cmd.Flags().Changed("confirm") {
+				dryRun = true
+			}
+			if confirmChanges && !cmd.Flags().Changed("dry-run") {
+				dryRun = false
+			}
+			if !dryRun && !confirmChanges {
+				return newConfigError("must use --confirm flag when not in dry-run mode", nil)
+			}
+
+			db, cfg, dbConfig, err := connectQuarantineDatabase(cmd)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			fmt.Printf("♻️  Restore Quarantined Rows\n")
+			fmt.Printf("   Database: %s\n", dbConfig.Database)
+			fmt.Printf("   Table: %s\n", tableName)
+			fmt.Printf("   Dry Run: %v\n", dryRun)
+			fmt.Printf("\n")
+
+			var recorder *audit.Recorder
+			if !dryRun {
+				recorder = startAudit("quarantine restore", "restore", dbConfig)
+			}
+
+			result, err := db.RestoreQuarantine(tableName, getQuarantineFilterFromFlags(), dryRun, skipConflicts)
+			if err != nil {
+				return finishAudit(cfg, db, recorder, audit.OutcomeFailed, 0, err, newInternalError("failed to restore quarantined rows", err))
+			}
+
+			if len(result.ConflictKeys) > 0 {
+				fmt.Printf("⚠️  Conflicts (%d): keys already present in %s\n", len(result.ConflictKeys), tableName)
+				for _, key := range result.ConflictKeys {
+					fmt.Printf("   • %s\n", key)
+				}
+				fmt.Printf("\n")
+			}
+
+			fmt.Printf("📊 Rows to restore: %d\n", result.Restored)
+
+			if len(result.ConflictKeys) > 0 && !skipConflicts {
+				fmt.Printf("\n❌ Nothing was restored because of conflicts; use --skip-conflicts to restore the other rows\n")
+				abortErr := newIssuesFoundError("restore aborted because of conflicts")
+				return finishAudit(cfg, db, recorder, audit.OutcomeRolledBack, 0, abortErr, abortErr)
+			}
+
+			if dryRun {
+				fmt.Printf("\n💡 To restore these rows, run with --confirm flag and without --dry-run\n")
+				return nil
+			}
+			fmt.Printf("\n✅ Restored %d rows into %s\n", result.Restored, tableName)
+
+			recorder.AddStatement(models.AuditStatement{
+				Table:       tableName,
+				Description: fmt.Sprintf("restore rows from %s", database.QuarantineTableName(tableName)),
+				Rows:        result.Restored,
+			})
+			return finishAudit(cfg, db, recorder, audit.OutcomeCommitted, result.Restored, nil, nil)
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be restored without making changes")
+	cmd.Flags().BoolVar(&confirmChanges, "confirm", false, "Confirm that you want to restore the rows (required for non-dry-run)")
+	cmd.Flags().BoolVar(&skipConflicts, "skip-conflicts", false, "Restore the rows that do not conflict and leave the others in quarantine")
+
+	return cmd
+}
+
+// newQuarantinePurgeCmd creates the quarantine purge command
+func newQuarantinePurgeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "purge <table>",
+		Short: "Permanently delete quarantined rows",
+		Long: `Permanently deletes quarantined rows of a table, optionally only those of one
+fix run (--run-id), one constraint (--constraint) or older than a given age
+(--older-than). With --drop and no filter the quarantine table is dropped.
+
+⚠️  WARNING: Purged rows cannot be restored, not even with 'fix undo'.
+
+Examples:
+  migrator quarantine purge orders --older-than 720h --dry-run
+  migrator quarantine purge orders --drop --confirm`,
+		Args: cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			tableName := args[0]
+
+			if !cmd.Flags().Changed("dry-run") && !cmd.Flags().Changed("confirm") {
+				dryRun = true
+			}
+			if confirmChanges && !cmd.Flags().Changed("dry-run") {
+				dryRun = false
+			}
+			if !dryRun && !confirmChanges {
+				return newConfigError("must use --confirm flag when not in dry-run mode", nil)
+			}
+
+			filter := getQuarantineFilterFromFlags()
+			if quarantineDrop && filter != (database.QuarantineFilter{}) {
+				return newConfigError("--drop cannot be combined with --run-id, --constraint or --older-than", nil)
+			}
+
+			db, cfg, dbConfig, err := connectQuarantineDatabase(cmd)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			fmt.Printf("🗑️  Purge Quarantined Rows\n")
+			fmt.Printf("   Database: %s\n", dbConfig.Database)
+			fmt.Printf("   Table: %s\n", database.QuarantineTableName(tableName))
+			fmt.Printf("   Dry Run: %v\n", dryRun)
+			fmt.Printf("\n")
+
+			var recorder *audit.Recorder
+			if !dryRun {
+				recorder = startAudit("quarantine purge", "purge", dbConfig)
+			}
+
+			count, err := db.PurgeQuarantine(tableName, filter, quarantineDrop, dryRun)
+			if err != nil {
+				return finishAudit(cfg, db, recorder, audit.OutcomeFailed, 0, err, newInternalError("failed to purge quarantined rows", err))
+			}
+
+			fmt.Printf("📊 Rows to purge: %d\n", count)
+			if dryRun {
+				fmt.Printf("\n💡 To purge these rows, run with --confirm flag and without --dry-run\n")
+				return nil
+			}
+
+			description := fmt.Sprintf("delete rows from %s", database.QuarantineTableName(tableName))
+			if quarantineDrop {
+				fmt.Printf("\n✅ Dropped %s (%d rows)\n", database.QuarantineTableName(tableName), count)
+				description = fmt.Sprintf("drop %s", database.QuarantineTableName(tableName))
+			} else {
+				fmt.Printf("\n✅ Purged %d rows\n", count)
+			}
+
+			recorder.AddStatement(models.AuditStatement{Table: tableName, Description: description, Rows: count})
+			return finishAudit(cfg, db, recorder, audit.OutcomeCommitted, count, nil, nil)
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be purged without making changes")
+	cmd.Flags().BoolVar(&confirmChanges, "confirm", false, "Confirm that you want to purge the rows (required for non-dry-run)")
+	cmd.Flags().DurationVar(&quarantineOlderThan, "older-than", 0, "Only rows quarantined at least this long ago (e.g. 720h)")
+	cmd.Flags().BoolVar(&quarantineDrop, "drop", false, "Drop the quarantine table instead of deleting its rows")
+
+	return cmd
+}
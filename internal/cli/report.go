@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/nkamuo/go-db-migration/internal/output"
+	"github.com/nkamuo/go-db-migration/internal/report"
+	"github.com/spf13/cobra"
+)
+
+// newReportCmd creates the report command group
+func newReportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Work with saved validation reports",
+		Long: `Commands for working with validation reports saved by the validate commands,
+such as comparing the reports of two runs to follow the trend over time.`,
+	}
+
+	cmd.AddCommand(newReportDiffCmd())
+
+	return cmd
+}
+
+// newReportDiffCmd creates the report diff command
+func newReportDiffCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff <old-report> <new-report>",
+		Short: "Compare two validation reports",
+		Long: `Compares two validation reports saved with --format json, yaml or ndjson, for
+example the reports of two nightly runs, to tell whether things are getting better
+or worse.
+
+This command will:
+- List new issues, found only in the new report
+- List resolved issues, found only in the old report
+- Count unchanged issues, found in both
+- Show the change in issues per issue type and per table
+- Summarize the trend: improving, worsening or unchanged
+
+Issues are matched by type, table, column and row identifier. The report format is
+taken from the file extension (.json, .yaml, .yml, .ndjson or .jsonl).
+
+Examples:
+  migrator report diff yesterday.json today.json
+  migrator report diff yesterday.json today.json --format markdown -o trend.md
+  migrator report diff yesterday.json today.json --format sarif -o trend.sarif`,
+		Aliases: []string{"compare"},
+		Args:    cobra.ExactArgs(2),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Disable usage on error for clean output
+			cmd.SilenceUsage = true
+
+			oldReport, err := report.Load(args[0])
+			if err != nil {
+				return newConfigError(fmt.Sprintf("failed to load report %s", args[0]), err)
+			}
+			newReport, err := report.Load(args[1])
+			if err != nil {
+				return newConfigError(fmt.Sprintf("failed to load report %s", args[1]), err)
+			}
+
+			diff := report.Compare(args[0], oldReport, args[1], newReport)
+
+			formatter := output.NewFormatter(outputFormat)
+			content, err := formatter.FormatReportDiff(diff)
+			if err != nil {
+				return newConfigError("failed to format output", err)
+			}
+
+			return saveOutput(content, cmd)
+		},
+	}
+}
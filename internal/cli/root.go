@@ -43,17 +43,23 @@ Examples:
   migrator connection test --connection "JAMES Database"`,
 
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		// Skip validation for help, version, and schema info commands
+		// Skip validation for help, version, schema info and report diff commands
 		if cmd.Name() == "help" || cmd.Name() == "version" ||
 			(cmd.Parent() != nil && cmd.Parent().Name() == "schema" && cmd.Name() == "info") ||
-			(cmd.Parent() != nil && cmd.Parent().Name() == "schema" && cmd.Name() == "validate") {
+			(cmd.Parent() != nil && cmd.Parent().Name() == "schema" && cmd.Name() == "validate") ||
+			(cmd.Parent() != nil && cmd.Parent().Name() == "report" && cmd.Name() == "diff") {
 			return nil
 		}
 
 		// Load and validate configuration
 		cfg, err := config.LoadConfig(cfgFile)
 		if err != nil {
-			return fmt.Errorf("failed to load configuration: %w", err)
+			cmd.SilenceUsage = true
+			cfgErr := newConfigError("failed to load configuration", err)
+			if isJSONOutput() {
+				return failCommand(cmd, cfgErr)
+			}
+			return cfgErr
 		}
 
 		// Store config in context for subcommands
@@ -64,10 +70,11 @@ Examples:
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// The process exit code reflects the kind of failure (see the Exit* constants).
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(exitCodeForError(err))
 	}
 }
 
@@ -76,14 +83,22 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./conf.json)")
 	rootCmd.PersistentFlags().StringVarP(&connectionName, "connection", "c", "", "database connection name from config")
 	rootCmd.PersistentFlags().StringVarP(&schemaFile, "schema", "s", "", "target schema file (default is ./schema.json)")
-	rootCmd.PersistentFlags().StringVarP(&outputFormat, "format", "f", "table", "output format (table, json, yaml, csv)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "format", "f", "table", "output format (table, json, yaml, csv, html, markdown, junit, sarif, ndjson, mermaid, dot, plantuml)")
 	rootCmd.PersistentFlags().StringVarP(&outputFile, "output", "o", "", "output file (default is stdout)")
 
+	// Command line mistakes are configuration errors
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return newConfigError("invalid command line", err)
+	})
+
 	// Add command groups
 	rootCmd.AddCommand(newValidateCmd())
 	rootCmd.AddCommand(newSchemaCmd())
 	rootCmd.AddCommand(newConnectionCmd())
 	rootCmd.AddCommand(newFixCmd())
+	rootCmd.AddCommand(newQuarantineCmd())
+	rootCmd.AddCommand(newReportCmd())
+	rootCmd.AddCommand(newHistoryCmd())
 	rootCmd.AddCommand(newVersionCmd())
 }
 
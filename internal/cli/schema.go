@@ -1,9 +1,13 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/nkamuo/go-db-migration/internal/database"
+	"github.com/nkamuo/go-db-migration/internal/models"
 	"github.com/nkamuo/go-db-migration/internal/output"
 	"github.com/nkamuo/go-db-migration/internal/schema"
 	"github.com/spf13/cobra"
@@ -25,6 +29,9 @@ validating schema files, and generating schema reports.`,
 	cmd.AddCommand(newSchemaInfoCmd())
 	cmd.AddCommand(newSchemaExportCmd())
 	cmd.AddCommand(newSchemaSnapshotCmd())
+	cmd.AddCommand(newSchemaInferFKsCmd())
+	cmd.AddCommand(newSchemaDiagramCmd())
+	cmd.AddCommand(newSchemaDocsCmd())
 
 	return cmd
 }
@@ -49,19 +56,19 @@ This command will:
 			// Load configuration
 			cfg, err := getConfigFromCmd(cmd)
 			if err != nil {
-				return err
+				return newConfigError("failed to load configuration", err)
 			}
 
 			// Get connection config
 			dbConfig, err := cfg.GetConnectionConfig(connectionName)
 			if err != nil {
-				return err
+				return newConfigError("failed to get connection config", err)
 			}
 
 			// Connect to database
 			db, err := database.NewConnection(dbConfig)
 			if err != nil {
-				return fmt.Errorf("failed to connect to database: %w", err)
+				return newConnectionError("failed to connect to database", err)
 			}
 			defer db.Close()
 
@@ -74,11 +81,12 @@ This command will:
 			// Load target schema
 			targetSchema, err := schema.LoadSchema(getSchemaFilePath())
 			if err != nil {
-				return fmt.Errorf("failed to load target schema: %w", err)
+				return newConfigError("failed to load target schema", err)
 			}
 
 			// Compare schemas
 			comparison := schema.CompareSchemas(currentSchema, targetSchema)
+			recordComparisonHistory(cmd, cfg, targetSchema, comparison)
 
 			// Format and output results
 			formatter := output.NewFormatter(outputFormat)
@@ -201,7 +209,7 @@ This includes checking for:
 			// Load target schema
 			targetSchema, err := schema.LoadSchema(getSchemaFilePath())
 			if err != nil {
-				return fmt.Errorf("failed to load target schema: %w", err)
+				return newConfigError("failed to load target schema", err)
 			}
 
 			// Validate schema structure
@@ -224,7 +232,7 @@ This includes checking for:
 				}
 			}
 
-			return fmt.Errorf("schema validation failed")
+			return newIssuesFoundError("schema validation failed")
 		},
 	}
 }
@@ -245,7 +253,7 @@ func newSchemaInfoCmd() *cobra.Command {
 			// Load target schema
 			targetSchema, err := schema.LoadSchema(getSchemaFilePath())
 			if err != nil {
-				return fmt.Errorf("failed to load target schema: %w", err)
+				return newConfigError("failed to load target schema", err)
 			}
 
 			// Create schema info
@@ -469,3 +477,350 @@ The snapshot format includes:
 		},
 	}
 }
+
+// newSchemaInferFKsCmd creates the schema infer-fks command
+func newSchemaInferFKsCmd() *cobra.Command {
+	var minConfidence float64
+	var merge bool
+
+	cmd := &cobra.Command{
+		Use:   "infer-fks",
+		Short: "Suggest undeclared foreign keys from naming and data",
+		Long: `Finds columns that look like references (e.g. customer_id, authorId) but have no
+declared foreign key constraint in the database, and suggests ForeignKey entries for them.
+
+This command will:
+- Match column names against table names (customer_id -> customers.id)
+- Require the column type to be compatible with the referenced key column
+- Measure the percentage of distinct values that exist in the referenced table
+- Score each suggestion with a confidence between 0 and 1
+
+Use --merge to print the target schema file with the suggestions added, ready to
+replace schema.json.
+
+Examples:
+  migrator schema infer-fks
+  migrator schema infer-fks --min-confidence 0.8 --format json
+  migrator schema infer-fks --merge -o schema.json`,
+		Aliases: []string{"infer-foreign-keys", "suggest-fks"},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Disable usage on error for clean output
+			cmd.SilenceUsage = true
+
+			if minConfidence < 0 || minConfidence > 1 {
+				return newConfigError(fmt.Sprintf("--min-confidence must be between 0 and 1, got %v", minConfidence), nil)
+			}
+
+			// Load configuration
+			cfg, err := getConfigFromCmd(cmd)
+			if err != nil {
+				return newConfigError("failed to load configuration", err)
+			}
+
+			// Get connection config
+			dbConfig, err := cfg.GetConnectionConfig(connectionName)
+			if err != nil {
+				return newConfigError("failed to get connection config", err)
+			}
+
+			// Connect to database
+			db, err := database.NewConnection(dbConfig)
+			if err != nil {
+				return newConnectionError("failed to connect to database", err)
+			}
+			defer db.Close()
+
+			fmt.Fprintf(os.Stderr, "🔍 Reading schema from database '%s'...\n", dbConfig.Database)
+			currentSchema, err := db.GetCurrentSchema()
+			if err != nil {
+				return newInternalError("failed to get current schema", err)
+			}
+			if err := schema.ApplyRowFilters(currentSchema, cfg.Validation.RowFilters); err != nil {
+				return newConfigError("invalid row filters", err)
+			}
+
+			candidates := schema.FindForeignKeyCandidates(currentSchema)
+			fmt.Fprintf(os.Stderr, "🔗 Checking %d candidate relationship(s) against the data...\n", len(candidates))
+
+			var suggestions []models.ForeignKeySuggestion
+			for _, candidate := range candidates {
+				rowFilter := ""
+				if table := currentSchema.GetTable(candidate.ForeignKey.TableName); table != nil {
+					rowFilter = table.RowFilter
+				}
+
+				distinct, matched, err := db.MeasureForeignKeyMatch(candidate.ForeignKey, rowFilter)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "⚠️  Skipping %s.%s -> %s.%s: %v\n",
+						candidate.ForeignKey.TableName, candidate.ForeignKey.ColumnName,
+						candidate.ForeignKey.ReferencedTable, candidate.ForeignKey.ReferencedColumn, err)
+					continue
+				}
+
+				suggestion := schema.NewForeignKeySuggestion(candidate, distinct, matched)
+				if suggestion.Confidence >= minConfidence {
+					suggestions = append(suggestions, suggestion)
+				}
+			}
+			schema.SortForeignKeySuggestions(suggestions)
+
+			if merge {
+				targetSchema, err := schema.LoadSchema(getSchemaFilePath())
+				if err != nil {
+					return newConfigError("failed to load target schema", err)
+				}
+
+				added := schema.MergeForeignKeySuggestions(targetSchema, suggestions)
+				fmt.Fprintf(os.Stderr, "✅ Added %d foreign key(s) to %s\n", added, getSchemaFilePath())
+
+				data, err := json.MarshalIndent(targetSchema, "", "    ")
+				if err != nil {
+					return newInternalError("failed to marshal merged schema", err)
+				}
+				return saveOutput(string(data)+"\n", cmd)
+			}
+
+			formatter := output.NewFormatter(outputFormat)
+			content, err := formatter.FormatForeignKeySuggestions(suggestions)
+			if err != nil {
+				return newConfigError("failed to format output", err)
+			}
+
+			return saveOutput(content, cmd)
+		},
+	}
+
+	cmd.Flags().Float64Var(&minConfidence, "min-confidence", 0.5, "Only report suggestions with at least this confidence (0-1)")
+	cmd.Flags().BoolVar(&merge, "merge", false, "Output the target schema file with the suggestions merged in")
+
+	return cmd
+}
+
+// newSchemaDiagramCmd creates the schema diagram command
+func newSchemaDiagramCmd() *cobra.Command {
+	var fromDB bool
+	var tables []string
+	var depth int
+	var highlightDiff bool
+	var hideColumns bool
+
+	cmd := &cobra.Command{
+		Use:   "diagram [schema-file]",
+		Short: "Render the schema as an entity-relationship diagram",
+		Long: `Renders a schema as an entity-relationship diagram in Mermaid, Graphviz DOT or
+PlantUML. The schema is read from the given file, the target schema file, or with
+--from-db from the current database.
+
+This command will:
+- Draw every table with its columns, marking primary and foreign key columns
+- Draw a relationship for every foreign key between the drawn tables
+- With --table, draw only the given tables and the tables within --depth foreign key hops
+- With --highlight-diff, color the tables that differ between the database and the target schema
+
+Select the diagram language with --format mermaid, dot or plantuml (Mermaid by default).
+
+Examples:
+  migrator schema diagram -o schema.mmd
+  migrator schema diagram --format dot | dot -Tsvg -o schema.svg
+  migrator schema diagram --from-db --table orders --depth 2 --format plantuml
+  migrator schema diagram --highlight-diff`,
+		Aliases: []string{"erd"},
+		Args:    cobra.MaximumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Disable usage on error for clean output
+			cmd.SilenceUsage = true
+
+			if depth < 0 {
+				return newConfigError(fmt.Sprintf("--depth must not be negative, got %d", depth), nil)
+			}
+
+			schemaPath := getSchemaFilePath()
+			if len(args) > 0 {
+				schemaPath = args[0]
+			}
+
+			var diagramSchema, targetSchema models.Schema
+			var err error
+			if !fromDB || highlightDiff {
+				targetSchema, err = schema.LoadSchema(schemaPath)
+				if err != nil {
+					return newConfigError("failed to load schema", err)
+				}
+				diagramSchema = targetSchema
+			}
+
+			opts := output.DiagramOptions{HideColumns: hideColumns}
+			if fromDB || highlightDiff {
+				// Load configuration
+				cfg, err := getConfigFromCmd(cmd)
+				if err != nil {
+					return newConfigError("failed to load configuration", err)
+				}
+
+				// Get connection config
+				dbConfig, err := cfg.GetConnectionConfig(connectionName)
+				if err != nil {
+					return newConfigError("failed to get connection config", err)
+				}
+
+				// Connect to database
+				db, err := database.NewConnection(dbConfig)
+				if err != nil {
+					return newConnectionError("failed to connect to database", err)
+				}
+				defer db.Close()
+
+				currentSchema, err := db.GetCurrentSchema()
+				if err != nil {
+					return newInternalError("failed to get current schema", err)
+				}
+				if fromDB {
+					diagramSchema = currentSchema
+				}
+
+				if highlightDiff {
+					comparison := schema.CompareSchemas(currentSchema, targetSchema)
+					opts.Highlight = make(map[string]string)
+					for _, tableName := range comparison.MissingTables {
+						opts.Highlight[tableName] = output.HighlightMissing
+					}
+					for _, tableName := range comparison.ExtraTables {
+						opts.Highlight[tableName] = output.HighlightExtra
+					}
+					for tableName := range comparison.TableDifferences {
+						opts.Highlight[tableName] = output.HighlightChanged
+					}
+					// Tables missing from the database are drawn from the target schema,
+					// so a diagram of the database shows them too
+					if fromDB {
+						for _, tableName := range comparison.MissingTables {
+							if table := targetSchema.GetTable(tableName); table != nil {
+								diagramSchema = append(diagramSchema, *table)
+							}
+						}
+					}
+				}
+			}
+
+			if len(tables) > 0 {
+				diagramSchema, err = diagramSchema.Neighborhood(tables, depth)
+				if err != nil {
+					return newConfigError("invalid --table", err)
+				}
+			}
+
+			formatter := output.NewFormatter(outputFormat)
+			content, err := formatter.FormatSchemaDiagram(diagramSchema, opts)
+			if err != nil {
+				return newConfigError("failed to format diagram", err)
+			}
+
+			return saveOutput(content, cmd)
+		},
+	}
+
+	cmd.Flags().BoolVar(&fromDB, "from-db", false, "Read the schema from the current database instead of a schema file")
+	cmd.Flags().StringSliceVar(&tables, "table", nil, "Only draw these tables and their foreign key neighbourhood (repeatable)")
+	cmd.Flags().IntVar(&depth, "depth", 1, "Number of foreign key hops around --table to include")
+	cmd.Flags().BoolVar(&highlightDiff, "highlight-diff", false, "Highlight tables that differ between the database and the target schema")
+	cmd.Flags().BoolVar(&hideColumns, "hide-columns", false, "Draw tables without their columns")
+
+	return cmd
+}
+
+// newSchemaDocsCmd creates the schema docs command
+func newSchemaDocsCmd() *cobra.Command {
+	var fromDB bool
+	var outputDir string
+
+	cmd := &cobra.Command{
+		Use:   "docs [schema-file]",
+		Short: "Generate a data dictionary of the schema",
+		Long: `Generates a browsable data dictionary of a schema as Markdown or static HTML pages.
+The schema is read from the given file, the target schema file, or with --from-db from
+the current database, which also picks up table and column comments.
+
+The data dictionary contains:
+- An index page with the schema statistics and a list of all tables
+- One page per table with its columns, types, nullability, defaults and comments
+- Links to the tables a table references and to the tables referencing it
+
+Select Markdown (the default) or HTML with --format.
+
+Examples:
+  migrator schema docs
+  migrator schema docs --from-db --format html --output-dir site/schema`,
+		Aliases: []string{"dictionary"},
+		Args:    cobra.MaximumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Disable usage on error for clean output
+			cmd.SilenceUsage = true
+
+			schemaPath := getSchemaFilePath()
+			if len(args) > 0 {
+				schemaPath = args[0]
+			}
+
+			var docsSchema models.Schema
+			var err error
+			if fromDB {
+				// Load configuration
+				cfg, err := getConfigFromCmd(cmd)
+				if err != nil {
+					return newConfigError("failed to load configuration", err)
+				}
+
+				// Get connection config
+				dbConfig, err := cfg.GetConnectionConfig(connectionName)
+				if err != nil {
+					return newConfigError("failed to get connection config", err)
+				}
+
+				// Connect to database
+				db, err := database.NewConnection(dbConfig)
+				if err != nil {
+					return newConnectionError("failed to connect to database", err)
+				}
+				defer db.Close()
+
+				docsSchema, err = db.GetCurrentSchema()
+				if err != nil {
+					return newInternalError("failed to get current schema", err)
+				}
+				schemaPath = ""
+			} else {
+				docsSchema, err = schema.LoadSchema(schemaPath)
+				if err != nil {
+					return newConfigError("failed to load schema", err)
+				}
+			}
+
+			formatter := output.NewFormatter(outputFormat)
+			pages, err := formatter.FormatDataDictionary(schemaPath, docsSchema)
+			if err != nil {
+				return newConfigError("failed to generate data dictionary", err)
+			}
+
+			for name, content := range pages {
+				path := filepath.Join(outputDir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					return newInternalError("failed to create output directory", err)
+				}
+				if err := output.WriteToFile(content, path); err != nil {
+					return newInternalError(fmt.Sprintf("failed to write %s", path), err)
+				}
+			}
+
+			fmt.Printf("✅ Data dictionary of %d tables written to %s\n", len(docsSchema), outputDir)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&fromDB, "from-db", false, "Read the schema from the current database instead of a schema file")
+	cmd.Flags().StringVar(&outputDir, "output-dir", "docs", "Directory to write the data dictionary to")
+
+	return cmd
+}
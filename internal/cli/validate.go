@@ -2,6 +2,8 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/nkamuo/go-db-migration/internal/config"
 	"github.com/nkamuo/go-db-migration/internal/database"
@@ -17,6 +19,16 @@ var (
 	ignoreMissingColumns bool
 	stopOnFirstError     bool
 	maxIssuesPerTable    int
+	failOn               string
+	crossBatchSize       int
+	metricsFile          string
+)
+
+// Supported --fail-on thresholds
+const (
+	FailOnNone    = "none"
+	FailOnError   = "error"
+	FailOnWarning = "warning"
 )
 
 // getValidationConfigFromFlags creates validation config from command line flags
@@ -29,6 +41,341 @@ func getValidationConfigFromFlags() config.ValidationConfig {
 	}
 }
 
+// validationContext holds the resources shared by the validate subcommands
+type validationContext struct {
+	cfg          *config.Config
+	dbConfig     *config.DBConfig
+	db           *database.DB
+	targetSchema models.Schema
+}
+
+// openValidationContext loads the configuration, connects to the database and
+// loads the target schema. Diagnostics are printed for any failure and the
+// returned error carries the matching exit code.
+func openValidationContext(cmd *cobra.Command) (*validationContext, error) {
+	showHints := !isJSONOutput()
+
+	if failOn != FailOnNone && failOn != FailOnError && failOn != FailOnWarning {
+		if showHints {
+			fmt.Printf("❌ Invalid --fail-on value '%s' (must be 'none', 'error' or 'warning')\n\n", failOn)
+		}
+		return nil, failCommand(cmd, newConfigError(fmt.Sprintf("invalid --fail-on value '%s'", failOn), nil))
+	}
+
+	// Load configuration
+	cfg, err := getConfigFromCmd(cmd)
+	if err != nil {
+		if showHints {
+			fmt.Printf("❌ Configuration Error\n\n")
+			fmt.Printf("Failed to load configuration: %v\n\n", err)
+			fmt.Printf("💡 Solutions:\n")
+			fmt.Printf("   • Check if conf.json exists in the current directory\n")
+			fmt.Printf("   • Verify JSON syntax is valid\n")
+			fmt.Printf("   • Use --config flag to specify a different config file\n\n")
+		}
+		return nil, failCommand(cmd, newConfigError("failed to load configuration", err))
+	}
+
+	// Get connection config
+	dbConfig, err := cfg.GetConnectionConfig(connectionName)
+	if err != nil {
+		if showHints {
+			fmt.Printf("❌ Connection Configuration Error\n\n")
+			fmt.Printf("Failed to get connection config: %v\n\n", err)
+			fmt.Printf("💡 Solutions:\n")
+			fmt.Printf("   • Check connection name in conf.json\n")
+			fmt.Printf("   • Use --connection flag to specify a valid connection\n")
+			fmt.Printf("   • Verify default connection is properly configured\n\n")
+		}
+		return nil, failCommand(cmd, newConfigError("failed to get connection config", err))
+	}
+
+	// Connect to database
+	db, err := database.NewConnection(dbConfig)
+	if err != nil {
+		if showHints {
+			fmt.Printf("❌ Database Connection Failed\n\n")
+			fmt.Printf("Database: %s\n", dbConfig.Database)
+			fmt.Printf("Host: %s:%d\n", dbConfig.Host, dbConfig.Port)
+			fmt.Printf("User: %s\n\n", dbConfig.Username)
+			fmt.Printf("Error: %v\n\n", err)
+			fmt.Printf("💡 Common Solutions:\n")
+			fmt.Printf("   • Verify database server is running\n")
+			fmt.Printf("   • Check connection details in config are correct\n")
+			fmt.Printf("   • Ensure user has required permissions\n")
+			fmt.Printf("   • Check firewall/network connectivity\n")
+			fmt.Printf("   • Verify pg_hba.conf allows your IP address\n\n")
+		}
+		connErr := newConnectionError("failed to connect to database", err)
+		connErr.Details = map[string]interface{}{
+			"database": dbConfig.Database,
+			"host":     dbConfig.Host,
+			"port":     dbConfig.Port,
+		}
+		return nil, failCommand(cmd, connErr)
+	}
+
+	// Load target schema
+	targetSchema, err := schema.LoadSchema(getSchemaFilePath())
+	if err != nil {
+		db.Close()
+		if showHints {
+			fmt.Printf("❌ Schema Loading Failed\n\n")
+			fmt.Printf("Schema file: %s\n\n", getSchemaFilePath())
+			fmt.Printf("Error: %v\n\n", err)
+			fmt.Printf("💡 Solutions:\n")
+			fmt.Printf("   • Verify schema file exists and is readable\n")
+			fmt.Printf("   • Check JSON format is valid\n")
+			fmt.Printf("   • Use --schema flag to specify correct file path\n\n")
+		}
+		schemaErr := newConfigError("failed to load target schema", err)
+		schemaErr.Details = map[string]interface{}{"schema_file": getSchemaFilePath()}
+		return nil, failCommand(cmd, schemaErr)
+	}
+
+	// Scope validation to the configured rows (e.g. skip soft-deleted records)
+	if err := schema.ApplyRowFilters(targetSchema, cfg.Validation.RowFilters); err != nil {
+		db.Close()
+		if showHints {
+			fmt.Printf("❌ Invalid Row Filter Configuration\n\n")
+			fmt.Printf("Error: %v\n\n", err)
+		}
+		return nil, failCommand(cmd, newConfigError("invalid row filter configuration", err))
+	}
+
+	return &validationContext{
+		cfg:          cfg,
+		dbConfig:     dbConfig,
+		db:           db,
+		targetSchema: targetSchema,
+	}, nil
+}
+
+// targetTable returns a table of the target schema, or a table with just its name when the
+// schema does not have it
+func (v *validationContext) targetTable(tableName string) models.Table {
+	for _, table := range v.targetSchema {
+		if table.TableName == tableName {
+			return table
+		}
+	}
+	return models.Table{TableName: tableName}
+}
+
+// countRows counts the rows of a target table that match its row filter
+func (v *validationContext) countRows(tableName string) (int64, error) {
+	return v.db.GetTableRowCount(tableName, v.targetTable(tableName).RowFilter)
+}
+
+// countViolatingRows counts the rows of a target table, within its row filter, that
+// violate any of the given checks
+func (v *validationContext) countViolatingRows(tableName string, checks []string) (int64, bool, error) {
+	return v.db.CountViolatingRows(v.targetTable(tableName), checks)
+}
+
+// tableSummaries builds the summary of every checked table, with exact row counts where
+// they can be queried
+func (v *validationContext) tableSummaries(tables *output.TableSummaryBuilder) []models.TableValidationSummary {
+	return tables.Build(v.countRows, v.countViolatingRows, v.cfg.GetValidationConfig().MaxIssuesPerTable)
+}
+
+// connectionPool opens named connections from the configuration on demand, so that
+// cross-connection references to the same database share one connection
+type connectionPool struct {
+	cfg   *config.Config
+	conns map[string]*database.DB
+}
+
+// newConnectionPool creates an empty connection pool
+func newConnectionPool(cfg *config.Config) *connectionPool {
+	return &connectionPool{cfg: cfg, conns: make(map[string]*database.DB)}
+}
+
+// get returns an open connection for the named connection
+func (p *connectionPool) get(name string) (*database.DB, error) {
+	if db, ok := p.conns[name]; ok {
+		return db, nil
+	}
+
+	dbConfig, err := p.cfg.GetConnectionConfig(name)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := database.NewConnection(dbConfig)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[name] = db
+	return db, nil
+}
+
+// closeAll closes every connection opened by the pool
+func (p *connectionPool) closeAll() {
+	for _, db := range p.conns {
+		db.Close()
+	}
+}
+
+// issueStream is the NDJSON stream of the running validate command, if any. Failures are
+// written to it as a trailing error record.
+var issueStream *output.NDJSONWriter
+
+// issueSink receives the issues of a validate command. It collects them for the report,
+// or with --format ndjson streams them straight to the output as they are found.
+type issueSink struct {
+	issues []models.ValidationIssue
+	file   *os.File
+	checks []models.CheckDuration
+	// tables summarizes every table, counting issues against the running check
+	tables       output.TableSummaryBuilder
+	currentCheck string
+}
+
+// newIssueSink creates the issue sink of a validate command, opening the NDJSON stream
+// when one was requested
+func newIssueSink(cmd *cobra.Command) (*issueSink, error) {
+	sink := &issueSink{}
+	if output.OutputFormat(outputFormat) != output.FormatNDJSON {
+		return sink, nil
+	}
+
+	out := os.Stdout
+	if outputFile != "" {
+		file, err := os.Create(outputFile)
+		if err != nil {
+			return nil, failCommand(cmd, newInternalError("failed to create output file", err))
+		}
+		sink.file = file
+		out = file
+	}
+	issueStream = output.NewNDJSONWriter(out, connectionName)
+	return sink, nil
+}
+
+// add receives one issue, recording the check that found it
+func (s *issueSink) add(issue models.ValidationIssue) error {
+	issue.Check = s.currentCheck
+	s.tables.AddIssue(s.currentCheck, issue)
+	if issueStream != nil {
+		return issueStream.WriteIssue(issue)
+	}
+	s.issues = append(s.issues, issue)
+	return nil
+}
+
+// check runs one check of the validation, timing it for the metrics
+func (s *issueSink) check(name string, run func() error) error {
+	s.currentCheck = name
+	start := time.Now()
+	err := run()
+	s.checks = append(s.checks, models.CheckDuration{Check: name, Seconds: time.Since(start).Seconds()})
+	return err
+}
+
+// checkTables runs a check of the target schema one table at a time, timing every table
+// for the table summary
+func (s *issueSink) checkTables(name string, targetSchema models.Schema, run func(tables models.Schema) error) error {
+	return s.check(name, func() error {
+		for _, table := range targetSchema {
+			start := time.Now()
+			err := run(models.Schema{table})
+			s.tables.AddTime(table.TableName, name, time.Since(start))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// addAll receives a list of issues
+func (s *issueSink) addAll(issues []models.ValidationIssue) error {
+	for _, issue := range issues {
+		if err := s.add(issue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish writes the metrics file and the report, or the trailing summary of the stream,
+// records the report in the run history, then applies the --fail-on threshold
+func (s *issueSink) finish(cmd *cobra.Command, vctx *validationContext) error {
+	tables := vctx.tableSummaries(&s.tables)
+	if issueStream == nil {
+		report := output.CreateValidationReport(connectionName, s.issues)
+		report.Tables = tables
+		if err := writeValidationMetrics(cmd, vctx, s.checks, report.Summary, tables); err != nil {
+			return err
+		}
+		recordValidationHistory(cmd, vctx, report)
+		return writeValidationReport(cmd, report)
+	}
+
+	// Metrics are written while the stream is open, so that a failure still ends it
+	if err := writeValidationMetrics(cmd, vctx, s.checks, issueStream.Summary(), tables); err != nil {
+		return err
+	}
+	report, err := issueStream.Close(tables)
+	issueStream = nil
+	if err != nil {
+		return failCommand(cmd, newInternalError("failed to write validation report", err))
+	}
+	recordValidationHistory(cmd, vctx, report)
+	return checkFailThreshold(cmd, report)
+}
+
+// close closes the output file of the stream, if any
+func (s *issueSink) close() {
+	issueStream = nil
+	if s.file != nil {
+		s.file.Close()
+	}
+}
+
+// writeValidationReport formats and outputs the report, then applies the --fail-on threshold
+func writeValidationReport(cmd *cobra.Command, report *models.ValidationReport) error {
+	formatter := output.NewFormatter(outputFormat)
+	content, err := formatter.FormatValidationReport(report)
+	if err != nil {
+		if !isJSONOutput() {
+			fmt.Printf("❌ Output Formatting Failed\n\n")
+			fmt.Printf("Error: %v\n\n", err)
+		}
+		return failCommand(cmd, newInternalError("failed to format validation report", err))
+	}
+
+	if err := saveOutput(content, cmd); err != nil {
+		return failCommand(cmd, newInternalError("failed to write validation report", err))
+	}
+
+	return checkFailThreshold(cmd, report)
+}
+
+// checkFailThreshold returns an ExitIssuesFound error when the report contains
+// issues at or above the --fail-on severity
+func checkFailThreshold(cmd *cobra.Command, report *models.ValidationReport) error {
+	failing := 0
+	switch failOn {
+	case FailOnError:
+		failing = report.Summary.ErrorCount
+	case FailOnWarning:
+		failing = report.Summary.ErrorCount + report.Summary.WarningCount
+	default:
+		return nil
+	}
+
+	if failing == 0 {
+		return nil
+	}
+
+	// The report itself was already written, so only a short note goes to stderr
+	cmd.SilenceErrors = true
+	fmt.Fprintf(os.Stderr, "❌ Validation failed: %d issue(s) at or above severity '%s'\n", failing, failOn)
+	return newIssuesFoundError(fmt.Sprintf("%d issue(s) at or above severity '%s'", failing, failOn))
+}
+
 // newValidateCmd creates the validate command group
 func newValidateCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -36,18 +383,34 @@ func newValidateCmd() *cobra.Command {
 		Short: "Validation commands for database migration readiness",
 		Long: `Commands to validate various aspects of the database to ensure
 migration readiness. This includes foreign key constraints, null value
-constraints, and comprehensive validation checks.`,
+constraints, and comprehensive validation checks.
+
+Exit codes:
+  0  Validation completed and no issues crossed the --fail-on threshold
+  1  Issues at or above the --fail-on severity were found
+  2  Configuration error (config file, connection name, schema file, flags)
+  3  Database connection error
+  4  Internal error (query or output failures)
+
+With --format json, failures are written as a JSON error envelope:
+  {"error": {"kind": "connection_error", "exit_code": 3, "message": "...", "cause": "..."}}`,
 	}
 
 	cmd.AddCommand(newValidateFKCmd())
 	cmd.AddCommand(newValidateNullCmd())
+	cmd.AddCommand(newValidatePolymorphicCmd())
+	cmd.AddCommand(newValidateCrossConnectionCmd())
 	cmd.AddCommand(newValidateAllCmd())
+	cmd.AddCommand(newValidateServeMetricsCmd())
 
 	// Add persistent flags that apply to all validate subcommands
 	cmd.PersistentFlags().BoolVar(&ignoreMissingTables, "ignore-missing-tables", false, "Skip validation for missing tables")
 	cmd.PersistentFlags().BoolVar(&ignoreMissingColumns, "ignore-missing-columns", false, "Skip validation for missing columns")
 	cmd.PersistentFlags().BoolVar(&stopOnFirstError, "stop-on-error", false, "Stop validation on first error")
 	cmd.PersistentFlags().IntVar(&maxIssuesPerTable, "max-issues", 1000, "Maximum issues to report per table")
+	cmd.PersistentFlags().StringVar(&failOn, "fail-on", FailOnNone, "Exit with code 1 when issues of this severity or higher are found: none|error|warning")
+	cmd.PersistentFlags().IntVar(&crossBatchSize, "batch-size", database.DefaultCrossConnectionBatchSize, "Number of key values checked per query for cross-connection references")
+	cmd.PersistentFlags().StringVar(&metricsFile, "metrics-file", "", "Write Prometheus metrics of the run to this file, in the node_exporter textfile format")
 
 	return cmd
 }
@@ -57,7 +420,7 @@ func newValidateFKCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "fk",
 		Short: "Validate foreign key constraints",
-		Long: `Validates foreign key constraints by identifying records that would violate 
+		Long: `Validates foreign key constraints by identifying records that would violate
 these constraints during migration.
 
 This command will:
@@ -71,91 +434,39 @@ This command will:
 			// Disable usage on error for clean output
 			cmd.SilenceUsage = true
 
-			// Load configuration
-			cfg, err := getConfigFromCmd(cmd)
+			vctx, err := openValidationContext(cmd)
 			if err != nil {
-				fmt.Printf("❌ Configuration Error\n\n")
-				fmt.Printf("Failed to load configuration: %v\n\n", err)
-				fmt.Printf("💡 Solutions:\n")
-				fmt.Printf("   • Check if conf.json exists in the current directory\n")
-				fmt.Printf("   • Verify JSON syntax is valid\n")
-				fmt.Printf("   • Use --config flag to specify a different config file\n\n")
-				return nil
+				return err
 			}
+			defer vctx.db.Close()
 
-			// Get connection config
-			dbConfig, err := cfg.GetConnectionConfig(connectionName)
+			sink, err := newIssueSink(cmd)
 			if err != nil {
-				fmt.Printf("❌ Connection Configuration Error\n\n")
-				fmt.Printf("Failed to get connection config: %v\n\n", err)
-				fmt.Printf("💡 Solutions:\n")
-				fmt.Printf("   • Check connection name in conf.json\n")
-				fmt.Printf("   • Use --connection flag to specify a valid connection\n")
-				fmt.Printf("   • Verify default connection is properly configured\n\n")
-				return nil
-			}
-
-			// Connect to database
-			db, err := database.NewConnection(dbConfig)
-			if err != nil {
-				fmt.Printf("❌ Database Connection Failed\n\n")
-				fmt.Printf("Database: %s\n", dbConfig.Database)
-				fmt.Printf("Host: %s:%d\n", dbConfig.Host, dbConfig.Port)
-				fmt.Printf("User: %s\n\n", dbConfig.Username)
-				fmt.Printf("Error: %v\n\n", err)
-				fmt.Printf("💡 Common Solutions:\n")
-				fmt.Printf("   • Verify database server is running\n")
-				fmt.Printf("   • Check connection details in config are correct\n")
-				fmt.Printf("   • Ensure user has required permissions\n")
-				fmt.Printf("   • Check firewall/network connectivity\n")
-				fmt.Printf("   • Verify pg_hba.conf allows your IP address\n\n")
-				return nil
-			}
-			defer db.Close()
-
-			// Load target schema
-			targetSchema, err := schema.LoadSchema(getSchemaFilePath())
-			if err != nil {
-				fmt.Printf("❌ Schema Loading Failed\n\n")
-				fmt.Printf("Schema file: %s\n\n", getSchemaFilePath())
-				fmt.Printf("Error: %v\n\n", err)
-				fmt.Printf("💡 Solutions:\n")
-				fmt.Printf("   • Verify schema file exists and is readable\n")
-				fmt.Printf("   • Check JSON format is valid\n")
-				fmt.Printf("   • Use --schema flag to specify correct file path\n\n")
-				return nil
+				return err
 			}
+			defer sink.close()
 
 			// Validate foreign keys
-			issues, err := db.ValidateForeignKeys(targetSchema)
-			if err != nil {
-				fmt.Printf("❌ Foreign Key Validation Failed\n\n")
-				fmt.Printf("Error: %v\n\n", err)
-				fmt.Printf("💡 Common Solutions:\n")
-				fmt.Printf("   • Verify that all referenced tables exist in the database\n")
-				fmt.Printf("   • Check that required columns are present\n")
-				fmt.Printf("   • Validate your schema file contains correct foreign key definitions\n")
-				fmt.Printf("   • Ensure database connection has proper permissions\n\n")
-				fmt.Printf("🔧 Debug Steps:\n")
-				fmt.Printf("   1. Run: ./bin/migrator schema info\n")
-				fmt.Printf("   2. Check which tables exist in your database\n")
-				fmt.Printf("   3. Compare with foreign key references in schema.json\n\n")
-				return nil
+			if err := sink.checkTables("foreign_keys", vctx.targetSchema, func(tables models.Schema) error {
+				return vctx.db.StreamForeignKeyViolations(tables, sink.add)
+			}); err != nil {
+				if !isJSONOutput() {
+					fmt.Printf("❌ Foreign Key Validation Failed\n\n")
+					fmt.Printf("Error: %v\n\n", err)
+					fmt.Printf("💡 Common Solutions:\n")
+					fmt.Printf("   • Verify that all referenced tables exist in the database\n")
+					fmt.Printf("   • Check that required columns are present\n")
+					fmt.Printf("   • Validate your schema file contains correct foreign key definitions\n")
+					fmt.Printf("   • Ensure database connection has proper permissions\n\n")
+					fmt.Printf("🔧 Debug Steps:\n")
+					fmt.Printf("   1. Run: ./bin/migrator schema info\n")
+					fmt.Printf("   2. Check which tables exist in your database\n")
+					fmt.Printf("   3. Compare with foreign key references in schema.json\n\n")
+				}
+				return failCommand(cmd, newInternalError("foreign key validation failed", err))
 			}
 
-			// Create report
-			report := output.CreateValidationReport(connectionName, issues)
-
-			// Format and output results
-			formatter := output.NewFormatter(outputFormat)
-			content, err := formatter.FormatValidationReport(report)
-			if err != nil {
-				fmt.Printf("❌ Output Formatting Failed\n\n")
-				fmt.Printf("Error: %v\n\n", err)
-				return nil
-			}
-
-			return saveOutput(content, cmd)
+			return sink.finish(cmd, vctx)
 		},
 	}
 }
@@ -165,7 +476,7 @@ func newValidateNullCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "null",
 		Short: "Validate NOT NULL constraints",
-		Long: `Validates NOT NULL constraints by identifying records with null values 
+		Long: `Validates NOT NULL constraints by identifying records with null values
 in columns that will be made NOT NULL during migration.
 
 This command will:
@@ -179,92 +490,142 @@ This command will:
 			// Disable usage on error for clean output
 			cmd.SilenceUsage = true
 
-			// Load configuration
-			cfg, err := getConfigFromCmd(cmd)
+			vctx, err := openValidationContext(cmd)
 			if err != nil {
-				fmt.Printf("❌ Configuration Error\n\n")
-				fmt.Printf("Failed to load configuration: %v\n\n", err)
-				fmt.Printf("💡 Solutions:\n")
-				fmt.Printf("   • Check if conf.json exists in the current directory\n")
-				fmt.Printf("   • Verify JSON syntax is valid\n")
-				fmt.Printf("   • Use --config flag to specify a different config file\n\n")
-				return nil
+				return err
 			}
+			defer vctx.db.Close()
 
-			// Get connection config
-			dbConfig, err := cfg.GetConnectionConfig(connectionName)
+			sink, err := newIssueSink(cmd)
 			if err != nil {
-				fmt.Printf("❌ Connection Configuration Error\n\n")
-				fmt.Printf("Failed to get connection config: %v\n\n", err)
-				fmt.Printf("💡 Solutions:\n")
-				fmt.Printf("   • Check connection name in conf.json\n")
-				fmt.Printf("   • Use --connection flag to specify a valid connection\n")
-				fmt.Printf("   • Verify default connection is properly configured\n\n")
-				return nil
+				return err
+			}
+			defer sink.close()
+
+			// Validate NOT NULL constraints with configuration
+			validationConfig := getValidationConfigFromFlags()
+			if err := sink.checkTables("not_null", vctx.targetSchema, func(tables models.Schema) error {
+				return vctx.db.StreamNullViolations(tables, &validationConfig, sink.add)
+			}); err != nil {
+				if !isJSONOutput() {
+					fmt.Printf("❌ NOT NULL Validation Failed\n\n")
+					fmt.Printf("Error: %v\n\n", err)
+					fmt.Printf("💡 Common Solutions:\n")
+					fmt.Printf("   • Verify that target tables exist in the database\n")
+					fmt.Printf("   • Check that required columns are present\n")
+					fmt.Printf("   • Validate your schema file contains correct column definitions\n")
+					fmt.Printf("   • Ensure database connection has proper permissions\n\n")
+					fmt.Printf("🔧 Debug Steps:\n")
+					fmt.Printf("   1. Run: ./bin/migrator schema info\n")
+					fmt.Printf("   2. Check which tables and columns exist in your database\n")
+					fmt.Printf("   3. Compare with NOT NULL constraints in schema.json\n\n")
+				}
+				return failCommand(cmd, newInternalError("NOT NULL validation failed", err))
 			}
 
-			// Connect to database
-			db, err := database.NewConnection(dbConfig)
+			return sink.finish(cmd, vctx)
+		},
+	}
+}
+
+// newValidatePolymorphicCmd creates the validate polymorphic command
+func newValidatePolymorphicCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "polymorphic",
+		Short: "Validate polymorphic associations",
+		Long: `Validates Rails-style polymorphic associations (e.g. commentable_type /
+commentable_id) declared with PolymorphicRelations in the target schema.
+
+This command will:
+- Find rows whose id does not exist in the table selected by their type value
+- Report type values that are not mapped to any table
+- Honor row filters and the --max-issues limit`,
+		Aliases: []string{"poly"},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Disable usage on error for clean output
+			cmd.SilenceUsage = true
+
+			vctx, err := openValidationContext(cmd)
 			if err != nil {
-				fmt.Printf("❌ Database Connection Failed\n\n")
-				fmt.Printf("Database: %s\n", dbConfig.Database)
-				fmt.Printf("Host: %s:%d\n", dbConfig.Host, dbConfig.Port)
-				fmt.Printf("User: %s\n\n", dbConfig.Username)
-				fmt.Printf("Error: %v\n\n", err)
-				fmt.Printf("💡 Common Solutions:\n")
-				fmt.Printf("   • Verify database server is running\n")
-				fmt.Printf("   • Check connection details in config are correct\n")
-				fmt.Printf("   • Ensure user has required permissions\n")
-				fmt.Printf("   • Check firewall/network connectivity\n")
-				fmt.Printf("   • Verify pg_hba.conf allows your IP address\n\n")
-				return nil
+				return err
 			}
-			defer db.Close()
+			defer vctx.db.Close()
 
-			// Load target schema
-			targetSchema, err := schema.LoadSchema(getSchemaFilePath())
+			sink, err := newIssueSink(cmd)
 			if err != nil {
-				fmt.Printf("❌ Schema Loading Failed\n\n")
-				fmt.Printf("Schema file: %s\n\n", getSchemaFilePath())
-				fmt.Printf("Error: %v\n\n", err)
-				fmt.Printf("💡 Solutions:\n")
-				fmt.Printf("   • Verify schema file exists and is readable\n")
-				fmt.Printf("   • Check JSON format is valid\n")
-				fmt.Printf("   • Use --schema flag to specify correct file path\n\n")
-				return nil
+				return err
 			}
+			defer sink.close()
 
-			// Validate NOT NULL constraints with configuration
 			validationConfig := getValidationConfigFromFlags()
-			issues, err := db.ValidateNotNullConstraintsWithConfig(targetSchema, &validationConfig)
+			if err := sink.checkTables("polymorphic", vctx.targetSchema, func(tables models.Schema) error {
+				return vctx.db.StreamPolymorphicViolations(tables, &validationConfig, sink.add)
+			}); err != nil {
+				if !isJSONOutput() {
+					fmt.Printf("❌ Polymorphic Association Validation Failed\n\n")
+					fmt.Printf("Error: %v\n\n", err)
+					fmt.Printf("💡 Common Solutions:\n")
+					fmt.Printf("   • Verify the type and id columns declared in PolymorphicRelations exist\n")
+					fmt.Printf("   • Check that every mapped table exists in the database\n\n")
+				}
+				return failCommand(cmd, newInternalError("polymorphic association validation failed", err))
+			}
+
+			return sink.finish(cmd, vctx)
+		},
+	}
+}
+
+// newValidateCrossConnectionCmd creates the validate cross-connection command
+func newValidateCrossConnectionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cross-connection",
+		Short: "Validate references into other databases",
+		Long: `Validates CrossConnectionReferences declared in the target schema: "foreign keys"
+whose referenced table lives in another database, reached through a named connection
+from the configuration (e.g. orders.customer_id -> crm.customers.id).
+
+This command will:
+- Stream the distinct key values from the source table
+- Check them in batches (--batch-size) against the referenced connection
+- Report orphans as ordinary foreign key violations`,
+		Aliases: []string{"xconn", "cross-db"},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Disable usage on error for clean output
+			cmd.SilenceUsage = true
+
+			vctx, err := openValidationContext(cmd)
 			if err != nil {
-				fmt.Printf("❌ NOT NULL Validation Failed\n\n")
-				fmt.Printf("Error: %v\n\n", err)
-				fmt.Printf("💡 Common Solutions:\n")
-				fmt.Printf("   • Verify that target tables exist in the database\n")
-				fmt.Printf("   • Check that required columns are present\n")
-				fmt.Printf("   • Validate your schema file contains correct column definitions\n")
-				fmt.Printf("   • Ensure database connection has proper permissions\n\n")
-				fmt.Printf("🔧 Debug Steps:\n")
-				fmt.Printf("   1. Run: ./bin/migrator schema info\n")
-				fmt.Printf("   2. Check which tables and columns exist in your database\n")
-				fmt.Printf("   3. Compare with NOT NULL constraints in schema.json\n\n")
-				return nil
+				return err
 			}
+			defer vctx.db.Close()
 
-			// Create report
-			report := output.CreateValidationReport(connectionName, issues)
+			pool := newConnectionPool(vctx.cfg)
+			defer pool.closeAll()
 
-			// Format and output results
-			formatter := output.NewFormatter(outputFormat)
-			content, err := formatter.FormatValidationReport(report)
+			sink, err := newIssueSink(cmd)
 			if err != nil {
-				fmt.Printf("❌ Output Formatting Failed\n\n")
-				fmt.Printf("Error: %v\n\n", err)
-				return nil
+				return err
 			}
+			defer sink.close()
 
-			return saveOutput(content, cmd)
+			validationConfig := getValidationConfigFromFlags()
+			if err := sink.checkTables("cross_connection", vctx.targetSchema, func(tables models.Schema) error {
+				return vctx.db.StreamCrossConnectionViolations(tables, pool.get, crossBatchSize, &validationConfig, sink.add)
+			}); err != nil {
+				if !isJSONOutput() {
+					fmt.Printf("❌ Cross-Connection Reference Validation Failed\n\n")
+					fmt.Printf("Error: %v\n\n", err)
+					fmt.Printf("💡 Common Solutions:\n")
+					fmt.Printf("   • Check that every referenced connection is defined in conf.json\n")
+					fmt.Printf("   • Verify the referenced databases are reachable\n\n")
+				}
+				return failCommand(cmd, newInternalError("cross-connection reference validation failed", err))
+			}
+
+			return sink.finish(cmd, vctx)
 		},
 	}
 }
@@ -280,6 +641,8 @@ NOT NULL constraints, and schema validation.
 This is a comprehensive check that combines:
 - Foreign key constraint validation
 - NOT NULL constraint validation
+- Polymorphic association validation
+- Cross-connection reference validation
 - Schema structure validation
 - Data integrity checks`,
 
@@ -287,111 +650,96 @@ This is a comprehensive check that combines:
 			// Disable usage on error for clean output
 			cmd.SilenceUsage = true
 
-			// Load configuration
-			cfg, err := getConfigFromCmd(cmd)
+			vctx, err := openValidationContext(cmd)
 			if err != nil {
-				fmt.Printf("❌ Configuration Error\n\n")
-				fmt.Printf("Failed to load configuration: %v\n\n", err)
-				fmt.Printf("💡 Solutions:\n")
-				fmt.Printf("   • Check if conf.json exists in the current directory\n")
-				fmt.Printf("   • Verify JSON syntax is valid\n")
-				fmt.Printf("   • Use --config flag to specify a different config file\n\n")
-				return nil
+				return err
 			}
+			defer vctx.db.Close()
 
-			// Get connection config
-			dbConfig, err := cfg.GetConnectionConfig(connectionName)
+			sink, err := newIssueSink(cmd)
 			if err != nil {
-				fmt.Printf("❌ Connection Configuration Error\n\n")
-				fmt.Printf("Failed to get connection config: %v\n\n", err)
-				fmt.Printf("💡 Solutions:\n")
-				fmt.Printf("   • Check connection name in conf.json\n")
-				fmt.Printf("   • Use --connection flag to specify a valid connection\n")
-				fmt.Printf("   • Verify default connection is properly configured\n\n")
-				return nil
+				return err
 			}
+			defer sink.close()
 
-			// Connect to database
-			db, err := database.NewConnection(dbConfig)
-			if err != nil {
-				fmt.Printf("❌ Database Connection Failed\n\n")
-				fmt.Printf("Database: %s\n", dbConfig.Database)
-				fmt.Printf("Host: %s:%d\n", dbConfig.Host, dbConfig.Port)
-				fmt.Printf("User: %s\n\n", dbConfig.Username)
-				fmt.Printf("Error: %v\n\n", err)
-				fmt.Printf("💡 Common Solutions:\n")
-				fmt.Printf("   • Verify database server is running\n")
-				fmt.Printf("   • Check connection details in config are correct\n")
-				fmt.Printf("   • Ensure user has required permissions\n")
-				fmt.Printf("   • Check firewall/network connectivity\n")
-				fmt.Printf("   • Verify pg_hba.conf allows your IP address\n\n")
-				return nil
+			if err := runAllChecks(cmd, vctx, sink); err != nil {
+				return err
 			}
-			defer db.Close()
 
-			// Load target schema
-			targetSchema, err := schema.LoadSchema(getSchemaFilePath())
-			if err != nil {
-				fmt.Printf("❌ Schema Loading Failed\n\n")
-				fmt.Printf("Schema file: %s\n\n", getSchemaFilePath())
-				fmt.Printf("Error: %v\n\n", err)
-				fmt.Printf("💡 Solutions:\n")
-				fmt.Printf("   • Verify schema file exists and is readable\n")
-				fmt.Printf("   • Check JSON format is valid\n")
-				fmt.Printf("   • Use --schema flag to specify correct file path\n\n")
-				return nil
-			}
-
-			var allIssues []models.ValidationIssue
-
-			// 1. Validate schema structure
-			fmt.Println("🔍 Validating schema structure...")
-			schemaIssues := schema.ValidateSchema(targetSchema)
-			allIssues = append(allIssues, schemaIssues...)
+			return sink.finish(cmd, vctx)
+		},
+	}
+}
 
-			// 2. Validate foreign keys
-			fmt.Println("🔍 Validating foreign key constraints...")
-			var fkIssues []models.ValidationIssue
-			fkIssues, err = db.ValidateForeignKeys(targetSchema)
-			if err != nil {
-				fmt.Printf("❌ Foreign Key Validation Failed\n\n")
-				fmt.Printf("Error: %v\n\n", err)
-				fmt.Printf("💡 Common Solutions:\n")
-				fmt.Printf("   • Verify that all referenced tables exist in the database\n")
-				fmt.Printf("   • Check that required columns are present\n")
-				fmt.Printf("   • Validate your schema file contains correct foreign key definitions\n\n")
-				return nil
-			}
-			allIssues = append(allIssues, fkIssues...)
+// runAllChecks runs every check of validate all, adding the issues found to sink
+func runAllChecks(cmd *cobra.Command, vctx *validationContext, sink *issueSink) error {
+	// Progress goes to stderr so that stdout only carries the report
+	// 1. Validate schema structure
+	fmt.Fprintln(os.Stderr, "🔍 Validating schema structure...")
+	if err := sink.check("schema_structure", func() error {
+		return sink.addAll(schema.ValidateSchema(vctx.targetSchema))
+	}); err != nil {
+		return failCommand(cmd, newInternalError("failed to write validation report", err))
+	}
 
-			// 3. Validate NOT NULL constraints
-			fmt.Println("🔍 Validating NOT NULL constraints...")
-			var nullIssues []models.ValidationIssue
-			nullIssues, err = db.ValidateNotNullConstraints(targetSchema)
-			if err != nil {
-				fmt.Printf("❌ NOT NULL Validation Failed\n\n")
-				fmt.Printf("Error: %v\n\n", err)
-				fmt.Printf("💡 Common Solutions:\n")
-				fmt.Printf("   • Verify that target tables exist in the database\n")
-				fmt.Printf("   • Check that required columns are present\n")
-				fmt.Printf("   • Validate your schema file contains correct column definitions\n\n")
-				return nil
-			}
-			allIssues = append(allIssues, nullIssues...)
+	// 2. Validate foreign keys
+	fmt.Fprintln(os.Stderr, "🔍 Validating foreign key constraints...")
+	if err := sink.checkTables("foreign_keys", vctx.targetSchema, func(tables models.Schema) error {
+		return vctx.db.StreamForeignKeyViolations(tables, sink.add)
+	}); err != nil {
+		if !isJSONOutput() {
+			fmt.Printf("❌ Foreign Key Validation Failed\n\n")
+			fmt.Printf("Error: %v\n\n", err)
+			fmt.Printf("💡 Common Solutions:\n")
+			fmt.Printf("   • Verify that all referenced tables exist in the database\n")
+			fmt.Printf("   • Check that required columns are present\n")
+			fmt.Printf("   • Validate your schema file contains correct foreign key definitions\n\n")
+		}
+		return failCommand(cmd, newInternalError("foreign key validation failed", err))
+	}
 
-			// Create comprehensive report
-			report := output.CreateValidationReport(connectionName, allIssues)
+	// 3. Validate NOT NULL constraints
+	fmt.Fprintln(os.Stderr, "🔍 Validating NOT NULL constraints...")
+	if err := sink.checkTables("not_null", vctx.targetSchema, func(tables models.Schema) error {
+		return vctx.db.StreamNullViolations(tables, nil, sink.add)
+	}); err != nil {
+		if !isJSONOutput() {
+			fmt.Printf("❌ NOT NULL Validation Failed\n\n")
+			fmt.Printf("Error: %v\n\n", err)
+			fmt.Printf("💡 Common Solutions:\n")
+			fmt.Printf("   • Verify that target tables exist in the database\n")
+			fmt.Printf("   • Check that required columns are present\n")
+			fmt.Printf("   • Validate your schema file contains correct column definitions\n\n")
+		}
+		return failCommand(cmd, newInternalError("NOT NULL validation failed", err))
+	}
 
-			// Format and output results
-			formatter := output.NewFormatter(outputFormat)
-			content, err := formatter.FormatValidationReport(report)
-			if err != nil {
-				fmt.Printf("❌ Output Formatting Failed\n\n")
-				fmt.Printf("Error: %v\n\n", err)
-				return nil
-			}
+	// 4. Validate polymorphic associations
+	fmt.Fprintln(os.Stderr, "🔍 Validating polymorphic associations...")
+	validationConfig := getValidationConfigFromFlags()
+	if err := sink.checkTables("polymorphic", vctx.targetSchema, func(tables models.Schema) error {
+		return vctx.db.StreamPolymorphicViolations(tables, &validationConfig, sink.add)
+	}); err != nil {
+		if !isJSONOutput() {
+			fmt.Printf("❌ Polymorphic Association Validation Failed\n\n")
+			fmt.Printf("Error: %v\n\n", err)
+		}
+		return failCommand(cmd, newInternalError("polymorphic association validation failed", err))
+	}
 
-			return saveOutput(content, cmd)
-		},
+	// 5. Validate references into other connections
+	fmt.Fprintln(os.Stderr, "🔍 Validating cross-connection references...")
+	pool := newConnectionPool(vctx.cfg)
+	defer pool.closeAll()
+	if err := sink.checkTables("cross_connection", vctx.targetSchema, func(tables models.Schema) error {
+		return vctx.db.StreamCrossConnectionViolations(tables, pool.get, crossBatchSize, &validationConfig, sink.add)
+	}); err != nil {
+		if !isJSONOutput() {
+			fmt.Printf("❌ Cross-Connection Reference Validation Failed\n\n")
+			fmt.Printf("Error: %v\n\n", err)
+		}
+		return failCommand(cmd, newInternalError("cross-connection reference validation failed", err))
 	}
+
+	return nil
 }
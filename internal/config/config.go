@@ -8,6 +8,13 @@ import (
 	"github.com/spf13/viper"
 )
 
+// DefaultAuditFile is where modifying runs are recorded unless configured otherwise
+const DefaultAuditFile = ".migrator/audit.jsonl"
+
+// DefaultHistoryDir is where validation and comparison runs are recorded, relative to the
+// home directory, unless configured otherwise
+const DefaultHistoryDir = ".migrator/history"
+
 // DBConfig represents a database configuration
 type DBConfig struct {
 	Type     string `json:"type" yaml:"type" mapstructure:"type"` // postgres, mysql
@@ -21,10 +28,35 @@ type DBConfig struct {
 
 // ValidationConfig represents validation behavior configuration
 type ValidationConfig struct {
-	IgnoreMissingTables  bool `json:"ignore_missing_tables" yaml:"ignore_missing_tables" mapstructure:"ignore_missing_tables"`
-	IgnoreMissingColumns bool `json:"ignore_missing_columns" yaml:"ignore_missing_columns" mapstructure:"ignore_missing_columns"`
-	StopOnFirstError     bool `json:"stop_on_first_error" yaml:"stop_on_first_error" mapstructure:"stop_on_first_error"`
-	MaxIssuesPerTable    int  `json:"max_issues_per_table" yaml:"max_issues_per_table" mapstructure:"max_issues_per_table"`
+	IgnoreMissingTables  bool        `json:"ignore_missing_tables" yaml:"ignore_missing_tables" mapstructure:"ignore_missing_tables"`
+	IgnoreMissingColumns bool        `json:"ignore_missing_columns" yaml:"ignore_missing_columns" mapstructure:"ignore_missing_columns"`
+	StopOnFirstError     bool        `json:"stop_on_first_error" yaml:"stop_on_first_error" mapstructure:"stop_on_first_error"`
+	MaxIssuesPerTable    int         `json:"max_issues_per_table" yaml:"max_issues_per_table" mapstructure:"max_issues_per_table"`
+	RowFilters           []RowFilter `json:"row_filters,omitempty" yaml:"row_filters,omitempty" mapstructure:"row_filters"`
+}
+
+// RowFilter scopes validation and fixes of a table to the rows matching a SQL predicate
+type RowFilter struct {
+	Table string `json:"table" yaml:"table" mapstructure:"table"`
+	Where string `json:"where" yaml:"where" mapstructure:"where"`
+}
+
+// AuditConfig controls where the runs that modify data are recorded
+type AuditConfig struct {
+	// File is the JSONL file every modifying run is appended to
+	File string `json:"file,omitempty" yaml:"file,omitempty" mapstructure:"file"`
+	// Table, if set, also records every run in this table of the target database
+	Table string `json:"table,omitempty" yaml:"table,omitempty" mapstructure:"table"`
+}
+
+// HistoryConfig controls the local history of validation and schema comparison runs
+type HistoryConfig struct {
+	// Dir is the directory of the history store
+	Dir string `json:"dir,omitempty" yaml:"dir,omitempty" mapstructure:"dir"`
+	// Disabled turns off recording runs
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty" mapstructure:"disabled"`
+	// MaxRuns, if set, keeps only this many of the most recent runs
+	MaxRuns int `json:"max_runs,omitempty" yaml:"max_runs,omitempty" mapstructure:"max_runs"`
 }
 
 // Connection represents a named database connection
@@ -46,6 +78,8 @@ type Config struct {
 		Connections []Connection `json:"connections" yaml:"connections" mapstructure:"connections"`
 	} `json:"DB" yaml:"DB" mapstructure:"DB"`
 	Validation ValidationConfig `json:"validation" yaml:"validation" mapstructure:"validation"`
+	Audit      AuditConfig      `json:"audit" yaml:"audit" mapstructure:"audit"`
+	History    HistoryConfig    `json:"history" yaml:"history" mapstructure:"history"`
 }
 
 // GetConnectionConfig returns the database configuration for a given connection name
@@ -122,6 +156,16 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate row filters
+	for i, filter := range c.Validation.RowFilters {
+		if filter.Table == "" {
+			return fmt.Errorf("row filter at index %d must have a table", i)
+		}
+		if filter.Where == "" {
+			return fmt.Errorf("row filter for table '%s' must have a where predicate", filter.Table)
+		}
+	}
+
 	return nil
 }
 
@@ -168,6 +212,28 @@ func (c *Config) GetValidationConfig() ValidationConfig {
 	return validationConfig
 }
 
+// GetAuditConfig returns the audit configuration with defaults
+func (c *Config) GetAuditConfig() AuditConfig {
+	auditConfig := c.Audit
+	if auditConfig.File == "" {
+		auditConfig.File = DefaultAuditFile
+	}
+	return auditConfig
+}
+
+// GetHistoryConfig returns the history configuration with defaults. The history is kept
+// in the home directory, so runs from any working directory end up in the same store.
+func (c *Config) GetHistoryConfig() HistoryConfig {
+	historyConfig := c.History
+	if historyConfig.Dir == "" {
+		historyConfig.Dir = DefaultHistoryDir
+		if home, err := os.UserHomeDir(); err == nil {
+			historyConfig.Dir = filepath.Join(home, DefaultHistoryDir)
+		}
+	}
+	return historyConfig
+}
+
 // GetDefaultSchemaPath returns the default path for the schema file
 func GetDefaultSchemaPath() string {
 	execPath, _ := os.Executable()
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fix plan actions for NULL value violations
+const (
+	FixActionRemove        = "remove"
+	FixActionQuarantine    = "quarantine"
+	FixActionSkip          = "skip"
+	FixActionSetValue      = "set-value"
+	FixActionExpression    = "expression"
+	FixActionSchemaDefault = "schema-default"
+	FixActionZeroValue     = "zero-value"
+	FixActionFromColumn    = "from-column"
+	FixActionLookup        = "lookup"
+)
+
+// FixPlan gives the strategy used to fix NULL values per table and column
+type FixPlan struct {
+	// Name identifies the plan in fix run manifests; defaults to the plan file path
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Default applies to NOT NULL columns that have no strategy of their own
+	Default *ColumnFixStrategy `json:"default,omitempty" yaml:"default,omitempty"`
+	// Tables maps table name -> column name -> strategy. The column "*" applies to every
+	// column of the table without a strategy of its own.
+	Tables map[string]map[string]ColumnFixStrategy `json:"tables" yaml:"tables"`
+}
+
+// ColumnFixStrategy describes how NULL values in one column are fixed
+type ColumnFixStrategy struct {
+	Action string `json:"action" yaml:"action"`
+	// Value is the literal assigned by set-value
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+	// Expression is the SQL expression assigned by expression
+	Expression string `json:"expression,omitempty" yaml:"expression,omitempty"`
+	// Column is the column of the same row copied by from-column
+	Column string `json:"column,omitempty" yaml:"column,omitempty"`
+	// Lookup reads the value from another table
+	Lookup *LookupStrategy `json:"lookup,omitempty" yaml:"lookup,omitempty"`
+}
+
+// LookupStrategy reads a value from another table. With MatchColumn and SourceColumn the
+// lookup row is the one whose MatchColumn equals the fixed row's SourceColumn; otherwise
+// the first row matching Where is used for every fixed row.
+type LookupStrategy struct {
+	Table        string `json:"table" yaml:"table"`
+	Column       string `json:"column" yaml:"column"`
+	MatchColumn  string `json:"match_column,omitempty" yaml:"match_column,omitempty"`
+	SourceColumn string `json:"source_column,omitempty" yaml:"source_column,omitempty"`
+	Where        string `json:"where,omitempty" yaml:"where,omitempty"`
+}
+
+// LoadFixPlan reads and validates a fix plan YAML (or JSON) file
+func LoadFixPlan(filePath string) (*FixPlan, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fix plan: %w", err)
+	}
+
+	var plan FixPlan
+	if err := yaml.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse fix plan: %w", err)
+	}
+	if plan.Name == "" {
+		plan.Name = filePath
+	}
+
+	if err := plan.Validate(); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// NewUniformFixPlan returns a plan applying one strategy to every NOT NULL column
+func NewUniformFixPlan(strategy ColumnFixStrategy) *FixPlan {
+	return &FixPlan{
+		Name:    strategy.Action,
+		Default: &strategy,
+	}
+}
+
+// StrategyFor returns the strategy for a column: its own, the table's "*" strategy or the
+// plan default, in that order
+func (p *FixPlan) StrategyFor(tableName, columnName string) (ColumnFixStrategy, bool) {
+	if columns, ok := p.Tables[tableName]; ok {
+		if strategy, ok := columns[columnName]; ok {
+			return strategy, true
+		}
+		if strategy, ok := columns["*"]; ok {
+			return strategy, true
+		}
+	}
+	if p.Default != nil {
+		return *p.Default, true
+	}
+	return ColumnFixStrategy{}, false
+}
+
+// Validate checks that every strategy has the settings its action needs
+func (p *FixPlan) Validate() error {
+	if p.Default != nil {
+		if err := p.Default.Validate(); err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+	}
+	for tableName, columns := range p.Tables {
+		for columnName, strategy := range columns {
+			if err := strategy.Validate(); err != nil {
+				return fmt.Errorf("%s.%s: %w", tableName, columnName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks that the strategy has the settings its action needs
+func (s ColumnFixStrategy) Validate() error {
+	switch s.Action {
+	case FixActionRemove, FixActionQuarantine, FixActionSkip, FixActionSchemaDefault, FixActionZeroValue:
+	case FixActionSetValue:
+		if s.Value == nil {
+			return fmt.Errorf("set-value requires a value")
+		}
+	case FixActionExpression:
+		if s.Expression == "" {
+			return fmt.Errorf("expression requires an expression")
+		}
+	case FixActionFromColumn:
+		if s.Column == "" {
+			return fmt.Errorf("from-column requires a column")
+		}
+	case FixActionLookup:
+		if s.Lookup == nil || s.Lookup.Table == "" || s.Lookup.Column == "" {
+			return fmt.Errorf("lookup requires lookup.table and lookup.column")
+		}
+		if (s.Lookup.MatchColumn == "") != (s.Lookup.SourceColumn == "") {
+			return fmt.Errorf("lookup.match_column and lookup.source_column must be given together")
+		}
+	case "":
+		return fmt.Errorf("action is required")
+	default:
+		return fmt.Errorf("unknown action: %s", s.Action)
+	}
+	return nil
+}
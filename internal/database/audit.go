@@ -0,0 +1,180 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// audit passes an executed statement to the Audit callback, if any
+func (o FixOptions) audit(stmt models.AuditStatement) {
+	if o.Audit != nil {
+		o.Audit(stmt)
+	}
+}
+
+// auditStatement describes an executed fix statement for the audit log, with its arguments
+// inlined
+func (db *DB) auditStatement(stmt fixStatement, rowsAffected int, err error) models.AuditStatement {
+	var query string
+	if stmt.Operation == fixOperationCreate {
+		query = stmt.Source
+	} else {
+		sql, args := db.fixStatementSQL(stmt)
+		query = strings.TrimSpace(db.inlineArgs(sql, args))
+	}
+
+	audited := models.AuditStatement{
+		Table:       stmt.Table,
+		Description: stmt.Description,
+		SQL:         query,
+		Rows:        int64(rowsAffected),
+	}
+	if err != nil {
+		audited.Error = err.Error()
+	}
+	return audited
+}
+
+// auditColumns are the columns of the audit table, in insert order
+var auditColumns = []string{
+	"run_id", "started_at", "finished_at", "duration_ms", "username", "host", "connection_name",
+	"database_name", "command", "command_line", "action", "outcome", "error", "rows_affected", "statements",
+	"plan_id",
+}
+
+// ensureAuditTable creates the audit table if it does not exist yet
+func (db *DB) ensureAuditTable(tableName string) error {
+	ddl := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	run_id VARCHAR(64) NOT NULL,
+	started_at VARCHAR(32) NOT NULL,
+	finished_at VARCHAR(32),
+	duration_ms BIGINT,
+	username VARCHAR(255),
+	host VARCHAR(255),
+	connection_name VARCHAR(255),
+	database_name VARCHAR(255),
+	command VARCHAR(255),
+	command_line TEXT,
+	action VARCHAR(255),
+	outcome VARCHAR(32),
+	error TEXT,
+	rows_affected BIGINT,
+	statements TEXT,
+	plan_id VARCHAR(64)
+)`, db.quoteIdentifier(tableName))
+	if _, err := db.conn.Exec(ddl); err != nil {
+		return fmt.Errorf("failed to create audit table %s: %w", tableName, err)
+	}
+
+	// Audit tables created before plan IDs were recorded lack the column
+	exists, err := db.columnExists(tableName, "plan_id")
+	if err != nil {
+		return fmt.Errorf("failed to inspect audit table %s: %w", tableName, err)
+	}
+	if !exists {
+		alter := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s VARCHAR(64)", db.quoteIdentifier(tableName), db.quoteIdentifier("plan_id"))
+		if _, err := db.conn.Exec(alter); err != nil {
+			return fmt.Errorf("failed to add plan_id to audit table %s: %w", tableName, err)
+		}
+	}
+	return nil
+}
+
+// SaveAuditRecord appends an audit record to tableName, creating the table on first use.
+// The executed statements are stored as JSON.
+func (db *DB) SaveAuditRecord(tableName string, record *models.AuditRecord) error {
+	if err := db.ensureAuditTable(tableName); err != nil {
+		return err
+	}
+
+	statements, err := json.Marshal(record.Statements)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audited statements: %w", err)
+	}
+
+	quoted := make([]string, len(auditColumns))
+	for i, column := range auditColumns {
+		quoted[i] = db.quoteIdentifier(column)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		db.quoteIdentifier(tableName), strings.Join(quoted, ", "), db.placeholderList(1, len(auditColumns)))
+	_, err = db.conn.Exec(query,
+		record.RunID, record.StartedAt, record.FinishedAt, record.DurationMs, record.User, record.Host,
+		record.Connection, record.Database, record.Command, record.CommandLine, record.Action,
+		record.Outcome, record.Error, record.RowsAffected, string(statements), record.PlanID)
+	if err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// ListAuditRecords reads the audit records stored in tableName, oldest first
+func (db *DB) ListAuditRecords(tableName string) ([]models.AuditRecord, error) {
+	// Audit tables written before plan IDs were recorded lack the column until the next
+	// run is audited
+	hasPlanID, err := db.columnExists(tableName, "plan_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect audit table %s: %w", tableName, err)
+	}
+	quoted := make([]string, len(auditColumns))
+	for i, column := range auditColumns {
+		quoted[i] = db.quoteIdentifier(column)
+		if column == "plan_id" && !hasPlanID {
+			quoted[i] = "NULL"
+		}
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s",
+		strings.Join(quoted, ", "), db.quoteIdentifier(tableName), db.quoteIdentifier("started_at"))
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	var records []models.AuditRecord
+	for rows.Next() {
+		var record models.AuditRecord
+		var finishedAt, user, host, connection, database, command, commandLine, action, outcome, errorText, statements, planID *string
+		var durationMs, rowsAffected *int64
+		if err := rows.Scan(&record.RunID, &record.StartedAt, &finishedAt, &durationMs, &user, &host,
+			&connection, &database, &command, &commandLine, &action, &outcome, &errorText, &rowsAffected, &statements, &planID); err != nil {
+			return nil, err
+		}
+		record.FinishedAt = stringValue(finishedAt)
+		record.User = stringValue(user)
+		record.Host = stringValue(host)
+		record.Connection = stringValue(connection)
+		record.Database = stringValue(database)
+		record.Command = stringValue(command)
+		record.CommandLine = stringValue(commandLine)
+		record.Action = stringValue(action)
+		record.Outcome = stringValue(outcome)
+		record.Error = stringValue(errorText)
+		record.PlanID = stringValue(planID)
+		if durationMs != nil {
+			record.DurationMs = *durationMs
+		}
+		if rowsAffected != nil {
+			record.RowsAffected = *rowsAffected
+		}
+		if statements != nil && *statements != "" {
+			if err := json.Unmarshal([]byte(*statements), &record.Statements); err != nil {
+				return nil, fmt.Errorf("failed to parse audited statements of run %s: %w", record.RunID, err)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// stringValue returns the string a nullable column was scanned into, or ""
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,409 @@
+package database
+
+import (
+	"bufio"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// DefaultBackupDir is where fix runs store their manifests and row backups
+const DefaultBackupDir = ".migrator/backups"
+
+// Fix run and backup entry statuses
+const (
+	RunStatusRunning    = "running"
+	RunStatusCommitted  = "committed"
+	RunStatusRolledBack = "rolled_back"
+	RunStatusPartial    = "partial"
+	RunStatusUndone     = "undone"
+
+	EntryStatusPending    = "pending"
+	EntryStatusCommitted  = "committed"
+	EntryStatusRolledBack = "rolled_back"
+	EntryStatusUndone     = "undone"
+)
+
+// manifestFileName is the name of the manifest inside a run's backup directory
+const manifestFileName = "manifest.json"
+
+// NewFixRunID returns a new, sortable fix run identifier
+func NewFixRunID() string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return time.Now().UTC().Format("20060102T150405.000000Z")
+	}
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// binaryValueKey marks a binary value in a backup file, which is written as a JSON object
+// holding its bytes in base64: {"$base64": "..."}
+const binaryValueKey = "$base64"
+
+// backupWriter copies the rows a fix statement is about to change into JSONL files and keeps
+// the run manifest up to date
+type backupWriter struct {
+	dir      string
+	manifest *models.FixRunManifest
+	// graph holds the foreign keys whose referential actions deletes cascade through;
+	// loaded on the first delete
+	graph cascadeGraph
+	// keyColumns caches the primary key column of every backed up table
+	keyColumns map[string]string
+}
+
+// newBackupWriter creates the run's backup directory and writes the initial manifest
+func newBackupWriter(baseDir string, manifest *models.FixRunManifest) (*backupWriter, error) {
+	if baseDir == "" {
+		baseDir = DefaultBackupDir
+	}
+	dir := filepath.Join(baseDir, manifest.RunID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	w := &backupWriter{dir: dir, manifest: manifest}
+	return w, w.save()
+}
+
+// backup writes the rows the statement is about to change to new backup files and returns
+// the indexes of the manifest entries describing them. For deletes this includes the rows
+// of other tables that ON DELETE CASCADE, SET NULL and SET DEFAULT rules would change.
+// Those entries come first, deepest first, so that undo restores them after their parents.
+func (w *backupWriter) backup(tx *sql.Tx, db *DB, stmt fixStatement) ([]int, error) {
+	var indexes []int
+	if stmt.Operation == fixOperationDelete {
+		cascades, err := w.cascadeStatements(db, stmt)
+		if err != nil {
+			return nil, err
+		}
+		for _, cascade := range cascades {
+			index, err := w.backupStatement(tx, db, cascade)
+			if err != nil {
+				return indexes, err
+			}
+			indexes = append(indexes, index)
+		}
+	}
+
+	index, err := w.backupStatement(tx, db, stmt)
+	if err != nil {
+		return indexes, err
+	}
+	return append(indexes, index), nil
+}
+
+// cascadeStatements returns, as fix statements, the changes the referential actions of a
+// delete make to other tables: one per table, kind and column, deepest first
+func (w *backupWriter) cascadeStatements(db *DB, stmt fixStatement) ([]fixStatement, error) {
+	if w.graph == nil {
+		graph, err := db.loadCascadeGraph()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read foreign keys to back up cascaded rows: %w", err)
+		}
+		w.graph = graph
+	}
+
+	walk := db.newImpactWalk(w.graph)
+	walk.visit(stmt.Table, db.inlineArgs(stmt.Where, stmt.Args), "", map[string]bool{}, 0)
+
+	// Merge the actions of each table, kind and column; rows reached through several
+	// foreign keys are backed up once
+	type actionKey struct{ table, kind, column string }
+	merged := make(map[actionKey]*cascadeAction)
+	var order []actionKey
+	for _, action := range walk.actions {
+		if action.Kind == impactBlocked {
+			// Blocked rows make the delete fail; nothing is changed
+			continue
+		}
+		if action.Kind == impactDeleted {
+			action.Column = ""
+		}
+		key := actionKey{action.Table, action.Kind, action.Column}
+		existing, ok := merged[key]
+		if !ok {
+			copied := action
+			merged[key] = &copied
+			order = append(order, key)
+			continue
+		}
+		existing.Condition = fmt.Sprintf("(%s) OR (%s)", existing.Condition, action.Condition)
+		if action.Depth > existing.Depth {
+			existing.Depth = action.Depth
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return merged[order[i]].Depth > merged[order[j]].Depth })
+
+	var stmts []fixStatement
+	for _, key := range order {
+		action := merged[key]
+		cascade := fixStatement{Table: action.Table, Where: action.Condition}
+		switch action.Kind {
+		case impactDeleted:
+			cascade.Operation = fixOperationDelete
+			cascade.Description = fmt.Sprintf("ON DELETE CASCADE from %s", stmt.Description)
+		case impactNulled:
+			cascade.Operation = fixOperationUpdate
+			cascade.Column = action.Column
+			cascade.Description = fmt.Sprintf("ON DELETE SET NULL of %s.%s from %s", action.Table, action.Column, stmt.Description)
+		default:
+			// SET DEFAULT: the default differs per column, so undo does not check it
+			cascade.Operation = fixOperationUpdate
+			cascade.Column = action.Column
+			cascade.Expression = "DEFAULT"
+			cascade.Description = fmt.Sprintf("ON DELETE SET DEFAULT of %s.%s from %s", action.Table, action.Column, stmt.Description)
+		}
+		stmts = append(stmts, cascade)
+	}
+	return stmts, nil
+}
+
+// backupStatement writes the rows selected by the statement to a new backup file and
+// returns the index of the manifest entry describing it
+func (w *backupWriter) backupStatement(tx *sql.Tx, db *DB, stmt fixStatement) (int, error) {
+	keyColumn, err := w.keyColumn(db, stmt)
+	if err != nil {
+		return -1, err
+	}
+
+	index := len(w.manifest.Entries)
+	fileName := fmt.Sprintf("%03d-%s.jsonl", index+1, stmt.Table)
+
+	query, args := db.fixBackupSQL(stmt)
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return -1, fmt.Errorf("failed to read rows to back up: %w", err)
+	}
+	defer rows.Close()
+
+	file, err := os.OpenFile(filepath.Join(w.dir, fileName), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	count := 0
+	for rows.Next() {
+		row, err := scanRowMap(rows)
+		if err != nil {
+			return -1, err
+		}
+		if err := encoder.Encode(row); err != nil {
+			return -1, fmt.Errorf("failed to write backup row: %w", err)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return -1, err
+	}
+	if err := writer.Flush(); err != nil {
+		return -1, fmt.Errorf("failed to write backup file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return -1, fmt.Errorf("failed to sync backup file: %w", err)
+	}
+
+	entry := models.FixBackupEntry{
+		Table:       stmt.Table,
+		Description: stmt.Description,
+		Operation:   stmt.Operation,
+		Column:      stmt.Column,
+		Value:       stmt.Value,
+		Expression:  stmt.Expression,
+		KeyColumn:   keyColumn,
+		File:        fileName,
+		RowCount:    count,
+		Status:      EntryStatusPending,
+	}
+	if isQuarantineCopy(stmt) {
+		entry.RunIDColumn = QuarantineRunIDColumn
+	}
+	w.manifest.Entries = append(w.manifest.Entries, entry)
+	return index, w.save()
+}
+
+// keyColumn returns the column undo identifies the backed up rows by: the statement's own
+// key column or the table's primary key. Tables without a single-column primary key are
+// not backed up, since undo could not tell their rows apart.
+func (w *backupWriter) keyColumn(db *DB, stmt fixStatement) (string, error) {
+	if stmt.KeyColumn != "" {
+		return stmt.KeyColumn, nil
+	}
+	if keyColumn, ok := w.keyColumns[stmt.Table]; ok {
+		return keyColumn, nil
+	}
+
+	columns, err := db.getPrimaryKeyColumns(stmt.Table)
+	if err != nil {
+		return "", fmt.Errorf("failed to read primary key of %s: %w", stmt.Table, err)
+	}
+	if len(columns) != 1 {
+		return "", fmt.Errorf("cannot back up rows of %s: the table has no single-column primary key to restore them by (use --no-backup to fix without a backup)", stmt.Table)
+	}
+	if w.keyColumns == nil {
+		w.keyColumns = make(map[string]string)
+	}
+	w.keyColumns[stmt.Table] = columns[0]
+	return columns[0], nil
+}
+
+// setEntryStatus updates the status of the given manifest entries
+func (w *backupWriter) setEntryStatus(indexes []int, status string) error {
+	for _, index := range indexes {
+		if index >= 0 && index < len(w.manifest.Entries) {
+			w.manifest.Entries[index].Status = status
+		}
+	}
+	return w.save()
+}
+
+// finish records the overall run status
+func (w *backupWriter) finish() error {
+	committed, rolledBack := 0, 0
+	for _, entry := range w.manifest.Entries {
+		switch entry.Status {
+		case EntryStatusCommitted:
+			committed++
+		case EntryStatusRolledBack, EntryStatusPending:
+			rolledBack++
+		}
+	}
+
+	switch {
+	case rolledBack == 0:
+		w.manifest.Status = RunStatusCommitted
+	case committed == 0:
+		w.manifest.Status = RunStatusRolledBack
+	default:
+		w.manifest.Status = RunStatusPartial
+	}
+	w.manifest.FinishedAt = time.Now().UTC().Format(time.RFC3339)
+	return w.save()
+}
+
+// save writes the manifest
+func (w *backupWriter) save() error {
+	return SaveFixManifest(filepath.Dir(w.dir), w.manifest)
+}
+
+// SaveFixManifest writes a run manifest into its backup directory
+func SaveFixManifest(baseDir string, manifest *models.FixRunManifest) error {
+	if baseDir == "" {
+		baseDir = DefaultBackupDir
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fix run manifest: %w", err)
+	}
+	return os.WriteFile(filepath.Join(baseDir, manifest.RunID, manifestFileName), data, 0600)
+}
+
+// LoadFixManifest reads the manifest of a fix run
+func LoadFixManifest(baseDir, runID string) (*models.FixRunManifest, error) {
+	if baseDir == "" {
+		baseDir = DefaultBackupDir
+	}
+	data, err := os.ReadFile(filepath.Join(baseDir, runID, manifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest for fix run %s: %w", runID, err)
+	}
+
+	var manifest models.FixRunManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest for fix run %s: %w", runID, err)
+	}
+	return &manifest, nil
+}
+
+// readBackupRows reads the rows stored in a backup file
+func readBackupRows(baseDir, runID, fileName string) ([]map[string]interface{}, error) {
+	if baseDir == "" {
+		baseDir = DefaultBackupDir
+	}
+	file, err := os.Open(filepath.Join(baseDir, runID, fileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer file.Close()
+
+	var rows []map[string]interface{}
+	decoder := json.NewDecoder(file)
+	decoder.UseNumber()
+	for decoder.More() {
+		var row map[string]interface{}
+		if err := decoder.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to parse backup file %s: %w", fileName, err)
+		}
+		for column, value := range row {
+			encoded, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			text, _ := encoded[binaryValueKey].(string)
+			data, err := base64.StdEncoding.DecodeString(text)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode binary value of %s in backup file %s: %w", column, fileName, err)
+			}
+			row[column] = data
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// isBinaryColumnType reports whether a database column type holds bytes rather than text
+func isBinaryColumnType(typeName string) bool {
+	typeName = strings.ToUpper(typeName)
+	return typeName == "BYTEA" || strings.Contains(typeName, "BLOB") || strings.Contains(typeName, "BINARY")
+}
+
+// scanRowMap scans the current row into a column name -> value map. Byte slices of text
+// columns are converted to strings so the row serializes readably; those of binary columns,
+// and any that are not valid UTF-8, are kept as bytes in a base64 object.
+func scanRowMap(rows *sql.Rows) (map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		if b, ok := values[i].([]byte); ok {
+			if isBinaryColumnType(columnTypes[i].DatabaseTypeName()) || !utf8.Valid(b) {
+				row[column] = map[string]string{binaryValueKey: base64.StdEncoding.EncodeToString(b)}
+			} else {
+				row[column] = string(b)
+			}
+		} else {
+			row[column] = values[i]
+		}
+	}
+	return row, nil
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// FixProgress reports the progress of a batched fix
+type FixProgress struct {
+	Table       string
+	Description string
+	Batch       int
+	RowsDone    int64
+	RowsTotal   int64
+	// ReplicationLag is set while batching is paused for lagging replicas
+	ReplicationLag time.Duration
+	// Message describes anything else worth reporting, e.g. falling back to a single statement
+	Message string
+}
+
+// getPrimaryKeyColumns returns the primary key columns of a table in key order
+func (db *DB) getPrimaryKeyColumns(tableName string) ([]string, error) {
+	rows, err := db.conn.Query(db.dialect.GetPrimaryKeyQuery(), tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// GetReplicationLag returns how far the replicas lag behind this database
+func (db *DB) GetReplicationLag() (time.Duration, error) {
+	query := db.dialect.GetReplicationLagQuery()
+	if query == "" {
+		return 0, fmt.Errorf("replication lag is not supported for %s", db.dbType)
+	}
+
+	var seconds float64
+	if err := db.conn.QueryRow(query).Scan(&seconds); err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// execBatched runs a fix statement in batches of at most BatchSize rows. Batches walk the
+// table's single-column primary key in order (keyset pagination) and each batch is
+// committed on its own, so locks are short-lived and the WAL grows gradually. Every batch
+// applies the same predicate as the single statement, restricted to its key range, so the
+// rows changed are the same.
+func (r *fixRun) execBatched(stmt fixStatement) (int, error) {
+	keyColumns, err := r.db.getPrimaryKeyColumns(stmt.Table)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read primary key of %s: %w", stmt.Table, err)
+	}
+	if len(keyColumns) != 1 {
+		r.progress(FixProgress{
+			Table:       stmt.Table,
+			Description: stmt.Description,
+			Message:     "no single-column primary key; running as a single statement",
+		})
+		return r.execOneCommitted(stmt)
+	}
+	keyColumn := keyColumns[0]
+
+	total, err := r.db.countFixRows(stmt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rows to fix in %s: %w", stmt.Table, err)
+	}
+
+	var lastKey interface{}
+	done := 0
+	for batch := 1; ; batch++ {
+		upperKey, err := r.db.nextBatchBound(stmt, keyColumn, lastKey, r.opts.BatchSize)
+		if err != nil {
+			return done, fmt.Errorf("failed to find next batch in %s: %w", stmt.Table, err)
+		}
+		if upperKey == nil {
+			return done, nil
+		}
+
+		batchStmt := r.db.keyRangeStatement(stmt, keyColumn, lastKey, upperKey)
+		batchStmt.Description = fmt.Sprintf("%s (batch %d)", stmt.Description, batch)
+		rowsAffected, err := r.execOneCommitted(batchStmt)
+		if err != nil {
+			return done, err
+		}
+		done += rowsAffected
+		lastKey = upperKey
+
+		r.progress(FixProgress{
+			Table:       stmt.Table,
+			Description: stmt.Description,
+			Batch:       batch,
+			RowsDone:    int64(done),
+			RowsTotal:   total,
+		})
+
+		if r.opts.BatchSleep > 0 {
+			time.Sleep(r.opts.BatchSleep)
+		}
+		if err := r.waitForReplication(stmt); err != nil {
+			return done, err
+		}
+	}
+}
+
+// execOneCommitted runs a single statement in its own transaction
+func (r *fixRun) execOneCommitted(stmt fixStatement) (int, error) {
+	rowsAffected, err := r.execOne(stmt)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.commit(); err != nil {
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// waitForReplication pauses while replicas lag behind by more than MaxReplicationLag
+func (r *fixRun) waitForReplication(stmt fixStatement) error {
+	if r.opts.MaxReplicationLag <= 0 {
+		return nil
+	}
+
+	pollInterval := r.opts.BatchSleep
+	if pollInterval < time.Second {
+		pollInterval = time.Second
+	}
+
+	for {
+		lag, err := r.db.GetReplicationLag()
+		if err != nil {
+			return fmt.Errorf("failed to read replication lag: %w", err)
+		}
+		if lag <= r.opts.MaxReplicationLag {
+			return nil
+		}
+
+		r.progress(FixProgress{
+			Table:          stmt.Table,
+			Description:    stmt.Description,
+			ReplicationLag: lag,
+		})
+		time.Sleep(pollInterval)
+	}
+}
+
+// progress reports batch progress if a callback is configured
+func (r *fixRun) progress(p FixProgress) {
+	if r.opts.Progress != nil {
+		r.opts.Progress(p)
+	}
+}
+
+// countFixRows counts the rows a fix statement will change
+func (db *DB) countFixRows(stmt fixStatement) (int64, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", db.quoteIdentifier(stmt.Table), stmt.Where)
+	var count int64
+	err := db.conn.QueryRow(query, stmt.Args...).Scan(&count)
+	return count, err
+}
+
+// nextBatchBound returns the largest key of the next batch of at most batchSize rows
+// matching the statement's predicate after lastKey, or nil when no rows are left
+func (db *DB) nextBatchBound(stmt fixStatement, keyColumn string, lastKey interface{}, batchSize int) (interface{}, error) {
+	key := db.quoteIdentifier(keyColumn)
+	where := "(" + stmt.Where + ")"
+	args := append([]interface{}{}, stmt.Args...)
+	if lastKey != nil {
+		args = append(args, lastKey)
+		where += fmt.Sprintf(" AND %s > %s", key, db.dialect.GetPlaceholder(len(args)))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT MAX(batch_keys.k)
+		FROM (
+			SELECT %s AS k
+			FROM %s
+			WHERE %s
+			ORDER BY %s
+			LIMIT %d
+		) batch_keys`,
+		key, db.quoteIdentifier(stmt.Table), where, key, batchSize)
+
+	var bound interface{}
+	if err := db.conn.QueryRow(query, args...).Scan(&bound); err != nil {
+		return nil, err
+	}
+	if b, ok := bound.([]byte); ok {
+		return string(b), nil
+	}
+	return bound, nil
+}
+
+// keyRangeStatement restricts a statement to the keys in (lastKey, upperKey]
+func (db *DB) keyRangeStatement(stmt fixStatement, keyColumn string, lastKey, upperKey interface{}) fixStatement {
+	key := db.quoteIdentifier(keyColumn)
+	where := "(" + stmt.Where + ")"
+	args := append([]interface{}{}, stmt.Args...)
+	if lastKey != nil {
+		args = append(args, lastKey)
+		where += fmt.Sprintf(" AND %s > %s", key, db.dialect.GetPlaceholder(len(args)))
+	}
+	args = append(args, upperKey)
+	where += fmt.Sprintf(" AND %s <= %s", key, db.dialect.GetPlaceholder(len(args)))
+
+	stmt.Where = where
+	stmt.Args = args
+	return stmt
+}
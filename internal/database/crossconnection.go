@@ -0,0 +1,322 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/nkamuo/go-db-migration/internal/config"
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// DefaultCrossConnectionBatchSize is the number of distinct key values checked per
+// query against the referenced connection
+const DefaultCrossConnectionBatchSize = 1000
+
+// ConnectionResolver returns an open connection for a named connection from the configuration
+type ConnectionResolver func(connectionName string) (*DB, error)
+
+// ValidateCrossConnectionReferences checks references into tables that live on other
+// connections. Distinct key values are streamed from this database and looked up in
+// batches on the referenced connection; orphans are reported as foreign key violations.
+func (db *DB) ValidateCrossConnectionReferences(targetSchema models.Schema, resolve ConnectionResolver, batchSize int, validationConfig *config.ValidationConfig) ([]models.ValidationIssue, error) {
+	var issues []models.ValidationIssue
+	if err := db.StreamCrossConnectionViolations(targetSchema, resolve, batchSize, validationConfig, collectIssues(&issues)); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// StreamCrossConnectionViolations checks references into tables that live on other
+// connections, passing every issue to handle as soon as it is found
+func (db *DB) StreamCrossConnectionViolations(targetSchema models.Schema, resolve ConnectionResolver, batchSize int, validationConfig *config.ValidationConfig, handle IssueHandler) error {
+	if batchSize <= 0 {
+		batchSize = DefaultCrossConnectionBatchSize
+	}
+	limit := 1000
+	if validationConfig != nil && validationConfig.MaxIssuesPerTable > 0 {
+		limit = validationConfig.MaxIssuesPerTable
+	}
+
+	for _, table := range targetSchema {
+		for _, ref := range table.CrossConnectionReferences {
+			handled := handlerErrors(handle)
+			err := db.streamCrossConnectionViolations(table, ref, resolve, batchSize, limit, validationConfig, handled.handle)
+			if handled.err != nil {
+				return handled.err
+			}
+			if err != nil {
+				if validationConfig != nil && validationConfig.StopOnFirstError {
+					return fmt.Errorf("failed to validate cross-connection reference '%s' on table %s: %w", ref.ConstraintName, table.TableName, err)
+				}
+				err = handle(models.ValidationIssue{
+					Type:     "foreign_key_validation_error",
+					Severity: "error",
+					Table:    table.TableName,
+					Column:   ref.ColumnName,
+					Message:  fmt.Sprintf("Failed to validate cross-connection reference '%s': %v", ref.ConstraintName, err),
+					Details: map[string]interface{}{
+						"constraint_name":       ref.ConstraintName,
+						"referenced_connection": ref.Connection,
+						"referenced_table":      ref.ReferencedTable,
+						"referenced_column":     ref.ReferencedColumn,
+						"error_type":            "validation_error",
+					},
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// streamCrossConnectionViolations validates a single cross-connection reference, passing
+// the violations of every batch to handle as soon as the batch is checked
+func (db *DB) streamCrossConnectionViolations(table models.Table, ref models.CrossConnectionReference, resolve ConnectionResolver, batchSize, limit int, validationConfig *config.ValidationConfig, handle IssueHandler) error {
+	ignoreMissingTables := validationConfig != nil && validationConfig.IgnoreMissingTables
+	ignoreMissingColumns := validationConfig != nil && validationConfig.IgnoreMissingColumns
+
+	newIssue := func(issueType, column, message string) models.ValidationIssue {
+		return models.ValidationIssue{
+			Type:     issueType,
+			Severity: "error",
+			Table:    table.TableName,
+			Column:   column,
+			Message:  message,
+			Details: map[string]interface{}{
+				"constraint_name":       ref.ConstraintName,
+				"referenced_connection": ref.Connection,
+				"referenced_table":      ref.ReferencedTable,
+				"referenced_column":     ref.ReferencedColumn,
+				"error_type":            issueType,
+			},
+		}
+	}
+
+	// Check the source side
+	sourceExists, err := db.tableExists(table.TableName)
+	if err != nil {
+		return fmt.Errorf("failed to check if source table '%s' exists: %w", table.TableName, err)
+	}
+	if !sourceExists {
+		if ignoreMissingTables {
+			return nil
+		}
+		return handle(newIssue("missing_source_table", ref.ColumnName,
+			fmt.Sprintf("Source table '%s' does not exist in the database (required by cross-connection reference '%s')", table.TableName, ref.ConstraintName)))
+	}
+
+	sourceColExists, err := db.columnExists(table.TableName, ref.ColumnName)
+	if err != nil {
+		return fmt.Errorf("failed to check if source column '%s.%s' exists: %w", table.TableName, ref.ColumnName, err)
+	}
+	if !sourceColExists {
+		if ignoreMissingColumns {
+			return nil
+		}
+		return handle(newIssue("missing_source_column", ref.ColumnName,
+			fmt.Sprintf("Source column '%s.%s' does not exist in the database (required by cross-connection reference '%s')", table.TableName, ref.ColumnName, ref.ConstraintName)))
+	}
+
+	// Check the referenced side on the other connection
+	remote, err := resolve(ref.Connection)
+	if err != nil {
+		return fmt.Errorf("failed to open referenced connection '%s': %w", ref.Connection, err)
+	}
+
+	refExists, err := remote.tableExists(ref.ReferencedTable)
+	if err != nil {
+		return fmt.Errorf("failed to check if referenced table '%s' exists on connection '%s': %w", ref.ReferencedTable, ref.Connection, err)
+	}
+	if !refExists {
+		if ignoreMissingTables {
+			return nil
+		}
+		return handle(newIssue("missing_referenced_table", ref.ColumnName,
+			fmt.Sprintf("Referenced table '%s' does not exist on connection '%s' (required by cross-connection reference '%s')", ref.ReferencedTable, ref.Connection, ref.ConstraintName)))
+	}
+
+	refColExists, err := remote.columnExists(ref.ReferencedTable, ref.ReferencedColumn)
+	if err != nil {
+		return fmt.Errorf("failed to check if referenced column '%s.%s' exists on connection '%s': %w", ref.ReferencedTable, ref.ReferencedColumn, ref.Connection, err)
+	}
+	if !refColExists {
+		if ignoreMissingColumns {
+			return nil
+		}
+		return handle(newIssue("missing_referenced_column", ref.ColumnName,
+			fmt.Sprintf("Referenced column '%s.%s' does not exist on connection '%s' (required by cross-connection reference '%s')", ref.ReferencedTable, ref.ReferencedColumn, ref.Connection, ref.ConstraintName)))
+	}
+
+	// Stream distinct key values from the source and check them in batches
+	query := fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s IS NOT NULL%s",
+		db.quoteIdentifier(ref.ColumnName),
+		db.quoteIdentifier(table.TableName),
+		db.quoteIdentifier(ref.ColumnName),
+		rowFilterCondition(table.RowFilter))
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to read distinct values of %s.%s: %w", table.TableName, ref.ColumnName, err)
+	}
+
+	found := 0
+	batch := make([]string, 0, batchSize)
+	checkBatch := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		missing, err := remote.findMissingValues(ref.ReferencedTable, ref.ReferencedColumn, batch)
+		if err != nil {
+			return fmt.Errorf("failed to look up values in %s.%s on connection '%s': %w", ref.ReferencedTable, ref.ReferencedColumn, ref.Connection, err)
+		}
+		batch = batch[:0]
+		if len(missing) == 0 {
+			return nil
+		}
+
+		// Look up the source rows holding the orphaned values
+		n, err := db.streamCrossConnectionRows(table, ref, missing, batchSize, limit-found, handle)
+		found += n
+		return err
+	}
+
+	for found < limit && rows.Next() {
+		var value sql.NullString
+		if err := rows.Scan(&value); err != nil {
+			rows.Close()
+			return err
+		}
+		batch = append(batch, value.String)
+		if len(batch) >= batchSize {
+			if err := checkBatch(); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	if found >= limit {
+		return nil
+	}
+	return checkBatch()
+}
+
+// findMissingValues returns the values that do not exist in tableName.columnName
+func (db *DB) findMissingValues(tableName, columnName string, values []string) ([]string, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (%s)",
+		db.quoteIdentifier(columnName),
+		db.quoteIdentifier(tableName),
+		db.quoteIdentifier(columnName),
+		db.placeholderList(1, len(values)))
+
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]bool, len(values))
+	for rows.Next() {
+		var value sql.NullString
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		found[value.String] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, value := range values {
+		if !found[value] {
+			missing = append(missing, value)
+		}
+	}
+	return missing, nil
+}
+
+// streamCrossConnectionRows passes a foreign key violation issue to handle for every source
+// row, up to limit, that holds one of the orphaned values. It returns the number of issues
+// passed to handle.
+func (db *DB) streamCrossConnectionRows(table models.Table, ref models.CrossConnectionReference, orphans []string, batchSize, limit int, handle IssueHandler) (int, error) {
+	identifierCol := db.getIdentifierColumn(table.TableName)
+
+	found := 0
+	for start := 0; start < len(orphans) && found < limit; start += batchSize {
+		end := start + batchSize
+		if end > len(orphans) {
+			end = len(orphans)
+		}
+		values := orphans[start:end]
+
+		query := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (%s)%s LIMIT %d",
+			db.quoteIdentifier(ref.ColumnName),
+			db.quoteIdentifier(identifierCol),
+			db.quoteIdentifier(table.TableName),
+			db.quoteIdentifier(ref.ColumnName),
+			db.placeholderList(1, len(values)),
+			rowFilterCondition(table.RowFilter),
+			limit-found)
+
+		args := make([]interface{}, len(values))
+		for i, value := range values {
+			args[i] = value
+		}
+
+		rows, err := db.conn.Query(query, args...)
+		if err != nil {
+			return found, fmt.Errorf("failed to look up rows referencing missing values in %s.%s: %w", table.TableName, ref.ColumnName, err)
+		}
+
+		for rows.Next() {
+			var foreignKeyValue, identifier sql.NullString
+			if err := rows.Scan(&foreignKeyValue, &identifier); err != nil {
+				rows.Close()
+				return found, err
+			}
+
+			err := handle(models.ValidationIssue{
+				Type:     "foreign_key_violation",
+				Severity: "error",
+				Table:    table.TableName,
+				Column:   ref.ColumnName,
+				Message: fmt.Sprintf("Foreign key violation: value '%s' references non-existent record in %s.%s on connection '%s'",
+					foreignKeyValue.String, ref.ReferencedTable, ref.ReferencedColumn, ref.Connection),
+				PrimaryKey: foreignKeyValue.String,
+				Identifier: identifier.String,
+				Details: map[string]interface{}{
+					"constraint_name":       ref.ConstraintName,
+					"referenced_connection": ref.Connection,
+					"referenced_table":      ref.ReferencedTable,
+					"referenced_column":     ref.ReferencedColumn,
+					"foreign_key_value":     foreignKeyValue.String,
+				},
+			})
+			if err != nil {
+				rows.Close()
+				return found, err
+			}
+			found++
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return found, err
+		}
+	}
+
+	return found, nil
+}
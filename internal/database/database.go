@@ -3,6 +3,8 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
@@ -36,9 +38,21 @@ type DatabaseDialect interface {
 	BuildConnectionString(cfg *config.DBConfig) string
 	GetDriverName() string
 	GetIdentifierQuote() string
-	GetTableRowCountQuery(tableName string) string
-	GetNullViolationsQuery(tableName, columnName, identifierCol string) string
-	GetForeignKeyViolationsQuery(fk models.ForeignKey, identifierCol string) string
+	GetTableRowCountQuery(tableName, rowFilter string) string
+	GetNullViolationsQuery(tableName, columnName, identifierCol, rowFilter string, limit int) string
+	GetForeignKeyViolationsQuery(fk models.ForeignKey, identifierCol, rowFilter string) string
+	GetPlaceholder(position int) string
+	GetPolymorphicViolationsQuery(tableName string, rel models.PolymorphicRelation, target models.PolymorphicTarget, identifierCol, rowFilter string, limit int) string
+	GetPolymorphicUnknownTypesQuery(tableName string, rel models.PolymorphicRelation, rowFilter string) string
+	GetForeignKeyMatchQuery(fk models.ForeignKey, rowFilter string) string
+	GetPrimaryKeyQuery() string
+	GetTableCommentQuery() string
+	GetReplicationLagQuery() string
+	GetZeroValueExpression(dataType string) string
+	GetDefaultValueExpression(defaultValue string) string
+	GetColumnDefinitionType(column models.Column) string
+	GetBeginTransactionStatement() string
+	GetRowCountAssertion(statement string, expected int64) string
 }
 
 // NewConnection creates a new database connection with the appropriate dialect
@@ -77,6 +91,21 @@ func NewConnection(cfg *config.DBConfig) (*DB, error) {
 	}, nil
 }
 
+// quoteIdentifier quotes a table or column name using the dialect's identifier quote
+func (db *DB) quoteIdentifier(name string) string {
+	quote := db.dialect.GetIdentifierQuote()
+	return quote + strings.ReplaceAll(name, quote, quote+quote) + quote
+}
+
+// placeholderList returns count comma-separated bind placeholders starting at position start
+func (db *DB) placeholderList(start, count int) string {
+	placeholders := make([]string, count)
+	for i := 0; i < count; i++ {
+		placeholders[i] = db.dialect.GetPlaceholder(start + i)
+	}
+	return strings.Join(placeholders, ", ")
+}
+
 // Close closes the database connection
 func (db *DB) Close() error {
 	if db.conn != nil {
@@ -101,6 +130,13 @@ func (db *DB) GetCurrentSchema() (models.Schema, error) {
 	for _, tableName := range tables {
 		table := models.Table{TableName: tableName}
 
+		// Get table comment
+		comment, err := db.getTableComment(tableName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get comment for table %s: %w", tableName, err)
+		}
+		table.Comment = comment
+
 		// Get columns
 		columns, err := db.getTableColumns(tableName)
 		if err != nil {
@@ -169,6 +205,7 @@ func (db *DB) getTableColumns(tableName string) ([]models.Column, error) {
 		var numericPrecision sql.NullInt64
 		var numericScale sql.NullInt64
 		var datetimePrecision sql.NullInt64
+		var comment sql.NullString
 
 		if err := rows.Scan(
 			&column.ColumnName,
@@ -179,6 +216,7 @@ func (db *DB) getTableColumns(tableName string) ([]models.Column, error) {
 			&numericPrecision,
 			&numericScale,
 			&datetimePrecision,
+			&comment,
 		); err != nil {
 			return nil, err
 		}
@@ -207,12 +245,23 @@ func (db *DB) getTableColumns(tableName string) ([]models.Column, error) {
 			column.DatetimePrecision = &val
 		}
 
+		column.Comment = comment.String
+
 		columns = append(columns, column)
 	}
 
 	return columns, rows.Err()
 }
 
+// getTableComment retrieves the comment of a table, empty when it has none
+func (db *DB) getTableComment(tableName string) (string, error) {
+	var comment sql.NullString
+	if err := db.conn.QueryRow(db.dialect.GetTableCommentQuery(), tableName).Scan(&comment); err != nil {
+		return "", err
+	}
+	return comment.String, nil
+}
+
 // getTableForeignKeys retrieves all foreign keys for a specific table
 func (db *DB) getTableForeignKeys(tableName string) ([]models.ForeignKey, error) {
 	query := db.dialect.GetForeignKeysQuery()
@@ -264,7 +313,15 @@ func (db *DB) tableExists(tableName string) (bool, error) {
 // ValidateForeignKeys checks for foreign key constraint violations
 func (db *DB) ValidateForeignKeys(targetSchema models.Schema) ([]models.ValidationIssue, error) {
 	var issues []models.ValidationIssue
+	if err := db.StreamForeignKeyViolations(targetSchema, collectIssues(&issues)); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
 
+// StreamForeignKeyViolations checks for foreign key constraint violations, passing every
+// issue to handle as soon as it is found
+func (db *DB) StreamForeignKeyViolations(targetSchema models.Schema, handle IssueHandler) error {
 	for _, table := range targetSchema {
 		for _, fk := range table.ForeignKeys {
 			// Ensure the foreign key has the table name set (it might not be in the JSON)
@@ -272,7 +329,11 @@ func (db *DB) ValidateForeignKeys(targetSchema models.Schema) ([]models.Validati
 				fk.TableName = table.TableName
 			}
 
-			violations, err := db.findForeignKeyViolations(fk)
+			handled := handlerErrors(handle)
+			err := db.streamForeignKeyViolations(fk, table.RowFilter, handled.handle)
+			if handled.err != nil {
+				return handled.err
+			}
 			if err != nil {
 				// Instead of returning immediately, create a validation issue for the error
 				issue := models.ValidationIssue{
@@ -288,22 +349,34 @@ func (db *DB) ValidateForeignKeys(targetSchema models.Schema) ([]models.Validati
 						"error_type":        "validation_error",
 					},
 				}
-				issues = append(issues, issue)
+				if err := handle(issue); err != nil {
+					return err
+				}
 				continue // Continue to next foreign key instead of stopping
 			}
-			issues = append(issues, violations...)
 		}
 	}
 
+	return nil
+}
+
+// findForeignKeyViolations finds records that violate a foreign key constraint.
+// Only rows matching rowFilter (when set) are considered.
+func (db *DB) findForeignKeyViolations(fk models.ForeignKey, rowFilter string) ([]models.ValidationIssue, error) {
+	var issues []models.ValidationIssue
+	if err := db.streamForeignKeyViolations(fk, rowFilter, collectIssues(&issues)); err != nil {
+		return nil, err
+	}
 	return issues, nil
 }
 
-// findForeignKeyViolations finds records that violate a foreign key constraint
-func (db *DB) findForeignKeyViolations(fk models.ForeignKey) ([]models.ValidationIssue, error) {
+// streamForeignKeyViolations finds records that violate a foreign key constraint and
+// passes them to handle one by one, as they are read
+func (db *DB) streamForeignKeyViolations(fk models.ForeignKey, rowFilter string, handle IssueHandler) error {
 	// First, check if both tables exist
 	sourceExists, err := db.tableExists(fk.TableName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if source table '%s' exists: %w", fk.TableName, err)
+		return fmt.Errorf("failed to check if source table '%s' exists: %w", fk.TableName, err)
 	}
 	if !sourceExists {
 		// Create a validation issue instead of returning an error
@@ -320,12 +393,12 @@ func (db *DB) findForeignKeyViolations(fk models.ForeignKey) ([]models.Validatio
 				"error_type":        "missing_source_table",
 			},
 		}
-		return []models.ValidationIssue{issue}, nil
+		return handle(issue)
 	}
 
 	referencedExists, err := db.tableExists(fk.ReferencedTable)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if referenced table '%s' exists: %w", fk.ReferencedTable, err)
+		return fmt.Errorf("failed to check if referenced table '%s' exists: %w", fk.ReferencedTable, err)
 	}
 	if !referencedExists {
 		// Create a validation issue instead of returning an error
@@ -342,13 +415,13 @@ func (db *DB) findForeignKeyViolations(fk models.ForeignKey) ([]models.Validatio
 				"error_type":        "missing_referenced_table",
 			},
 		}
-		return []models.ValidationIssue{issue}, nil
+		return handle(issue)
 	}
 
 	// Check if the source column exists
 	sourceColExists, err := db.columnExists(fk.TableName, fk.ColumnName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if source column '%s.%s' exists: %w", fk.TableName, fk.ColumnName, err)
+		return fmt.Errorf("failed to check if source column '%s.%s' exists: %w", fk.TableName, fk.ColumnName, err)
 	}
 	if !sourceColExists {
 		issue := models.ValidationIssue{
@@ -364,13 +437,13 @@ func (db *DB) findForeignKeyViolations(fk models.ForeignKey) ([]models.Validatio
 				"error_type":        "missing_source_column",
 			},
 		}
-		return []models.ValidationIssue{issue}, nil
+		return handle(issue)
 	}
 
 	// Check if the referenced column exists
 	refColExists, err := db.columnExists(fk.ReferencedTable, fk.ReferencedColumn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if referenced column '%s.%s' exists: %w", fk.ReferencedTable, fk.ReferencedColumn, err)
+		return fmt.Errorf("failed to check if referenced column '%s.%s' exists: %w", fk.ReferencedTable, fk.ReferencedColumn, err)
 	}
 	if !refColExists {
 		issue := models.ValidationIssue{
@@ -386,25 +459,24 @@ func (db *DB) findForeignKeyViolations(fk models.ForeignKey) ([]models.Validatio
 				"error_type":        "missing_referenced_column",
 			},
 		}
-		return []models.ValidationIssue{issue}, nil
+		return handle(issue)
 	}
 
 	// Build query to find orphaned records using dialect
 	identifierCol := db.getIdentifierColumn(fk.TableName)
-	query := db.dialect.GetForeignKeyViolationsQuery(fk, identifierCol)
+	query := db.dialect.GetForeignKeyViolationsQuery(fk, identifierCol, rowFilter)
 
 	rows, err := db.conn.Query(query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute foreign key validation query for constraint '%s' (table: %s, column: %s, references: %s.%s): %w",
+		return fmt.Errorf("failed to execute foreign key validation query for constraint '%s' (table: %s, column: %s, references: %s.%s): %w",
 			fk.ConstraintName, fk.TableName, fk.ColumnName, fk.ReferencedTable, fk.ReferencedColumn, err)
 	}
 	defer rows.Close()
 
-	var issues []models.ValidationIssue
 	for rows.Next() {
 		var foreignKeyValue, identifier sql.NullString
 		if err := rows.Scan(&foreignKeyValue, &identifier); err != nil {
-			return nil, err
+			return err
 		}
 
 		issue := models.ValidationIssue{
@@ -423,10 +495,12 @@ func (db *DB) findForeignKeyViolations(fk models.ForeignKey) ([]models.Validatio
 				"foreign_key_value": foreignKeyValue.String,
 			},
 		}
-		issues = append(issues, issue)
+		if err := handle(issue); err != nil {
+			return err
+		}
 	}
 
-	return issues, rows.Err()
+	return rows.Err()
 }
 
 // ValidateNotNullConstraints checks for null values in columns that should be NOT NULL
@@ -437,6 +511,15 @@ func (db *DB) ValidateNotNullConstraints(targetSchema models.Schema) ([]models.V
 // ValidateNotNullConstraintsWithConfig checks for null values with validation configuration
 func (db *DB) ValidateNotNullConstraintsWithConfig(targetSchema models.Schema, validationConfig *config.ValidationConfig) ([]models.ValidationIssue, error) {
 	var issues []models.ValidationIssue
+	if err := db.StreamNullViolations(targetSchema, validationConfig, collectIssues(&issues)); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// StreamNullViolations checks for null values with validation configuration, passing
+// every issue to handle as soon as it is found
+func (db *DB) StreamNullViolations(targetSchema models.Schema, validationConfig *config.ValidationConfig, handle IssueHandler) error {
 	var defaultConfig config.ValidationConfig
 
 	if validationConfig == nil {
@@ -454,15 +537,17 @@ func (db *DB) ValidateNotNullConstraintsWithConfig(targetSchema models.Schema, v
 		tableExists, err := db.tableExists(table.TableName)
 		if err != nil {
 			if validationConfig.StopOnFirstError {
-				return nil, fmt.Errorf("failed to check if table %s exists: %w", table.TableName, err)
+				return fmt.Errorf("failed to check if table %s exists: %w", table.TableName, err)
 			}
 			// Add as validation issue and continue
-			issues = append(issues, models.ValidationIssue{
+			if err := handle(models.ValidationIssue{
 				Type:     "table_check_error",
 				Severity: "error",
 				Table:    table.TableName,
 				Message:  fmt.Sprintf("Failed to check if table exists: %v", err),
-			})
+			}); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -471,12 +556,14 @@ func (db *DB) ValidateNotNullConstraintsWithConfig(targetSchema models.Schema, v
 				continue // Skip this table
 			}
 			// Add as validation issue
-			issues = append(issues, models.ValidationIssue{
+			if err := handle(models.ValidationIssue{
 				Type:     "missing_table",
 				Severity: "warning",
 				Table:    table.TableName,
 				Message:  fmt.Sprintf("Table '%s' does not exist in database", table.TableName),
-			})
+			}); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -486,15 +573,17 @@ func (db *DB) ValidateNotNullConstraintsWithConfig(targetSchema models.Schema, v
 				columnExists, err := db.columnExists(table.TableName, column.ColumnName)
 				if err != nil {
 					if validationConfig.StopOnFirstError {
-						return nil, fmt.Errorf("failed to check if column %s.%s exists: %w", table.TableName, column.ColumnName, err)
+						return fmt.Errorf("failed to check if column %s.%s exists: %w", table.TableName, column.ColumnName, err)
 					}
-					issues = append(issues, models.ValidationIssue{
+					if err := handle(models.ValidationIssue{
 						Type:     "column_check_error",
 						Severity: "error",
 						Table:    table.TableName,
 						Column:   column.ColumnName,
 						Message:  fmt.Sprintf("Failed to check if column exists: %v", err),
-					})
+					}); err != nil {
+						return err
+					}
 					continue
 				}
 
@@ -502,40 +591,58 @@ func (db *DB) ValidateNotNullConstraintsWithConfig(targetSchema models.Schema, v
 					if validationConfig.IgnoreMissingColumns {
 						continue // Skip this column
 					}
-					issues = append(issues, models.ValidationIssue{
+					if err := handle(models.ValidationIssue{
 						Type:     "missing_column",
 						Severity: "warning",
 						Table:    table.TableName,
 						Column:   column.ColumnName,
 						Message:  fmt.Sprintf("Column '%s.%s' does not exist in database", table.TableName, column.ColumnName),
-					})
+					}); err != nil {
+						return err
+					}
 					continue
 				}
 
-				violations, err := db.findNullViolations(table.TableName, column, validationConfig.MaxIssuesPerTable)
+				handled := handlerErrors(handle)
+				err = db.streamNullViolations(table.TableName, column, table.RowFilter, handled.handle, validationConfig.MaxIssuesPerTable)
+				if handled.err != nil {
+					return handled.err
+				}
 				if err != nil {
 					if validationConfig.StopOnFirstError {
-						return nil, fmt.Errorf("failed to validate NOT NULL constraint for %s.%s: %w", table.TableName, column.ColumnName, err)
+						return fmt.Errorf("failed to validate NOT NULL constraint for %s.%s: %w", table.TableName, column.ColumnName, err)
 					}
-					issues = append(issues, models.ValidationIssue{
+					if err := handle(models.ValidationIssue{
 						Type:     "validation_error",
 						Severity: "error",
 						Table:    table.TableName,
 						Column:   column.ColumnName,
 						Message:  fmt.Sprintf("Failed to validate NOT NULL constraint: %v", err),
-					})
+					}); err != nil {
+						return err
+					}
 					continue
 				}
-				issues = append(issues, violations...)
 			}
 		}
 	}
 
+	return nil
+}
+
+// findNullViolations finds records with null values in columns that should be NOT NULL.
+// Only rows matching rowFilter (when set) are considered.
+func (db *DB) findNullViolations(tableName string, column models.Column, rowFilter string, maxIssues ...int) ([]models.ValidationIssue, error) {
+	var issues []models.ValidationIssue
+	if err := db.streamNullViolations(tableName, column, rowFilter, collectIssues(&issues), maxIssues...); err != nil {
+		return nil, err
+	}
 	return issues, nil
 }
 
-// findNullViolations finds records with null values in columns that should be NOT NULL
-func (db *DB) findNullViolations(tableName string, column models.Column, maxIssues ...int) ([]models.ValidationIssue, error) {
+// streamNullViolations finds records with null values in a NOT NULL column and passes
+// them to handle one by one, as they are read
+func (db *DB) streamNullViolations(tableName string, column models.Column, rowFilter string, handle IssueHandler, maxIssues ...int) error {
 	limit := 1000 // Default limit
 	if len(maxIssues) > 0 && maxIssues[0] > 0 {
 		limit = maxIssues[0]
@@ -543,24 +650,19 @@ func (db *DB) findNullViolations(tableName string, column models.Column, maxIssu
 
 	identifierCol := db.getIdentifierColumn(tableName)
 
-	// Build query with custom limit (using quoted identifiers for PostgreSQL compatibility)
-	query := fmt.Sprintf(`
-		SELECT "%s"
-		FROM "%s"
-		WHERE "%s" IS NULL
-		LIMIT %d`, identifierCol, tableName, column.ColumnName, limit)
+	// Build query with custom limit using the dialect's identifier quoting
+	query := db.dialect.GetNullViolationsQuery(tableName, column.ColumnName, identifierCol, rowFilter, limit)
 
 	rows, err := db.conn.Query(query)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var issues []models.ValidationIssue
 	for rows.Next() {
 		var identifier sql.NullString
 		if err := rows.Scan(&identifier); err != nil {
-			return nil, err
+			return err
 		}
 
 		issue := models.ValidationIssue{
@@ -575,10 +677,12 @@ func (db *DB) findNullViolations(tableName string, column models.Column, maxIssu
 				"data_type": column.DataType,
 			},
 		}
-		issues = append(issues, issue)
+		if err := handle(issue); err != nil {
+			return err
+		}
 	}
 
-	return issues, rows.Err()
+	return rows.Err()
 }
 
 // getIdentifierColumn returns the best column to use as an identifier for a table
@@ -622,32 +726,73 @@ func (db *DB) columnExists(tableName, columnName string) (bool, error) {
 	return true, nil
 }
 
-// GetTableRowCount returns the number of rows in a table
-func (db *DB) GetTableRowCount(tableName string) (int64, error) {
-	query := db.dialect.GetTableRowCountQuery(tableName)
+// GetTableRowCount returns the number of rows in a table that match rowFilter
+// (all rows when rowFilter is empty)
+func (db *DB) GetTableRowCount(tableName, rowFilter string) (int64, error) {
+	query := db.dialect.GetTableRowCountQuery(tableName, rowFilter)
 	var count int64
 	err := db.conn.QueryRow(query).Scan(&count)
 	return count, err
 }
 
-// FixForeignKeyViolations fixes foreign key constraint violations
-func (db *DB) FixForeignKeyViolations(targetSchema models.Schema, action string, dryRun bool, validationConfig *config.ValidationConfig) (models.FixResults, error) {
+// MeasureForeignKeyMatch returns the number of distinct non-null values in the foreign
+// key column and how many of them exist in the referenced column
+func (db *DB) MeasureForeignKeyMatch(fk models.ForeignKey, rowFilter string) (distinct, matched int64, err error) {
+	query := db.dialect.GetForeignKeyMatchQuery(fk, rowFilter)
+	err = db.conn.QueryRow(query).Scan(&distinct, &matched)
+	return distinct, matched, err
+}
+
+// ForeignKeyFix describes how foreign key violations are fixed
+type ForeignKeyFix struct {
+	// Action is one of remove, set-null, create-parent or reassign
+	Action string
+	// Constraint restricts the fix to a single foreign key constraint
+	Constraint string
+	// ParentDefaults are column values for the stub parent rows created by create-parent
+	ParentDefaults map[string]string
+	// ReassignTo is the existing parent key that reassign points orphans at
+	ReassignTo string
+	// ValueMapping maps orphaned key values to existing parent keys for reassign
+	ValueMapping map[string]string
+}
+
+// FixForeignKeyViolations fixes foreign key constraint violations. Changes are made inside
+// transactions according to opts.CommitPolicy, with a savepoint per constraint.
+func (db *DB) FixForeignKeyViolations(targetSchema models.Schema, fix ForeignKeyFix, opts FixOptions, validationConfig *config.ValidationConfig) (models.FixResults, error) {
 	results := make(models.FixResults)
+	action := fix.Action
 
-	for _, table := range targetSchema {
+	run, err := db.newFixRun(opts, "fk", action, results)
+	if err != nil {
+		return nil, err
+	}
+
+	var graph cascadeGraph
+	if opts.DryRun {
+		if graph, err = db.loadCascadeGraph(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parents before children, so fixes run in dependency order
+	for _, table := range targetSchema.OrderedByDependency() {
 		for _, fk := range table.ForeignKeys {
 			// Ensure the foreign key has the table name set (it might not be in the JSON)
 			if fk.TableName == "" {
 				fk.TableName = table.TableName
 			}
-			
+			if fix.Constraint != "" && fk.ConstraintName != fix.Constraint {
+				continue
+			}
+
 			tableName := fk.TableName
 			if _, exists := results[tableName]; !exists {
 				results[tableName] = models.FixResult{}
 			}
 
 			// Find violations first
-			violations, err := db.findForeignKeyViolations(fk)
+			violations, err := db.findForeignKeyViolations(fk, table.RowFilter)
 			if err != nil {
 				if validationConfig != nil && validationConfig.IgnoreMissingTables {
 					continue
@@ -658,53 +803,103 @@ func (db *DB) FixForeignKeyViolations(targetSchema models.Schema, action string,
 				continue
 			}
 
-			violationCount := len(violations)
-			if violationCount == 0 {
+			if len(violations) == 0 {
 				continue
 			}
 
+			// The violations found above are a capped sample; count all of them
 			result := results[tableName]
+			violationCount, err := db.countViolatingRows(tableName, db.foreignKeyViolationCondition(fk, table.RowFilter))
+			if err != nil {
+				result.Error = err.Error()
+				results[tableName] = result
+				continue
+			}
 			result.IssuesFound += violationCount
 
-			if !dryRun {
-				var recordsAffected int
-				var fixErr error
+			// Build the statements up front so that dry runs also check reassignment targets
+			stmts, err := db.foreignKeyFixStatements(fk, fix, table.RowFilter, opts)
+			if err != nil && opts.DryRun {
+				result.Error = err.Error()
+				results[tableName] = result
+				continue
+			}
 
-				switch action {
-				case "remove":
-					recordsAffected, fixErr = db.removeForeignKeyViolatingRecords(fk)
-				case "set-null":
-					recordsAffected, fixErr = db.setForeignKeyColumnsToNull(fk)
-				default:
-					fixErr = fmt.Errorf("unknown action: %s", action)
+			if !opts.DryRun {
+				var recordsAffected int
+				if err == nil {
+					recordsAffected, err = run.execAll(stmts)
 				}
 
-				if fixErr != nil {
-					result.Error = fixErr.Error()
-					result.Success = false
-				} else {
+				if err != nil {
+					// Batches committed before the failure stay applied
 					result.RecordsAffected += recordsAffected
-					result.Success = true
+					results[tableName] = result
+					if run.failFix(tableName, err) {
+						return results, nil
+					}
+					// The table's changes were rolled back; leave the rest of it untouched
+					break
 				}
+
+				result.RecordsAffected += recordsAffected
+				result.Success = true
 			} else {
-				// In dry-run mode, count what would be affected
-				result.RecordsAffected += violationCount
+				// In dry-run mode, count exactly what would be affected, including the rows
+				// that foreign keys referencing the changed rows would cascade to
+				count, impact, err := db.dryRunCounts(graph, stmts)
+				if err != nil {
+					result.Error = err.Error()
+					results[tableName] = result
+					continue
+				}
+				result.RecordsAffected += count
+				result.Impact = mergeCascadeImpact(result.Impact, impact)
 				result.Success = true
-				result.Details = fmt.Sprintf("Would %s %d records", action, violationCount)
+				switch action {
+				case "create-parent":
+					result.Details = fmt.Sprintf("Would create %d parent rows in %s", count, fk.ReferencedTable)
+				case "reassign":
+					result.Details = fmt.Sprintf("Would reassign %d records to existing %s rows", count, fk.ReferencedTable)
+				default:
+					result.Details = fmt.Sprintf("Would %s %d records", action, count)
+				}
 			}
 
 			results[tableName] = result
 		}
+
+		if err := run.endTable(); err != nil {
+			return results, err
+		}
+	}
+
+	if err := run.finish(); err != nil {
+		return results, err
 	}
 
 	return results, nil
 }
 
-// FixNullValueViolations fixes NULL value violations for NOT NULL constraints
-func (db *DB) FixNullValueViolations(targetSchema models.Schema, action, defaultValue string, dryRun bool, validationConfig *config.ValidationConfig) (models.FixResults, error) {
+// FixNullValueViolations fixes NULL value violations for NOT NULL constraints, using the
+// strategy the plan gives for each column. Changes are made inside transactions according
+// to opts.CommitPolicy, with a savepoint per column.
+func (db *DB) FixNullValueViolations(targetSchema models.Schema, plan *config.FixPlan, opts FixOptions, validationConfig *config.ValidationConfig) (models.FixResults, error) {
 	results := make(models.FixResults)
 
-	for _, table := range targetSchema {
+	run, err := db.newFixRun(opts, "null", plan.Name, results)
+	if err != nil {
+		return nil, err
+	}
+
+	var graph cascadeGraph
+	if opts.DryRun {
+		if graph, err = db.loadCascadeGraph(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, table := range targetSchema.OrderedByDependency() {
 		tableName := table.TableName
 		if _, exists := results[tableName]; !exists {
 			results[tableName] = models.FixResult{}
@@ -715,6 +910,11 @@ func (db *DB) FixNullValueViolations(targetSchema models.Schema, action, default
 				continue
 			}
 
+			strategy, ok := plan.StrategyFor(tableName, column.ColumnName)
+			if !ok || strategy.Action == config.FixActionSkip {
+				continue
+			}
+
 			// Check if table/column exists
 			if validationConfig != nil {
 				if validationConfig.IgnoreMissingTables {
@@ -732,7 +932,7 @@ func (db *DB) FixNullValueViolations(targetSchema models.Schema, action, default
 			}
 
 			// Find null violations
-			violations, err := db.findNullViolations(tableName, column)
+			violations, err := db.findNullViolations(tableName, column, table.RowFilter)
 			if err != nil {
 				result := results[tableName]
 				result.Error = err.Error()
@@ -740,130 +940,415 @@ func (db *DB) FixNullValueViolations(targetSchema models.Schema, action, default
 				continue
 			}
 
-			violationCount := len(violations)
-			if violationCount == 0 {
+			if len(violations) == 0 {
 				continue
 			}
 
+			// The violations found above are a capped sample; count all of them
 			result := results[tableName]
+			violationCount, err := db.countViolatingRows(tableName, db.nullValueCondition(column.ColumnName, table.RowFilter))
+			if err != nil {
+				result.Error = err.Error()
+				results[tableName] = result
+				continue
+			}
 			result.IssuesFound += violationCount
 
-			if !dryRun {
-				var recordsAffected int
-				var fixErr error
+			// Build the statements up front so that dry runs also report unusable strategies
+			stmts, err := db.nullFixStatements(table, column, strategy, opts)
+			if err != nil && opts.DryRun {
+				result.Error = err.Error()
+				results[tableName] = result
+				continue
+			}
 
-				switch action {
-				case "remove":
-					recordsAffected, fixErr = db.removeNullValueRecords(tableName, column.ColumnName)
-				case "set-default":
-					recordsAffected, fixErr = db.setNullValuesToDefault(tableName, column.ColumnName, defaultValue)
-				default:
-					fixErr = fmt.Errorf("unknown action: %s", action)
+			if !opts.DryRun {
+				var recordsAffected int
+				if err == nil {
+					recordsAffected, err = run.execAll(stmts)
 				}
 
-				if fixErr != nil {
-					result.Error = fixErr.Error()
-					result.Success = false
-				} else {
+				if err != nil {
+					// Batches committed before the failure stay applied
 					result.RecordsAffected += recordsAffected
-					result.Success = true
+					results[tableName] = result
+					if run.failFix(tableName, err) {
+						return results, nil
+					}
+					// The table's changes were rolled back; leave the rest of it untouched
+					break
 				}
+
+				result.RecordsAffected += recordsAffected
+				result.Success = true
 			} else {
-				// In dry-run mode, count what would be affected
-				result.RecordsAffected += violationCount
+				// In dry-run mode, count exactly what would be affected, including the rows
+				// that foreign keys referencing the changed rows would cascade to
+				count, impact, err := db.dryRunCounts(graph, stmts)
+				if err != nil {
+					result.Error = err.Error()
+					results[tableName] = result
+					continue
+				}
+				result.RecordsAffected += count
+				result.Impact = mergeCascadeImpact(result.Impact, impact)
 				result.Success = true
-				result.Details = fmt.Sprintf("Would %s %d records in column %s", action, violationCount, column.ColumnName)
+				result.Details = fmt.Sprintf("Would %s %d records in column %s", strategy.Action, count, column.ColumnName)
 			}
 
 			results[tableName] = result
 		}
+
+		if err := run.endTable(); err != nil {
+			return results, err
+		}
+	}
+
+	if err := run.finish(); err != nil {
+		return results, err
 	}
 
 	return results, nil
 }
 
-// Helper methods for actual fix operations
+// Statement builders for the actual fix operations.
+// Every statement is restricted by the table's row filter so fixes only touch in-scope rows.
 
-func (db *DB) removeForeignKeyViolatingRecords(fk models.ForeignKey) (int, error) {
-	query := fmt.Sprintf(`
-		DELETE FROM "%s"
-		WHERE "%s" IS NOT NULL
+// foreignKeyViolationCondition returns the predicate selecting the rows that violate fk
+func (db *DB) foreignKeyViolationCondition(fk models.ForeignKey, rowFilter string) string {
+	return fmt.Sprintf(`%s IS NOT NULL
 		  AND NOT EXISTS (
-			SELECT 1 FROM "%s" AS ref_table
-			WHERE ref_table."%s" = "%s"."%s"
-		  )`,
-		fk.TableName,
-		fk.ColumnName,
-		fk.ReferencedTable,
-		fk.ReferencedColumn,
-		fk.TableName,
-		fk.ColumnName)
-
-	result, err := db.conn.Exec(query)
-	if err != nil {
-		return 0, err
-	}
+			SELECT 1 FROM %s AS ref_table
+			WHERE ref_table.%s = %s.%s
+		  )%s`,
+		db.quoteIdentifier(fk.ColumnName),
+		db.quoteIdentifier(fk.ReferencedTable),
+		db.quoteIdentifier(fk.ReferencedColumn),
+		db.quoteIdentifier(fk.TableName),
+		db.quoteIdentifier(fk.ColumnName),
+		rowFilterCondition(rowFilter))
+}
 
-	rowsAffected, err := result.RowsAffected()
-	return int(rowsAffected), err
+// nullValueCondition returns the predicate selecting the rows with NULL in columnName
+func (db *DB) nullValueCondition(columnName, rowFilter string) string {
+	return fmt.Sprintf("%s IS NULL%s", db.quoteIdentifier(columnName), rowFilterCondition(rowFilter))
 }
 
-func (db *DB) setForeignKeyColumnsToNull(fk models.ForeignKey) (int, error) {
-	query := fmt.Sprintf(`
-		UPDATE "%s"
-		SET "%s" = NULL
-		WHERE "%s" IS NOT NULL
+// polymorphicViolationCondition returns the predicate selecting the rows of the given type
+// whose id has no match in the target table
+func (db *DB) polymorphicViolationCondition(tableName string, rel models.PolymorphicRelation, target models.PolymorphicTarget) string {
+	return db.inlineArgs(fmt.Sprintf(`%s = %s
+		  AND %s IS NOT NULL
 		  AND NOT EXISTS (
-			SELECT 1 FROM "%s" AS ref_table
-			WHERE ref_table."%s" = "%s"."%s"
+			SELECT 1 FROM %s AS ref_table
+			WHERE ref_table.%s = %s.%s
 		  )`,
-		fk.TableName,
-		fk.ColumnName,
-		fk.ColumnName,
-		fk.ReferencedTable,
-		fk.ReferencedColumn,
-		fk.TableName,
-		fk.ColumnName)
-
-	result, err := db.conn.Exec(query)
-	if err != nil {
-		return 0, err
+		db.quoteIdentifier(rel.TypeColumn), db.dialect.GetPlaceholder(1),
+		db.quoteIdentifier(rel.IDColumn),
+		db.quoteIdentifier(target.ReferencedTable),
+		db.quoteIdentifier(target.GetReferencedColumn()),
+		db.quoteIdentifier(tableName),
+		db.quoteIdentifier(rel.IDColumn)), []interface{}{target.TypeValue})
+}
+
+// unknownPolymorphicTypeCondition returns the predicate selecting the rows whose type value
+// is not mapped to a table by the relation
+func (db *DB) unknownPolymorphicTypeCondition(rel models.PolymorphicRelation) string {
+	condition := db.quoteIdentifier(rel.TypeColumn) + " IS NOT NULL"
+	values := rel.GetTypeValues()
+	if len(values) == 0 {
+		return condition
 	}
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return db.inlineArgs(fmt.Sprintf("%s AND %s NOT IN (%s)", condition,
+		db.quoteIdentifier(rel.TypeColumn), db.placeholderList(1, len(values))), args)
+}
 
-	rowsAffected, err := result.RowsAffected()
-	return int(rowsAffected), err
+// checkViolationConditions returns the predicates selecting the rows of table that a
+// validation check reports as violating, without the row filter. Checks are named as in
+// the table summary of a validation report. ok is false when the check cannot be counted
+// with a query on this database.
+func (db *DB) checkViolationConditions(table models.Table, check string) (conditions []string, ok bool) {
+	switch check {
+	case "foreign_keys":
+		for _, fk := range table.ForeignKeys {
+			if fk.TableName == "" {
+				fk.TableName = table.TableName
+			}
+			conditions = append(conditions, db.foreignKeyViolationCondition(fk, ""))
+		}
+	case "not_null":
+		for _, column := range table.Columns {
+			if column.IsNotNull() {
+				conditions = append(conditions, db.nullValueCondition(column.ColumnName, ""))
+			}
+		}
+	case "polymorphic":
+		for _, rel := range table.PolymorphicRelations {
+			for _, target := range rel.Targets {
+				conditions = append(conditions, db.polymorphicViolationCondition(table.TableName, rel, target))
+			}
+			conditions = append(conditions, db.unknownPolymorphicTypeCondition(rel))
+		}
+	case "cross_connection":
+		// The referenced rows live on another connection
+		return nil, len(table.CrossConnectionReferences) == 0
+	default:
+		return nil, false
+	}
+	return conditions, true
 }
 
-func (db *DB) removeNullValueRecords(tableName, columnName string) (int, error) {
-	query := fmt.Sprintf(`
-		DELETE FROM "%s"
-		WHERE "%s" IS NULL`,
-		tableName,
-		columnName)
+// CountViolatingRows returns the exact number of rows of table, within its row filter,
+// that violate any of the given validation checks. ok is false when one of the checks
+// cannot be counted with a query.
+func (db *DB) CountViolatingRows(table models.Table, checks []string) (count int64, ok bool, err error) {
+	var conditions []string
+	for _, check := range checks {
+		checkConditions, ok := db.checkViolationConditions(table, check)
+		if !ok {
+			return 0, false, nil
+		}
+		conditions = append(conditions, checkConditions...)
+	}
+	if len(conditions) == 0 {
+		return 0, true, nil
+	}
 
-	result, err := db.conn.Exec(query)
+	where := "((" + strings.Join(conditions, ") OR (") + "))" + rowFilterCondition(table.RowFilter)
+	count, err = db.countFixRows(fixStatement{Table: table.TableName, Where: where})
 	if err != nil {
-		return 0, err
+		return 0, false, fmt.Errorf("failed to count violating rows in %s: %w", table.TableName, err)
 	}
+	return count, true, nil
+}
 
-	rowsAffected, err := result.RowsAffected()
-	return int(rowsAffected), err
+// foreignKeyFixStatements builds the statements that fix the rows violating fk
+func (db *DB) foreignKeyFixStatements(fk models.ForeignKey, fix ForeignKeyFix, rowFilter string, opts FixOptions) ([]fixStatement, error) {
+	switch fix.Action {
+	case "remove":
+		return []fixStatement{{
+			Table:           fk.TableName,
+			Description:     fmt.Sprintf("DELETE FROM %s violating %s", fk.TableName, fk.ConstraintName),
+			Operation:       fixOperationDelete,
+			Where:           db.foreignKeyViolationCondition(fk, rowFilter),
+			SingleStatement: fk.ReferencedTable == fk.TableName,
+		}}, nil
+	case "set-null":
+		return []fixStatement{{
+			Table:       fk.TableName,
+			Description: fmt.Sprintf("UPDATE %s SET %s = NULL violating %s", fk.TableName, fk.ColumnName, fk.ConstraintName),
+			Operation:   fixOperationUpdate,
+			Column:      fk.ColumnName,
+			Where:       db.foreignKeyViolationCondition(fk, rowFilter),
+		}}, nil
+	case "quarantine":
+		reason := fmt.Sprintf("%s.%s references a missing %s.%s", fk.TableName, fk.ColumnName, fk.ReferencedTable, fk.ReferencedColumn)
+		return db.quarantineStatements(fk.TableName, db.foreignKeyViolationCondition(fk, rowFilter), reason, fk.ConstraintName, opts)
+	case "create-parent":
+		return []fixStatement{db.createParentStatement(fk, fix.ParentDefaults, rowFilter)}, nil
+	case "reassign":
+		return db.reassignStatements(fk, fix, rowFilter)
+	default:
+		return nil, fmt.Errorf("unknown action: %s", fix.Action)
+	}
+}
+
+// createParentStatement builds the statement inserting a stub parent row for every missing
+// key value referenced by fk, filling the other columns from defaults
+func (db *DB) createParentStatement(fk models.ForeignKey, defaults map[string]string, rowFilter string) fixStatement {
+	defaultColumns := make([]string, 0, len(defaults))
+	for column := range defaults {
+		if column != fk.ReferencedColumn {
+			defaultColumns = append(defaultColumns, column)
+		}
+	}
+	sort.Strings(defaultColumns)
+
+	columns := append([]string{fk.ReferencedColumn}, defaultColumns...)
+	selectList := []string{fmt.Sprintf("missing_keys.%s", db.quoteIdentifier(fk.ReferencedColumn))}
+	var args []interface{}
+	for _, column := range defaultColumns {
+		args = append(args, defaults[column])
+		selectList = append(selectList, fmt.Sprintf("%s AS %s", db.dialect.GetPlaceholder(len(args)), db.quoteIdentifier(column)))
+	}
+
+	return fixStatement{
+		Table:       fk.ReferencedTable,
+		ResultTable: fk.TableName,
+		Description: fmt.Sprintf("INSERT INTO %s stub rows for %s", fk.ReferencedTable, fk.ConstraintName),
+		Operation:   fixOperationInsert,
+		KeyColumn:   fk.ReferencedColumn,
+		Columns:     columns,
+		Source: fmt.Sprintf(`SELECT %s
+		FROM (
+			SELECT DISTINCT %s.%s AS %s
+			FROM %s
+			WHERE %s
+		) missing_keys`,
+			strings.Join(selectList, ", "),
+			db.quoteIdentifier(fk.TableName), db.quoteIdentifier(fk.ColumnName), db.quoteIdentifier(fk.ReferencedColumn),
+			db.quoteIdentifier(fk.TableName), db.foreignKeyViolationCondition(fk, rowFilter)),
+		Args: args,
+		// New parents change the predicate of later batches, and the source is one small scan
+		SingleStatement: true,
+	}
 }
 
-func (db *DB) setNullValuesToDefault(tableName, columnName, defaultValue string) (int, error) {
-	query := fmt.Sprintf(`
-		UPDATE "%s"
-		SET "%s" = $1
-		WHERE "%s" IS NULL`,
-		tableName,
-		columnName,
-		columnName)
+// reassignStatements builds the statements pointing orphans at existing parents: every
+// orphan at ReassignTo, or each orphaned value at its target from ValueMapping
+func (db *DB) reassignStatements(fk models.ForeignKey, fix ForeignKeyFix, rowFilter string) ([]fixStatement, error) {
+	condition := db.foreignKeyViolationCondition(fk, rowFilter)
+
+	if fix.ReassignTo != "" {
+		if err := db.checkParentValuesExist(fk, []string{fix.ReassignTo}); err != nil {
+			return nil, err
+		}
+		return []fixStatement{{
+			Table:       fk.TableName,
+			Description: fmt.Sprintf("UPDATE %s SET %s = '%s' violating %s", fk.TableName, fk.ColumnName, fix.ReassignTo, fk.ConstraintName),
+			Operation:   fixOperationUpdate,
+			Column:      fk.ColumnName,
+			Value:       fix.ReassignTo,
+			Where:       condition,
+		}}, nil
+	}
+
+	if len(fix.ValueMapping) == 0 {
+		return nil, fmt.Errorf("reassign requires a target value or a value mapping")
+	}
+
+	// One statement per target value, so every statement assigns a single value
+	oldValuesByTarget := make(map[string][]string)
+	for oldValue, newValue := range fix.ValueMapping {
+		oldValuesByTarget[newValue] = append(oldValuesByTarget[newValue], oldValue)
+	}
+	targets := make([]string, 0, len(oldValuesByTarget))
+	for target := range oldValuesByTarget {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	if err := db.checkParentValuesExist(fk, targets); err != nil {
+		return nil, err
+	}
 
-	result, err := db.conn.Exec(query, defaultValue)
+	var stmts []fixStatement
+	for _, target := range targets {
+		oldValues := oldValuesByTarget[target]
+		sort.Strings(oldValues)
+
+		args := make([]interface{}, len(oldValues))
+		for i, value := range oldValues {
+			args[i] = value
+		}
+		stmts = append(stmts, fixStatement{
+			Table:       fk.TableName,
+			Description: fmt.Sprintf("UPDATE %s SET %s = '%s' for %d mapped value(s) violating %s", fk.TableName, fk.ColumnName, target, len(oldValues), fk.ConstraintName),
+			Operation:   fixOperationUpdate,
+			Column:      fk.ColumnName,
+			Value:       target,
+			Where: fmt.Sprintf("%s\n\t\t  AND %s IN (%s)",
+				condition, db.quoteIdentifier(fk.ColumnName), db.placeholderList(1, len(oldValues))),
+			Args: args,
+		})
+	}
+	return stmts, nil
+}
+
+// checkParentValuesExist returns an error naming the values missing from the referenced column
+func (db *DB) checkParentValuesExist(fk models.ForeignKey, values []string) error {
+	missing, err := db.findMissingValues(fk.ReferencedTable, fk.ReferencedColumn, values)
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("failed to look up reassignment targets in %s.%s: %w", fk.ReferencedTable, fk.ReferencedColumn, err)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("reassignment target(s) not found in %s.%s: %s", fk.ReferencedTable, fk.ReferencedColumn, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// nullFixStatements builds the statements fixing the NULL values of a column with strategy
+func (db *DB) nullFixStatements(table models.Table, column models.Column, strategy config.ColumnFixStrategy, opts FixOptions) ([]fixStatement, error) {
+	if strategy.Action != config.FixActionQuarantine {
+		stmt, err := db.nullFixStatement(table, column, strategy)
+		return []fixStatement{stmt}, err
+	}
+	reason := fmt.Sprintf("%s.%s is NULL but must not be", table.TableName, column.ColumnName)
+	constraint := fmt.Sprintf("%s NOT NULL", column.ColumnName)
+	return db.quarantineStatements(table.TableName, db.nullValueCondition(column.ColumnName, table.RowFilter), reason, constraint, opts)
+}
+
+// nullFixStatement builds the statement fixing the NULL values of a column with strategy
+func (db *DB) nullFixStatement(table models.Table, column models.Column, strategy config.ColumnFixStrategy) (fixStatement, error) {
+	tableName, columnName := table.TableName, column.ColumnName
+	stmt := fixStatement{
+		Table:     tableName,
+		Operation: fixOperationUpdate,
+		Column:    columnName,
+		Where:     db.nullValueCondition(columnName, table.RowFilter),
+	}
+
+	switch strategy.Action {
+	case config.FixActionRemove:
+		stmt.Operation = fixOperationDelete
+		stmt.Column = ""
+		stmt.Description = fmt.Sprintf("DELETE FROM %s WHERE %s IS NULL", tableName, columnName)
+		return stmt, nil
+	case config.FixActionSetValue:
+		stmt.Value = strategy.Value
+		stmt.Description = fmt.Sprintf("UPDATE %s SET %s = '%v' WHERE %s IS NULL", tableName, columnName, strategy.Value, columnName)
+		return stmt, nil
+	case config.FixActionExpression:
+		stmt.Expression = strategy.Expression
+	case config.FixActionSchemaDefault:
+		if column.DefaultValue == nil || fmt.Sprint(column.DefaultValue) == "" {
+			return stmt, fmt.Errorf("column %s.%s has no default value in the schema", tableName, columnName)
+		}
+		stmt.Expression = db.dialect.GetDefaultValueExpression(fmt.Sprint(column.DefaultValue))
+	case config.FixActionZeroValue:
+		stmt.Expression = db.dialect.GetZeroValueExpression(column.DataType)
+		if stmt.Expression == "" {
+			return stmt, fmt.Errorf("no zero value for column %s.%s of type %s", tableName, columnName, column.DataType)
+		}
+	case config.FixActionFromColumn:
+		stmt.Expression = db.quoteIdentifier(strategy.Column)
+		// Copying a NULL would not fix anything
+		stmt.Where += fmt.Sprintf(" AND %s IS NOT NULL", db.quoteIdentifier(strategy.Column))
+	case config.FixActionLookup:
+		expression, condition := db.lookupExpression(tableName, strategy.Lookup)
+		stmt.Expression = expression
+		// Rows without a lookup match are left for the next validation to report
+		stmt.Where += " AND " + condition
+	default:
+		return stmt, fmt.Errorf("unknown action: %s", strategy.Action)
+	}
+
+	stmt.Description = fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL", tableName, columnName, stmt.Expression, columnName)
+	return stmt, nil
+}
+
+// lookupExpression returns the subquery reading a lookup value for a row of tableName and
+// the condition requiring that the lookup finds a row
+func (db *DB) lookupExpression(tableName string, lookup *config.LookupStrategy) (string, string) {
+	var conditions []string
+	if lookup.MatchColumn != "" {
+		conditions = append(conditions, fmt.Sprintf("lookup_table.%s = %s.%s",
+			db.quoteIdentifier(lookup.MatchColumn), db.quoteIdentifier(tableName), db.quoteIdentifier(lookup.SourceColumn)))
+	}
+	if lookup.Where != "" {
+		conditions = append(conditions, "("+lookup.Where+")")
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	return int(rowsAffected), err
+	from := fmt.Sprintf("FROM %s AS lookup_table%s", db.quoteIdentifier(lookup.Table), where)
+	expression := fmt.Sprintf("(SELECT lookup_table.%s %s LIMIT 1)", db.quoteIdentifier(lookup.Column), from)
+	condition := fmt.Sprintf("EXISTS (SELECT 1 %s)", from)
+	return expression, condition
 }
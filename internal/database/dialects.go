@@ -2,11 +2,22 @@ package database
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/nkamuo/go-db-migration/internal/config"
 	"github.com/nkamuo/go-db-migration/internal/models"
 )
 
+// rowFilterCondition returns an AND clause restricting a query to the rows
+// matched by a table's row filter, or an empty string when there is none
+func rowFilterCondition(rowFilter string) string {
+	if rowFilter == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n\t\t  AND (%s)", rowFilter)
+}
+
 // PostgreSQLDialect implements PostgreSQL-specific queries
 type PostgreSQLDialect struct{}
 
@@ -46,7 +57,8 @@ func (d *PostgreSQLDialect) GetColumnsQuery() string {
 			character_maximum_length,
 			numeric_precision,
 			numeric_scale,
-			datetime_precision
+			datetime_precision,
+			col_description((quote_ident(table_schema) || '.' || quote_ident(table_name))::regclass, ordinal_position::int)
 		FROM information_schema.columns 
 		WHERE table_schema = 'public' 
 		  AND table_name = $1
@@ -83,19 +95,22 @@ func (d *PostgreSQLDialect) GetColumnExistsQuery() string {
 		  AND column_name = $2`
 }
 
-func (d *PostgreSQLDialect) GetTableRowCountQuery(tableName string) string {
-	return fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, tableName)
+func (d *PostgreSQLDialect) GetTableRowCountQuery(tableName, rowFilter string) string {
+	if rowFilter == "" {
+		return fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, tableName)
+	}
+	return fmt.Sprintf(`SELECT COUNT(*) FROM "%s" WHERE (%s)`, tableName, rowFilter)
 }
 
-func (d *PostgreSQLDialect) GetNullViolationsQuery(tableName, columnName, identifierCol string) string {
+func (d *PostgreSQLDialect) GetNullViolationsQuery(tableName, columnName, identifierCol, rowFilter string, limit int) string {
 	return fmt.Sprintf(`
 		SELECT "%s"
 		FROM "%s"
-		WHERE "%s" IS NULL
-		LIMIT 1000`, identifierCol, tableName, columnName)
+		WHERE "%s" IS NULL%s
+		LIMIT %d`, identifierCol, tableName, columnName, rowFilterCondition(rowFilter), limit)
 }
 
-func (d *PostgreSQLDialect) GetForeignKeyViolationsQuery(fk models.ForeignKey, identifierCol string) string {
+func (d *PostgreSQLDialect) GetForeignKeyViolationsQuery(fk models.ForeignKey, identifierCol, rowFilter string) string {
 	return fmt.Sprintf(`
 		SELECT "%s", "%s"
 		FROM "%s" t1
@@ -103,10 +118,11 @@ func (d *PostgreSQLDialect) GetForeignKeyViolationsQuery(fk models.ForeignKey, i
 		  AND NOT EXISTS (
 			SELECT 1 FROM "%s" t2 
 			WHERE t2."%s" = t1."%s"
-		  )
+		  )%s
 		LIMIT 1000`,
 		fk.ColumnName, identifierCol, fk.TableName, fk.ColumnName,
-		fk.ReferencedTable, fk.ReferencedColumn, fk.ColumnName)
+		fk.ReferencedTable, fk.ReferencedColumn, fk.ColumnName,
+		rowFilterCondition(rowFilter))
 }
 
 // MySQLDialect implements MySQL-specific queries
@@ -144,7 +160,8 @@ func (d *MySQLDialect) GetColumnsQuery() string {
 			character_maximum_length,
 			numeric_precision,
 			numeric_scale,
-			datetime_precision
+			datetime_precision,
+			NULLIF(column_comment, '')
 		FROM information_schema.columns 
 		WHERE table_schema = DATABASE()
 		  AND table_name = ?
@@ -173,19 +190,22 @@ func (d *MySQLDialect) GetForeignKeysQuery() string {
 		  AND tc.table_name = ?`
 }
 
-func (d *MySQLDialect) GetTableRowCountQuery(tableName string) string {
-	return fmt.Sprintf("SELECT COUNT(*) FROM `%s`", tableName)
+func (d *MySQLDialect) GetTableRowCountQuery(tableName, rowFilter string) string {
+	if rowFilter == "" {
+		return fmt.Sprintf("SELECT COUNT(*) FROM `%s`", tableName)
+	}
+	return fmt.Sprintf("SELECT COUNT(*) FROM `%s` WHERE (%s)", tableName, rowFilter)
 }
 
-func (d *MySQLDialect) GetNullViolationsQuery(tableName, columnName, identifierCol string) string {
+func (d *MySQLDialect) GetNullViolationsQuery(tableName, columnName, identifierCol, rowFilter string, limit int) string {
 	return fmt.Sprintf(`
 		SELECT `+"`%s`"+`
 		FROM `+"`%s`"+`
-		WHERE `+"`%s`"+` IS NULL
-		LIMIT 1000`, identifierCol, tableName, columnName)
+		WHERE `+"`%s`"+` IS NULL%s
+		LIMIT %d`, identifierCol, tableName, columnName, rowFilterCondition(rowFilter), limit)
 }
 
-func (d *MySQLDialect) GetForeignKeyViolationsQuery(fk models.ForeignKey, identifierCol string) string {
+func (d *MySQLDialect) GetForeignKeyViolationsQuery(fk models.ForeignKey, identifierCol, rowFilter string) string {
 	return fmt.Sprintf(`
 		SELECT `+"`%s`, `%s`"+`
 		FROM `+"`%s`"+` t1
@@ -193,10 +213,11 @@ func (d *MySQLDialect) GetForeignKeyViolationsQuery(fk models.ForeignKey, identi
 		  AND NOT EXISTS (
 			SELECT 1 FROM `+"`%s`"+` t2 
 			WHERE t2.`+"`%s`"+` = t1.`+"`%s`"+`
-		  )
+		  )%s
 		LIMIT 1000`,
 		fk.ColumnName, identifierCol, fk.TableName, fk.ColumnName,
-		fk.ReferencedTable, fk.ReferencedColumn, fk.ColumnName)
+		fk.ReferencedTable, fk.ReferencedColumn, fk.ColumnName,
+		rowFilterCondition(rowFilter))
 }
 
 func (d *MySQLDialect) GetColumnExistsQuery() string {
@@ -207,3 +228,311 @@ func (d *MySQLDialect) GetColumnExistsQuery() string {
 		  AND table_name = ? 
 		  AND column_name = ?`
 }
+
+func (d *PostgreSQLDialect) GetPlaceholder(position int) string {
+	return fmt.Sprintf("$%d", position)
+}
+
+// GetPolymorphicViolationsQuery selects rows of the given type whose id has no match
+// in the target table. The type value is bound as the first query parameter.
+func (d *PostgreSQLDialect) GetPolymorphicViolationsQuery(tableName string, rel models.PolymorphicRelation, target models.PolymorphicTarget, identifierCol, rowFilter string, limit int) string {
+	return fmt.Sprintf(`
+		SELECT "%s", "%s"
+		FROM "%s" t1
+		WHERE t1."%s" = $1
+		  AND t1."%s" IS NOT NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM "%s" t2
+			WHERE t2."%s" = t1."%s"
+		  )%s
+		LIMIT %d`,
+		rel.IDColumn, identifierCol, tableName,
+		rel.TypeColumn, rel.IDColumn,
+		target.ReferencedTable, target.GetReferencedColumn(), rel.IDColumn,
+		rowFilterCondition(rowFilter), limit)
+}
+
+// GetPolymorphicUnknownTypesQuery counts rows per type value that is not mapped by the
+// relation. The mapped type values are bound as query parameters in order.
+func (d *PostgreSQLDialect) GetPolymorphicUnknownTypesQuery(tableName string, rel models.PolymorphicRelation, rowFilter string) string {
+	placeholders := make([]string, len(rel.Targets))
+	for i := range rel.Targets {
+		placeholders[i] = d.GetPlaceholder(i + 1)
+	}
+	notIn := ""
+	if len(placeholders) > 0 {
+		notIn = fmt.Sprintf("\n\t\t  AND \"%s\" NOT IN (%s)", rel.TypeColumn, strings.Join(placeholders, ", "))
+	}
+	return fmt.Sprintf(`
+		SELECT "%s", COUNT(*)
+		FROM "%s"
+		WHERE "%s" IS NOT NULL%s%s
+		GROUP BY "%s"`,
+		rel.TypeColumn, tableName, rel.TypeColumn, notIn,
+		rowFilterCondition(rowFilter), rel.TypeColumn)
+}
+
+func (d *MySQLDialect) GetPlaceholder(position int) string {
+	return "?"
+}
+
+// GetPolymorphicViolationsQuery selects rows of the given type whose id has no match
+// in the target table. The type value is bound as the first query parameter.
+func (d *MySQLDialect) GetPolymorphicViolationsQuery(tableName string, rel models.PolymorphicRelation, target models.PolymorphicTarget, identifierCol, rowFilter string, limit int) string {
+	return fmt.Sprintf(`
+		SELECT `+"`%s`, `%s`"+`
+		FROM `+"`%s`"+` t1
+		WHERE t1.`+"`%s`"+` = ?
+		  AND t1.`+"`%s`"+` IS NOT NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM `+"`%s`"+` t2
+			WHERE t2.`+"`%s`"+` = t1.`+"`%s`"+`
+		  )%s
+		LIMIT %d`,
+		rel.IDColumn, identifierCol, tableName,
+		rel.TypeColumn, rel.IDColumn,
+		target.ReferencedTable, target.GetReferencedColumn(), rel.IDColumn,
+		rowFilterCondition(rowFilter), limit)
+}
+
+// GetPolymorphicUnknownTypesQuery counts rows per type value that is not mapped by the
+// relation. The mapped type values are bound as query parameters in order.
+func (d *MySQLDialect) GetPolymorphicUnknownTypesQuery(tableName string, rel models.PolymorphicRelation, rowFilter string) string {
+	placeholders := make([]string, len(rel.Targets))
+	for i := range rel.Targets {
+		placeholders[i] = d.GetPlaceholder(i + 1)
+	}
+	notIn := ""
+	if len(placeholders) > 0 {
+		notIn = fmt.Sprintf("\n\t\t  AND `%s` NOT IN (%s)", rel.TypeColumn, strings.Join(placeholders, ", "))
+	}
+	return fmt.Sprintf(`
+		SELECT `+"`%s`"+`, COUNT(*)
+		FROM `+"`%s`"+`
+		WHERE `+"`%s`"+` IS NOT NULL%s%s
+		GROUP BY `+"`%s`",
+		rel.TypeColumn, tableName, rel.TypeColumn, notIn,
+		rowFilterCondition(rowFilter), rel.TypeColumn)
+}
+
+// GetForeignKeyMatchQuery counts the distinct non-null values of fk.ColumnName and how
+// many of them exist in the referenced column
+func (d *PostgreSQLDialect) GetForeignKeyMatchQuery(fk models.ForeignKey, rowFilter string) string {
+	return fmt.Sprintf(`
+		SELECT COUNT(*),
+		       COUNT(CASE WHEN EXISTS (
+		           SELECT 1 FROM "%s" p WHERE p."%s" = c.v
+		       ) THEN 1 END)
+		FROM (
+			SELECT DISTINCT "%s" AS v
+			FROM "%s"
+			WHERE "%s" IS NOT NULL%s
+		) c`,
+		fk.ReferencedTable, fk.ReferencedColumn,
+		fk.ColumnName, fk.TableName, fk.ColumnName,
+		rowFilterCondition(rowFilter))
+}
+
+// GetForeignKeyMatchQuery counts the distinct non-null values of fk.ColumnName and how
+// many of them exist in the referenced column
+func (d *MySQLDialect) GetForeignKeyMatchQuery(fk models.ForeignKey, rowFilter string) string {
+	return fmt.Sprintf(`
+		SELECT COUNT(*),
+		       COUNT(CASE WHEN EXISTS (
+		           SELECT 1 FROM `+"`%s`"+` p WHERE p.`+"`%s`"+` = c.v
+		       ) THEN 1 END)
+		FROM (
+			SELECT DISTINCT `+"`%s`"+` AS v
+			FROM `+"`%s`"+`
+			WHERE `+"`%s`"+` IS NOT NULL%s
+		) c`,
+		fk.ReferencedTable, fk.ReferencedColumn,
+		fk.ColumnName, fk.TableName, fk.ColumnName,
+		rowFilterCondition(rowFilter))
+}
+
+// GetPrimaryKeyQuery lists the primary key columns of a table in key order
+func (d *PostgreSQLDialect) GetPrimaryKeyQuery() string {
+	return `
+		SELECT kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+		  ON tc.constraint_name = kcu.constraint_name
+		 AND tc.table_schema = kcu.table_schema
+		 AND tc.table_name = kcu.table_name
+		WHERE tc.constraint_type = 'PRIMARY KEY'
+		  AND tc.table_schema = 'public'
+		  AND tc.table_name = $1
+		ORDER BY kcu.ordinal_position`
+}
+
+// GetTableCommentQuery returns the comment of a table, or NULL when it has none
+func (d *PostgreSQLDialect) GetTableCommentQuery() string {
+	return `SELECT obj_description(('public.' || quote_ident($1))::regclass, 'pg_class')`
+}
+
+// GetReplicationLagQuery returns the largest replay lag of the connected standbys in seconds
+func (d *PostgreSQLDialect) GetReplicationLagQuery() string {
+	return `
+		SELECT COALESCE(MAX(EXTRACT(EPOCH FROM replay_lag)), 0)
+		FROM pg_stat_replication`
+}
+
+// GetPrimaryKeyQuery lists the primary key columns of a table in key order
+func (d *MySQLDialect) GetPrimaryKeyQuery() string {
+	return `
+		SELECT column_name
+		FROM information_schema.key_column_usage
+		WHERE table_schema = DATABASE()
+		  AND table_name = ?
+		  AND constraint_name = 'PRIMARY'
+		ORDER BY ordinal_position`
+}
+
+// GetTableCommentQuery returns the comment of a table, or NULL when it has none
+func (d *MySQLDialect) GetTableCommentQuery() string {
+	return `
+		SELECT NULLIF(table_comment, '')
+		FROM information_schema.tables
+		WHERE table_schema = DATABASE()
+		  AND table_name = ?`
+}
+
+// GetReplicationLagQuery is not supported for MySQL: replica lag is only visible on the replicas
+func (d *MySQLDialect) GetReplicationLagQuery() string {
+	return ""
+}
+
+// GetZeroValueExpression returns the type-appropriate empty value for a column, or "" when
+// the type has none
+func (d *PostgreSQLDialect) GetZeroValueExpression(dataType string) string {
+	dataType = strings.ToLower(dataType)
+	switch {
+	case dataType == "array" || strings.HasPrefix(dataType, "_") || strings.HasSuffix(dataType, "[]"):
+		return "'{}'"
+	case strings.Contains(dataType, "char") || dataType == "text" || dataType == "citext" || dataType == "bytea":
+		return "''"
+	case strings.Contains(dataType, "int") || strings.Contains(dataType, "serial") || dataType == "numeric" ||
+		dataType == "decimal" || dataType == "real" || dataType == "double precision" || dataType == "money":
+		return "0"
+	case dataType == "boolean" || dataType == "bool":
+		return "FALSE"
+	case strings.HasPrefix(dataType, "timestamp"):
+		return "'1970-01-01 00:00:00'"
+	case dataType == "date":
+		return "'1970-01-01'"
+	case strings.HasPrefix(dataType, "time"):
+		return "'00:00:00'"
+	case dataType == "interval":
+		return "'0 seconds'"
+	case dataType == "uuid":
+		return "'00000000-0000-0000-0000-000000000000'"
+	case dataType == "json" || dataType == "jsonb":
+		return "'{}'"
+	default:
+		return ""
+	}
+}
+
+// GetDefaultValueExpression returns a column default from information_schema as an SQL
+// expression; PostgreSQL already reports defaults as expressions
+func (d *PostgreSQLDialect) GetDefaultValueExpression(defaultValue string) string {
+	return defaultValue
+}
+
+// GetZeroValueExpression returns the type-appropriate empty value for a column, or "" when
+// the type has none
+func (d *MySQLDialect) GetZeroValueExpression(dataType string) string {
+	dataType = strings.ToLower(dataType)
+	switch {
+	case strings.Contains(dataType, "char") || strings.Contains(dataType, "text") ||
+		strings.Contains(dataType, "binary") || strings.Contains(dataType, "blob"):
+		return "''"
+	case strings.Contains(dataType, "int") || dataType == "decimal" || dataType == "numeric" ||
+		dataType == "float" || dataType == "double" || dataType == "bit":
+		return "0"
+	case dataType == "boolean" || dataType == "bool":
+		return "FALSE"
+	case dataType == "datetime" || dataType == "timestamp":
+		return "'1970-01-01 00:00:01'"
+	case dataType == "date":
+		return "'1970-01-01'"
+	case dataType == "time":
+		return "'00:00:00'"
+	case dataType == "year":
+		return "1970"
+	case dataType == "json":
+		return "'{}'"
+	default:
+		return ""
+	}
+}
+
+// GetDefaultValueExpression returns a column default from information_schema as an SQL
+// expression. MySQL reports literal defaults unquoted, so anything that is not a number or
+// a function call is quoted.
+func (d *MySQLDialect) GetDefaultValueExpression(defaultValue string) string {
+	if _, err := strconv.ParseFloat(defaultValue, 64); err == nil {
+		return defaultValue
+	}
+	upper := strings.ToUpper(defaultValue)
+	if strings.HasPrefix(upper, "CURRENT_") || strings.HasSuffix(defaultValue, ")") {
+		return defaultValue
+	}
+	return "'" + strings.ReplaceAll(defaultValue, "'", "''") + "'"
+}
+
+// GetColumnDefinitionType returns the type used to re-create an introspected column, e.g. in
+// a quarantine table. Enums and arrays are stored as text.
+func (d *PostgreSQLDialect) GetColumnDefinitionType(column models.Column) string {
+	switch column.DataType {
+	case "USER-DEFINED", "ARRAY":
+		return "text"
+	}
+	return column.GetFullDataType()
+}
+
+// GetColumnDefinitionType returns the type used to re-create an introspected column, e.g. in
+// a quarantine table. Enums and sets are stored as text.
+func (d *MySQLDialect) GetColumnDefinitionType(column models.Column) string {
+	switch strings.ToLower(column.DataType) {
+	case "enum", "set":
+		return "text"
+	}
+	return column.GetFullDataType()
+}
+
+// GetBeginTransactionStatement returns the statement opening a transaction in a SQL script
+func (d *PostgreSQLDialect) GetBeginTransactionStatement() string {
+	return "BEGIN"
+}
+
+// GetRowCountAssertion wraps a statement in a DO block that raises an error unless it
+// changes exactly the expected number of rows
+func (d *PostgreSQLDialect) GetRowCountAssertion(statement string, expected int64) string {
+	return fmt.Sprintf(`DO $fix$
+DECLARE
+	affected bigint;
+BEGIN
+	%s;
+	GET DIAGNOSTICS affected = ROW_COUNT;
+	IF affected <> %d THEN
+		RAISE EXCEPTION 'row count assertion failed: expected %d rows, got %%', affected;
+	END IF;
+END
+$fix$;`, strings.ReplaceAll(statement, "\n", "\n\t"), expected, expected)
+}
+
+// GetBeginTransactionStatement returns the statement opening a transaction in a SQL script
+func (d *MySQLDialect) GetBeginTransactionStatement() string {
+	return "START TRANSACTION"
+}
+
+// GetRowCountAssertion follows a statement with a check of ROW_COUNT(). MySQL has no way to
+// raise an error outside stored programs, so a mismatch evaluates a subquery returning two
+// rows, which fails with "Subquery returns more than 1 row".
+func (d *MySQLDialect) GetRowCountAssertion(statement string, expected int64) string {
+	return fmt.Sprintf(`%s;
+DO (SELECT IF(ROW_COUNT() = %d, 1, (SELECT 'row count assertion failed' UNION ALL SELECT 'expected %d rows')));`,
+		statement, expected, expected)
+}
@@ -0,0 +1,231 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// ordersFK is a foreign key from orders.customer_id to customers.id
+var ordersFK = models.ForeignKey{
+	ConstraintName:   "fk_orders_customer",
+	TableName:        "orders",
+	ColumnName:       "customer_id",
+	ReferencedTable:  "customers",
+	ReferencedColumn: "id",
+}
+
+// existingParents answers parent lookups with every value asked for, so that reassignment
+// targets are found
+func existingParents(query string, args []driver.Value) ([]string, [][]driver.Value) {
+	var rows [][]driver.Value
+	for _, arg := range args {
+		rows = append(rows, []driver.Value{arg})
+	}
+	return []string{"id"}, rows
+}
+
+func TestReassignMappingBindsTargetFirst(t *testing.T) {
+	for _, dbType := range []DatabaseType{MySQL, PostgreSQL} {
+		t.Run(string(dbType), func(t *testing.T) {
+			rec := &recorder{query: existingParents}
+			db := newRecordingDB(t, dbType, rec)
+
+			stmts, err := db.reassignStatements(ordersFK, ForeignKeyFix{
+				Action:       "reassign",
+				ValueMapping: map[string]string{"7": "1", "9": "1"},
+			}, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			run, err := db.newFixRun(FixOptions{NoBackup: true}, "fix fk", "reassign", models.FixResults{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := run.execAll(stmts); err != nil {
+				t.Fatal(err)
+			}
+			if err := run.finish(); err != nil {
+				t.Fatal(err)
+			}
+
+			updates := rec.updates()
+			if len(updates) != 1 {
+				t.Fatalf("got %d updates, want 1", len(updates))
+			}
+			want := []driver.Value{"1", "7", "9"}
+			if !reflect.DeepEqual(updates[0].Args, want) {
+				t.Errorf("bound args = %v, want %v", updates[0].Args, want)
+			}
+			if dbType == PostgreSQL && !strings.Contains(updates[0].Query, `"customer_id" IN ($2, $3)`) {
+				t.Errorf("predicate placeholders not renumbered:\n%s", updates[0].Query)
+			}
+		})
+	}
+}
+
+// batchedTable answers the queries of a batched fix on a table keyed by id with two
+// batches, bounded by ids 10 and 20
+func batchedTable(query string, args []driver.Value) ([]string, [][]driver.Value) {
+	switch {
+	case strings.Contains(query, "information_schema"):
+		return []string{"column_name"}, [][]driver.Value{{"id"}}
+	case strings.Contains(query, "COUNT(*)"):
+		return []string{"count"}, [][]driver.Value{{int64(4)}}
+	case strings.Contains(query, "MAX(batch_keys.k)"):
+		bound := map[int]driver.Value{0: int64(10), 1: int64(20)}[len(args)]
+		if len(args) == 1 && args[0] != int64(10) {
+			bound = nil
+		}
+		return []string{"max"}, [][]driver.Value{{bound}}
+	}
+	return nil, nil
+}
+
+func TestBatchedUpdateBindsValueFirst(t *testing.T) {
+	rec := &recorder{query: batchedTable, rowsAffected: func(string, []driver.Value) int64 { return 2 }}
+	db := newRecordingDB(t, MySQL, rec)
+
+	run, err := db.newFixRun(FixOptions{NoBackup: true, BatchSize: 2}, "fix fk", "reassign", models.FixResults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := fixStatement{
+		Table:     "orders",
+		Operation: fixOperationUpdate,
+		Column:    "customer_id",
+		Value:     "1",
+		Where:     db.foreignKeyViolationCondition(ordersFK, ""),
+	}
+	if _, err := run.exec(stmt); err != nil {
+		t.Fatal(err)
+	}
+
+	updates := rec.updates()
+	want := [][]driver.Value{{"1", int64(10)}, {"1", int64(10), int64(20)}}
+	if len(updates) != len(want) {
+		t.Fatalf("got %d batches, want %d", len(updates), len(want))
+	}
+	for i, update := range updates {
+		if !reflect.DeepEqual(update.Args, want[i]) {
+			t.Errorf("batch %d bound args = %v, want %v", i+1, update.Args, want[i])
+		}
+	}
+}
+
+func TestApplyFixPlanBindsValueFirst(t *testing.T) {
+	rec := &recorder{query: existingParents, rowsAffected: func(string, []driver.Value) int64 { return 2 }}
+	db := newRecordingDB(t, MySQL, rec)
+
+	stmts, err := db.reassignStatements(ordersFK, ForeignKeyFix{
+		Action:       "reassign",
+		ValueMapping: map[string]string{"7": "1", "9": "1"},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := stmts[0]
+	plan := &models.FixRunPlan{
+		RunID:        "plan-1",
+		DatabaseType: string(MySQL),
+		Command:      "fix fk",
+		Action:       "reassign",
+		Operations: []models.PlannedOperation{{
+			Table:       stmt.Table,
+			Description: stmt.Description,
+			Operation:   stmt.Operation,
+			KeyColumn:   "id",
+			Column:      stmt.Column,
+			Value:       stmt.Value,
+			Where:       stmt.Where,
+			Args:        stmt.Args,
+			RowCount:    2,
+			Keys:        []string{"3", "5"},
+			Exact:       true,
+		}},
+		Results: models.FixResults{"orders": {IssuesFound: 2}},
+	}
+
+	results, err := db.ApplyFixPlan(plan, FixOptions{NoBackup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !results["orders"].Success {
+		t.Fatalf("apply failed: %s", results["orders"].Error)
+	}
+
+	updates := rec.updates()
+	if len(updates) != 1 {
+		t.Fatalf("got %d updates, want 1", len(updates))
+	}
+	want := []driver.Value{"1", "7", "9"}
+	if !reflect.DeepEqual(updates[0].Args, want) {
+		t.Errorf("bound args = %v, want %v", updates[0].Args, want)
+	}
+	if !strings.Contains(updates[0].Query, "`id` IN ('3', '5')") {
+		t.Errorf("update is not restricted to the planned keys:\n%s", updates[0].Query)
+	}
+}
+
+func TestFailedBatchKeepsCommittedBatches(t *testing.T) {
+	tests := []struct {
+		name           string
+		failingBatch   int
+		wantRolledBack bool
+		wantRecords    int
+	}{
+		{name: "first batch fails", failingBatch: 1, wantRolledBack: true, wantRecords: 0},
+		{name: "second batch fails", failingBatch: 2, wantRolledBack: false, wantRecords: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{query: batchedTable, rowsAffected: func(string, []driver.Value) int64 { return 2 }}
+			batch := 0
+			rec.fail = func(query string, args []driver.Value) error {
+				if !strings.HasPrefix(strings.TrimSpace(query), "UPDATE") {
+					return nil
+				}
+				if batch++; batch == tt.failingBatch {
+					return errors.New("constraint violated")
+				}
+				return nil
+			}
+			db := newRecordingDB(t, MySQL, rec)
+
+			results := models.FixResults{"orders": {IssuesFound: 4}}
+			run, err := db.newFixRun(FixOptions{NoBackup: true, BatchSize: 2}, "fix fk", "reassign", results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			recordsAffected, err := run.exec(fixStatement{
+				Table:     "orders",
+				Operation: fixOperationUpdate,
+				Column:    "customer_id",
+				Value:     "1",
+				Where:     db.foreignKeyViolationCondition(ordersFK, ""),
+			})
+			if err == nil {
+				t.Fatal("expected the batch to fail")
+			}
+			result := results["orders"]
+			result.RecordsAffected += recordsAffected
+			results["orders"] = result
+			run.failFix("orders", err)
+
+			result = results["orders"]
+			if result.Success {
+				t.Error("failed fix reported as successful")
+			}
+			if result.RolledBack != tt.wantRolledBack {
+				t.Errorf("RolledBack = %v, want %v", result.RolledBack, tt.wantRolledBack)
+			}
+			if result.RecordsAffected != tt.wantRecords {
+				t.Errorf("RecordsAffected = %d, want %d", result.RecordsAffected, tt.wantRecords)
+			}
+		})
+	}
+}
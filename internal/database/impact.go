@@ -0,0 +1,288 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// maxCascadeDepth limits how many foreign keys deep the impact analysis follows cascades
+const maxCascadeDepth = 8
+
+// Kinds of rows counted by the impact analysis
+const (
+	impactDeleted = "deleted"
+	impactNulled  = "nulled"
+	impactUpdated = "updated"
+	impactBlocked = "blocked"
+)
+
+// cascadeGraph indexes the foreign keys of the live database by the table they reference
+type cascadeGraph map[string][]models.ForeignKey
+
+// loadCascadeGraph introspects the foreign keys of every table, with their ON DELETE and
+// ON UPDATE rules
+func (db *DB) loadCascadeGraph() (cascadeGraph, error) {
+	tables, err := db.getTables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	graph := make(cascadeGraph)
+	for _, tableName := range tables {
+		foreignKeys, err := db.getTableForeignKeys(tableName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get foreign keys for table %s: %w", tableName, err)
+		}
+		for _, fk := range foreignKeys {
+			graph[fk.ReferencedTable] = append(graph[fk.ReferencedTable], fk)
+		}
+	}
+	return graph, nil
+}
+
+// cascadeAction is one referential action reached by an impact walk: the rows of Table
+// matching Condition are affected as Kind, through a foreign key on Column
+type cascadeAction struct {
+	Table     string
+	Kind      string
+	Column    string
+	Condition string
+	// Depth is the number of foreign keys between the fixed table and Table
+	Depth int
+}
+
+// impactWalk collects, per table and kind, the predicates selecting the rows a fix affects
+// through referential actions
+type impactWalk struct {
+	db         *DB
+	graph      cascadeGraph
+	conditions map[string]map[string][]string
+	blockedBy  map[string][]string
+	actions    []cascadeAction
+}
+
+// newImpactWalk creates an empty impact walk over graph
+func (db *DB) newImpactWalk(graph cascadeGraph) *impactWalk {
+	return &impactWalk{
+		db:         db,
+		graph:      graph,
+		conditions: make(map[string]map[string][]string),
+		blockedBy:  make(map[string][]string),
+	}
+}
+
+// add records that the rows of tableName matching condition are affected as kind, through
+// a foreign key on column
+func (w *impactWalk) add(tableName, kind, column, condition string, depth int) {
+	if w.conditions[tableName] == nil {
+		w.conditions[tableName] = make(map[string][]string)
+	}
+	w.conditions[tableName][kind] = append(w.conditions[tableName][kind], condition)
+	w.actions = append(w.actions, cascadeAction{Table: tableName, Kind: kind, Column: column, Condition: condition, Depth: depth + 1})
+}
+
+// visit follows the foreign keys referencing the rows of tableName matching condition.
+// With column empty the rows are deleted; otherwise column is updated.
+func (w *impactWalk) visit(tableName, condition, column string, path map[string]bool, depth int) {
+	if depth >= maxCascadeDepth {
+		return
+	}
+
+	for _, fk := range w.graph[tableName] {
+		if column != "" && fk.ReferencedColumn != column {
+			continue
+		}
+		// Each foreign key is followed once per path, which ends self-referencing cascades
+		key := fk.TableName + "." + fk.ConstraintName
+		if path[key] {
+			continue
+		}
+
+		childCondition := fmt.Sprintf("%s.%s IN (SELECT %s.%s FROM %s WHERE %s)",
+			w.db.quoteIdentifier(fk.TableName), w.db.quoteIdentifier(fk.ColumnName),
+			w.db.quoteIdentifier(tableName), w.db.quoteIdentifier(fk.ReferencedColumn),
+			w.db.quoteIdentifier(tableName), condition)
+
+		rule := fk.DeleteRule
+		if column != "" {
+			rule = fk.UpdateRule
+		}
+
+		childPath := make(map[string]bool, len(path)+1)
+		for k := range path {
+			childPath[k] = true
+		}
+		childPath[key] = true
+
+		switch strings.ToUpper(rule) {
+		case "CASCADE":
+			if column == "" {
+				w.add(fk.TableName, impactDeleted, "", childCondition, depth)
+				w.visit(fk.TableName, childCondition, "", childPath, depth+1)
+			} else {
+				w.add(fk.TableName, impactUpdated, fk.ColumnName, childCondition, depth)
+				w.visit(fk.TableName, childCondition, fk.ColumnName, childPath, depth+1)
+			}
+		case "SET NULL":
+			w.add(fk.TableName, impactNulled, fk.ColumnName, childCondition, depth)
+		case "SET DEFAULT":
+			w.add(fk.TableName, impactUpdated, fk.ColumnName, childCondition, depth)
+		default:
+			// RESTRICT and NO ACTION
+			w.add(fk.TableName, impactBlocked, fk.ColumnName, childCondition, depth)
+			w.blockedBy[fk.TableName] = append(w.blockedBy[fk.TableName], fk.ConstraintName)
+		}
+	}
+}
+
+// fixImpact counts the rows of other tables that the referential actions of the given
+// statements would delete, null, update or be blocked by. Counts are exact; a row reached
+// through several foreign keys of one statement is counted once, and rows the statements
+// delete themselves are not counted.
+func (db *DB) fixImpact(graph cascadeGraph, stmts []fixStatement) ([]models.CascadeImpact, error) {
+	// Rows deleted by the fix itself are neither cascaded to nor blocking it, e.g. the
+	// children of an orphan that are orphans as well
+	exclude := make(map[string]string)
+	for _, stmt := range stmts {
+		if stmt.Operation == fixOperationDelete {
+			// CASE keeps rows whose predicate is NULL, which NOT (...) would drop
+			exclude[stmt.Table] += fmt.Sprintf(" AND CASE WHEN %s THEN 1 ELSE 0 END = 0", db.inlineArgs(stmt.Where, stmt.Args))
+		}
+	}
+
+	totals := make(map[string]*models.CascadeImpact)
+	for _, stmt := range stmts {
+		var column string
+		switch stmt.Operation {
+		case fixOperationDelete:
+		case fixOperationUpdate:
+			column = stmt.Column
+		default:
+			continue
+		}
+
+		walk := db.newImpactWalk(graph)
+		walk.visit(stmt.Table, db.inlineArgs(stmt.Where, stmt.Args), column, map[string]bool{}, 0)
+
+		for tableName, kinds := range walk.conditions {
+			impact := totals[tableName]
+			if impact == nil {
+				impact = &models.CascadeImpact{Table: tableName}
+				totals[tableName] = impact
+			}
+			for kind, conditions := range kinds {
+				query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE ((%s))%s",
+					db.quoteIdentifier(tableName), strings.Join(conditions, ") OR ("), exclude[tableName])
+				var count int64
+				if err := db.conn.QueryRow(query).Scan(&count); err != nil {
+					return nil, fmt.Errorf("failed to count %s rows in %s: %w", kind, tableName, err)
+				}
+				switch kind {
+				case impactDeleted:
+					impact.Deleted += count
+				case impactNulled:
+					impact.Nulled += count
+				case impactUpdated:
+					impact.Updated += count
+				case impactBlocked:
+					impact.Blocked += count
+					if count > 0 {
+						impact.BlockedBy = appendUnique(impact.BlockedBy, walk.blockedBy[tableName]...)
+					}
+				}
+			}
+		}
+	}
+
+	var impacts []models.CascadeImpact
+	for _, impact := range totals {
+		if impact.Deleted+impact.Nulled+impact.Updated+impact.Blocked > 0 {
+			impacts = append(impacts, *impact)
+		}
+	}
+	sort.Slice(impacts, func(i, j int) bool { return impacts[i].Table < impacts[j].Table })
+	return impacts, nil
+}
+
+// mergeCascadeImpact adds the counts of more to impacts, table by table
+func mergeCascadeImpact(impacts, more []models.CascadeImpact) []models.CascadeImpact {
+	for _, impact := range more {
+		merged := false
+		for i := range impacts {
+			if impacts[i].Table == impact.Table {
+				impacts[i].Deleted += impact.Deleted
+				impacts[i].Nulled += impact.Nulled
+				impacts[i].Updated += impact.Updated
+				impacts[i].Blocked += impact.Blocked
+				impacts[i].BlockedBy = appendUnique(impacts[i].BlockedBy, impact.BlockedBy...)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			impacts = append(impacts, impact)
+		}
+	}
+	sort.Slice(impacts, func(i, j int) bool { return impacts[i].Table < impacts[j].Table })
+	return impacts
+}
+
+// dryRunCounts returns the exact number of rows the statements would change and their
+// impact on other tables
+func (db *DB) dryRunCounts(graph cascadeGraph, stmts []fixStatement) (int, []models.CascadeImpact, error) {
+	count, err := db.countFixStatements(stmts)
+	if err != nil {
+		return 0, nil, err
+	}
+	impact, err := db.fixImpact(graph, stmts)
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, impact, nil
+}
+
+// countFixStatements returns the exact number of rows the counted statements would change
+func (db *DB) countFixStatements(stmts []fixStatement) (int, error) {
+	total := 0
+	for _, stmt := range stmts {
+		if stmt.Uncounted || stmt.Operation == fixOperationCreate {
+			continue
+		}
+		count, err := db.countStatementRows(stmt)
+		if err != nil {
+			return total, fmt.Errorf("failed to count rows for %s: %w", stmt.Description, err)
+		}
+		total += int(count)
+	}
+	return total, nil
+}
+
+// countViolatingRows returns the exact number of rows of tableName matching a violation
+// predicate, which the sample read by validation caps
+func (db *DB) countViolatingRows(tableName, condition string) (int, error) {
+	count, err := db.countFixRows(fixStatement{Table: tableName, Where: condition})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count violations in %s: %w", tableName, err)
+	}
+	return int(count), nil
+}
+
+// appendUnique appends the values not yet in list
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
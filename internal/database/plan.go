@@ -0,0 +1,375 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// FixPlanVersion is the version of the fix plan file format
+const FixPlanVersion = 1
+
+// FixPlanRecorder collects fix statements, with the keys of the rows they would change,
+// into a fix plan instead of executing them
+type FixPlanRecorder struct {
+	db         *DB
+	operations []models.PlannedOperation
+	// modified records the tables changed by earlier operations of the plan
+	modified map[string]bool
+	// created records the tables created by the plan
+	created map[string]bool
+}
+
+// NewFixPlanRecorder creates an empty fix plan recorder
+func (db *DB) NewFixPlanRecorder() *FixPlanRecorder {
+	return &FixPlanRecorder{db: db, modified: make(map[string]bool), created: make(map[string]bool)}
+}
+
+// Operations returns the recorded operations in execution order
+func (p *FixPlanRecorder) Operations() []models.PlannedOperation {
+	return p.operations
+}
+
+// add records a fix statement and returns the number of rows it would change, read from the
+// current data. Nothing is modified.
+func (p *FixPlanRecorder) add(stmt fixStatement) (int, error) {
+	op := models.PlannedOperation{
+		Table:           stmt.Table,
+		ResultTable:     stmt.ResultTable,
+		Description:     stmt.Description,
+		Operation:       stmt.Operation,
+		Columns:         stmt.Columns,
+		Source:          stmt.Source,
+		Column:          stmt.Column,
+		Value:           stmt.Value,
+		Expression:      stmt.Expression,
+		Where:           stmt.Where,
+		Args:            stmt.Args,
+		Uncounted:       stmt.Uncounted,
+		SingleStatement: stmt.SingleStatement,
+	}
+
+	if stmt.Operation == fixOperationCreate {
+		// Fixes for several constraints of one table each ask for its quarantine table
+		if !p.created[stmt.Table] {
+			p.created[stmt.Table] = true
+			p.operations = append(p.operations, op)
+		}
+		return 0, nil
+	}
+
+	keyColumn := p.db.statementKeyColumn(stmt)
+	count, keys, err := p.db.statementRows(stmt, keyColumn)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rows for %s: %w", stmt.Description, err)
+	}
+	op.KeyColumn = keyColumn
+	op.RowCount = count
+	op.Keys = keys
+	op.Exact = !p.db.readsAnyTable(stmt, p.modified)
+
+	p.operations = append(p.operations, op)
+	p.modified[stmt.Table] = true
+	return int(count), nil
+}
+
+// statementKeyColumn returns the column identifying the rows a statement changes, or ""
+// when the table has no usable one
+func (db *DB) statementKeyColumn(stmt fixStatement) string {
+	keyColumn := stmt.KeyColumn
+	if keyColumn == "" {
+		keyColumn = db.getIdentifierColumn(stmt.Table)
+	}
+	if keyColumn == "1" {
+		return ""
+	}
+	return keyColumn
+}
+
+// statementRows returns the number of rows a statement would change and, when keyColumn is
+// given, their sorted keys. Inserts report the keys of the rows they would insert.
+func (db *DB) statementRows(stmt fixStatement, keyColumn string) (int64, []string, error) {
+	if keyColumn == "" {
+		count, err := db.countStatementRows(stmt)
+		return count, nil, err
+	}
+
+	var query string
+	if stmt.Operation == fixOperationInsert {
+		query = fmt.Sprintf("SELECT planned_rows.%s FROM (%s) planned_rows",
+			db.quoteIdentifier(keyColumn), db.inlineArgs(stmt.Source, stmt.Args))
+	} else {
+		query = fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+			db.quoteIdentifier(keyColumn), db.quoteIdentifier(stmt.Table), db.inlineArgs(stmt.Where, stmt.Args))
+	}
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key interface{}
+		if err := rows.Scan(&key); err != nil {
+			return 0, nil, err
+		}
+		if b, ok := key.([]byte); ok {
+			key = string(b)
+		}
+		keys = append(keys, fmt.Sprint(key))
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+	sort.Strings(keys)
+	return int64(len(keys)), keys, nil
+}
+
+// plannedStatement rebuilds the fix statement of a planned operation. Deletes and updates
+// are restricted to the planned keys, so rows that started violating after the plan was
+// made are left alone.
+func (db *DB) plannedStatement(op models.PlannedOperation) fixStatement {
+	stmt := fixStatement{
+		Table:           op.Table,
+		ResultTable:     op.ResultTable,
+		Description:     op.Description,
+		Operation:       op.Operation,
+		KeyColumn:       op.KeyColumn,
+		Columns:         op.Columns,
+		Source:          op.Source,
+		Column:          op.Column,
+		Value:           op.Value,
+		Expression:      op.Expression,
+		Where:           op.Where,
+		Args:            op.Args,
+		Uncounted:       op.Uncounted,
+		SingleStatement: op.SingleStatement,
+	}
+
+	if op.KeyColumn != "" && (op.Operation == fixOperationDelete || op.Operation == fixOperationUpdate) {
+		if len(op.Keys) == 0 {
+			stmt.Where = fmt.Sprintf("(%s) AND 1 = 0", op.Where)
+		} else {
+			literals := make([]string, len(op.Keys))
+			for i, key := range op.Keys {
+				literals[i] = db.quoteString(key)
+			}
+			stmt.Where = fmt.Sprintf("(%s) AND %s IN (%s)", op.Where, db.quoteIdentifier(op.KeyColumn), strings.Join(literals, ", "))
+		}
+	}
+	return stmt
+}
+
+// retagQuarantine tags the rows a planned quarantine copy moves with the ID of the run
+// applying the plan instead of the plan's, so that they match its manifest and audit record
+func retagQuarantine(stmt fixStatement, planID, runID string) fixStatement {
+	if !isQuarantineCopy(stmt) {
+		return stmt
+	}
+	args := make([]interface{}, len(stmt.Args))
+	for i, arg := range stmt.Args {
+		if arg == planID {
+			arg = runID
+		}
+		args[i] = arg
+	}
+	stmt.Args = args
+	return stmt
+}
+
+// CheckFixPlanDrift compares the rows every planned operation would change now with the
+// rows recorded in the plan and describes each difference. No differences means the plan
+// can be applied as reviewed.
+func (db *DB) CheckFixPlanDrift(plan *models.FixRunPlan) ([]string, error) {
+	var drift []string
+	if plan.DatabaseType != string(db.dbType) {
+		return []string{fmt.Sprintf("the plan was made for %s, not %s", plan.DatabaseType, db.dbType)}, nil
+	}
+
+	for i, op := range plan.Operations {
+		label := fmt.Sprintf("[%d] %s", i+1, op.Description)
+		if op.Operation == fixOperationCreate {
+			columns, err := db.getTableColumns(op.Table)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check table %s: %w", op.Table, err)
+			}
+			if len(columns) > 0 {
+				drift = append(drift, fmt.Sprintf("%s: %s has been created since the plan was made", label, op.Table))
+			}
+			continue
+		}
+
+		stmt := db.plannedStatement(op)
+		stmt.Where = op.Where
+		count, keys, err := db.statementRows(stmt, op.KeyColumn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rows for %s: %w", op.Description, err)
+		}
+
+		if op.KeyColumn != "" {
+			missing, added := diffSortedKeys(op.Keys, keys)
+			if len(missing) > 0 {
+				drift = append(drift, fmt.Sprintf("%s: %d planned rows no longer match (%s: %s)", label, len(missing), op.KeyColumn, sampleKeys(missing)))
+			}
+			if len(added) > 0 {
+				drift = append(drift, fmt.Sprintf("%s: %d rows match that are not in the plan (%s: %s)", label, len(added), op.KeyColumn, sampleKeys(added)))
+			}
+		} else if count != op.RowCount {
+			drift = append(drift, fmt.Sprintf("%s: %d rows planned, %d now", label, op.RowCount, count))
+		}
+	}
+	return drift, nil
+}
+
+// diffSortedKeys returns the keys only in planned and the keys only in current
+func diffSortedKeys(planned, current []string) (missing, added []string) {
+	i, j := 0, 0
+	for i < len(planned) || j < len(current) {
+		switch {
+		case j >= len(current) || (i < len(planned) && planned[i] < current[j]):
+			missing = append(missing, planned[i])
+			i++
+		case i >= len(planned) || current[j] < planned[i]:
+			added = append(added, current[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return missing, added
+}
+
+// sampleKeys lists the first few keys
+func sampleKeys(keys []string) string {
+	const max = 5
+	if len(keys) <= max {
+		return strings.Join(keys, ", ")
+	}
+	return strings.Join(keys[:max], ", ") + fmt.Sprintf(", ... %d more", len(keys)-max)
+}
+
+// ApplyFixPlan executes exactly the operations of a plan. Deletes and updates only touch the
+// planned rows, and an operation changing a different number of rows than planned fails
+// its table, which is rolled back according to opts.CommitPolicy. Check the plan for drift
+// first with CheckFixPlanDrift.
+func (db *DB) ApplyFixPlan(plan *models.FixRunPlan, opts FixOptions) (models.FixResults, error) {
+	results := make(models.FixResults)
+	for tableName, planned := range plan.Results {
+		results[tableName] = models.FixResult{IssuesFound: planned.IssuesFound}
+	}
+
+	run, err := db.newFixRun(opts, plan.Command, plan.Action, results)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tables are created before any transaction starts: MySQL commits implicitly on DDL
+	for _, op := range plan.Operations {
+		if op.Operation != fixOperationCreate {
+			continue
+		}
+		_, err := db.conn.Exec(op.Source)
+		opts.audit(db.auditStatement(db.plannedStatement(op), 0, err))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create table %s: %w", op.Table, err)
+		}
+	}
+
+	currentTable := ""
+	failed := make(map[string]bool)
+	for _, op := range plan.Operations {
+		if op.Operation == fixOperationCreate {
+			continue
+		}
+
+		stmt := retagQuarantine(db.plannedStatement(op), plan.RunID, run.opts.RunID)
+		tableName := stmt.resultTable()
+		if currentTable != "" && tableName != currentTable {
+			if err := run.endTable(); err != nil {
+				return results, err
+			}
+		}
+		currentTable = tableName
+		if failed[tableName] {
+			// The table's changes were rolled back; leave the rest of it untouched
+			continue
+		}
+
+		result := results[tableName]
+		recordsAffected, err := run.exec(stmt)
+		if err == nil {
+			err = checkPlannedRowCount(op, recordsAffected)
+		}
+		if !op.Uncounted {
+			result.RecordsAffected += recordsAffected
+		}
+		if err != nil {
+			results[tableName] = result
+			if run.failFix(tableName, err) {
+				return results, nil
+			}
+			failed[tableName] = true
+			continue
+		}
+
+		result.Success = true
+		results[tableName] = result
+	}
+
+	if currentTable != "" {
+		if err := run.endTable(); err != nil {
+			return results, err
+		}
+	}
+	if err := run.finish(); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// checkPlannedRowCount fails an operation that changed more rows than planned, or fewer
+// when no earlier operation of the plan explains the difference
+func checkPlannedRowCount(op models.PlannedOperation, recordsAffected int) error {
+	affected := int64(recordsAffected)
+	if affected == op.RowCount || (!op.Exact && affected < op.RowCount) {
+		return nil
+	}
+	return fmt.Errorf("%s changed %d rows but the plan has %d", op.Description, affected, op.RowCount)
+}
+
+// SaveFixRunPlan writes a fix plan as JSON
+func SaveFixRunPlan(filePath string, plan *models.FixRunPlan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fix plan: %w", err)
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
+
+// LoadFixRunPlan reads a fix plan written by SaveFixRunPlan
+func LoadFixRunPlan(filePath string) (*models.FixRunPlan, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fix plan: %w", err)
+	}
+	defer file.Close()
+
+	var plan models.FixRunPlan
+	decoder := json.NewDecoder(file)
+	// Keep large integer keys and values exact
+	decoder.UseNumber()
+	if err := decoder.Decode(&plan); err != nil {
+		return nil, fmt.Errorf("failed to parse fix plan: %w", err)
+	}
+	if plan.Version != FixPlanVersion {
+		return nil, fmt.Errorf("unsupported fix plan version %d (expected %d)", plan.Version, FixPlanVersion)
+	}
+	return &plan, nil
+}
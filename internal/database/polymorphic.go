@@ -0,0 +1,269 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/nkamuo/go-db-migration/internal/config"
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// ValidatePolymorphicRelations checks the polymorphic relations declared in the target
+// schema for rows whose id does not exist in the table selected by their type value
+func (db *DB) ValidatePolymorphicRelations(targetSchema models.Schema, validationConfig *config.ValidationConfig) ([]models.ValidationIssue, error) {
+	var issues []models.ValidationIssue
+	if err := db.StreamPolymorphicViolations(targetSchema, validationConfig, collectIssues(&issues)); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// StreamPolymorphicViolations checks the polymorphic relations declared in the target
+// schema, passing every issue to handle as soon as it is found
+func (db *DB) StreamPolymorphicViolations(targetSchema models.Schema, validationConfig *config.ValidationConfig, handle IssueHandler) error {
+	limit := 1000
+	if validationConfig != nil && validationConfig.MaxIssuesPerTable > 0 {
+		limit = validationConfig.MaxIssuesPerTable
+	}
+
+	for _, table := range targetSchema {
+		for _, rel := range table.PolymorphicRelations {
+			handled := handlerErrors(handle)
+			err := db.streamPolymorphicViolations(table, rel, limit, validationConfig, handled.handle)
+			if handled.err != nil {
+				return handled.err
+			}
+			if err != nil {
+				if validationConfig != nil && validationConfig.StopOnFirstError {
+					return fmt.Errorf("failed to validate polymorphic relation '%s' on table %s: %w", rel.RelationName, table.TableName, err)
+				}
+				err = handle(models.ValidationIssue{
+					Type:     "polymorphic_validation_error",
+					Severity: "error",
+					Table:    table.TableName,
+					Column:   rel.IDColumn,
+					Message:  fmt.Sprintf("Failed to validate polymorphic relation '%s': %v", rel.RelationName, err),
+					Details: map[string]interface{}{
+						"relation_name": rel.RelationName,
+						"type_column":   rel.TypeColumn,
+						"error_type":    "validation_error",
+					},
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// streamPolymorphicViolations validates a single polymorphic relation, passing every issue
+// to handle as it is read
+func (db *DB) streamPolymorphicViolations(table models.Table, rel models.PolymorphicRelation, limit int, validationConfig *config.ValidationConfig, handle IssueHandler) error {
+	ignoreMissingTables := validationConfig != nil && validationConfig.IgnoreMissingTables
+	ignoreMissingColumns := validationConfig != nil && validationConfig.IgnoreMissingColumns
+
+	relationDetails := func() map[string]interface{} {
+		return map[string]interface{}{
+			"relation_name": rel.RelationName,
+			"type_column":   rel.TypeColumn,
+			"id_column":     rel.IDColumn,
+		}
+	}
+
+	// Check the source table and both relation columns
+	sourceExists, err := db.tableExists(table.TableName)
+	if err != nil {
+		return fmt.Errorf("failed to check if source table '%s' exists: %w", table.TableName, err)
+	}
+	if !sourceExists {
+		if ignoreMissingTables {
+			return nil
+		}
+		details := relationDetails()
+		details["error_type"] = "missing_source_table"
+		return handle(models.ValidationIssue{
+			Type:     "missing_source_table",
+			Severity: "error",
+			Table:    table.TableName,
+			Column:   rel.IDColumn,
+			Message:  fmt.Sprintf("Source table '%s' does not exist in the database (required by polymorphic relation '%s')", table.TableName, rel.RelationName),
+			Details:  details,
+		})
+	}
+
+	for _, columnName := range []string{rel.TypeColumn, rel.IDColumn} {
+		exists, err := db.columnExists(table.TableName, columnName)
+		if err != nil {
+			return fmt.Errorf("failed to check if source column '%s.%s' exists: %w", table.TableName, columnName, err)
+		}
+		if !exists {
+			if ignoreMissingColumns {
+				return nil
+			}
+			details := relationDetails()
+			details["error_type"] = "missing_source_column"
+			return handle(models.ValidationIssue{
+				Type:     "missing_source_column",
+				Severity: "error",
+				Table:    table.TableName,
+				Column:   columnName,
+				Message:  fmt.Sprintf("Source column '%s.%s' does not exist in the database (required by polymorphic relation '%s')", table.TableName, columnName, rel.RelationName),
+				Details:  details,
+			})
+		}
+	}
+
+	identifierCol := db.getIdentifierColumn(table.TableName)
+
+	for _, target := range rel.Targets {
+		referencedColumn := target.GetReferencedColumn()
+
+		// Check the table selected by this type value
+		refExists, err := db.tableExists(target.ReferencedTable)
+		if err != nil {
+			return fmt.Errorf("failed to check if referenced table '%s' exists: %w", target.ReferencedTable, err)
+		}
+		if !refExists {
+			if ignoreMissingTables {
+				continue
+			}
+			details := relationDetails()
+			details["type_value"] = target.TypeValue
+			details["referenced_table"] = target.ReferencedTable
+			details["error_type"] = "missing_referenced_table"
+			err := handle(models.ValidationIssue{
+				Type:     "missing_referenced_table",
+				Severity: "error",
+				Table:    table.TableName,
+				Column:   rel.IDColumn,
+				Message:  fmt.Sprintf("Referenced table '%s' does not exist in the database (required by polymorphic relation '%s', type '%s')", target.ReferencedTable, rel.RelationName, target.TypeValue),
+				Details:  details,
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		refColExists, err := db.columnExists(target.ReferencedTable, referencedColumn)
+		if err != nil {
+			return fmt.Errorf("failed to check if referenced column '%s.%s' exists: %w", target.ReferencedTable, referencedColumn, err)
+		}
+		if !refColExists {
+			if ignoreMissingColumns {
+				continue
+			}
+			details := relationDetails()
+			details["type_value"] = target.TypeValue
+			details["referenced_table"] = target.ReferencedTable
+			details["referenced_column"] = referencedColumn
+			details["error_type"] = "missing_referenced_column"
+			err := handle(models.ValidationIssue{
+				Type:     "missing_referenced_column",
+				Severity: "error",
+				Table:    table.TableName,
+				Column:   rel.IDColumn,
+				Message:  fmt.Sprintf("Referenced column '%s.%s' does not exist in the database (required by polymorphic relation '%s', type '%s')", target.ReferencedTable, referencedColumn, rel.RelationName, target.TypeValue),
+				Details:  details,
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Find rows of this type that point at a non-existent record
+		query := db.dialect.GetPolymorphicViolationsQuery(table.TableName, rel, target, identifierCol, table.RowFilter, limit)
+		rows, err := db.conn.Query(query, target.TypeValue)
+		if err != nil {
+			return fmt.Errorf("failed to execute polymorphic validation query for relation '%s' (type: %s, references: %s.%s): %w",
+				rel.RelationName, target.TypeValue, target.ReferencedTable, referencedColumn, err)
+		}
+
+		for rows.Next() {
+			var idValue, identifier sql.NullString
+			if err := rows.Scan(&idValue, &identifier); err != nil {
+				rows.Close()
+				return err
+			}
+
+			details := relationDetails()
+			details["type_value"] = target.TypeValue
+			details["referenced_table"] = target.ReferencedTable
+			details["referenced_column"] = referencedColumn
+			details["foreign_key_value"] = idValue.String
+			err := handle(models.ValidationIssue{
+				Type:     "polymorphic_violation",
+				Severity: "error",
+				Table:    table.TableName,
+				Column:   rel.IDColumn,
+				Message: fmt.Sprintf("Polymorphic reference violation: %s '%s' references non-existent record in %s.%s",
+					target.TypeValue, idValue.String, target.ReferencedTable, referencedColumn),
+				PrimaryKey: idValue.String,
+				Identifier: identifier.String,
+				Details:    details,
+			})
+			if err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Report type values that are not mapped to any table
+	return db.streamUnknownPolymorphicTypes(table, rel, handle)
+}
+
+// streamUnknownPolymorphicTypes reports type values that the relation does not map to a
+// table, passing every issue to handle as it is read
+func (db *DB) streamUnknownPolymorphicTypes(table models.Table, rel models.PolymorphicRelation, handle IssueHandler) error {
+	query := db.dialect.GetPolymorphicUnknownTypesQuery(table.TableName, rel, table.RowFilter)
+
+	args := make([]interface{}, 0, len(rel.Targets))
+	for _, value := range rel.GetTypeValues() {
+		args = append(args, value)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to look up unmapped type values for polymorphic relation '%s': %w", rel.RelationName, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var typeValue sql.NullString
+		var count int64
+		if err := rows.Scan(&typeValue, &count); err != nil {
+			return err
+		}
+
+		err := handle(models.ValidationIssue{
+			Type:     "polymorphic_unknown_type",
+			Severity: "warning",
+			Table:    table.TableName,
+			Column:   rel.TypeColumn,
+			Message: fmt.Sprintf("%d row(s) have type '%s' which is not mapped to a table by polymorphic relation '%s'",
+				count, typeValue.String, rel.RelationName),
+			Details: map[string]interface{}{
+				"relation_name": rel.RelationName,
+				"type_column":   rel.TypeColumn,
+				"id_column":     rel.IDColumn,
+				"type_value":    typeValue.String,
+				"row_count":     count,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
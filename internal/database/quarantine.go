@@ -0,0 +1,409 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// QuarantineSuffix is appended to a table name to name its quarantine table
+const QuarantineSuffix = "__quarantine"
+
+// Bookkeeping columns added to every quarantine table
+const (
+	QuarantineReasonColumn     = "quarantine_reason"
+	QuarantineConstraintColumn = "quarantine_constraint"
+	QuarantineRunIDColumn      = "quarantine_run_id"
+	QuarantinedAtColumn        = "quarantined_at"
+)
+
+// QuarantineTableName returns the name of the quarantine table mirroring tableName
+func QuarantineTableName(tableName string) string {
+	return tableName + QuarantineSuffix
+}
+
+// quarantineColumns returns the bookkeeping columns of a quarantine table
+func quarantineColumns() []models.Column {
+	constraintLength, runIDLength := 255, 64
+	return []models.Column{
+		{ColumnName: QuarantineReasonColumn, DataType: "text", IsNullable: "YES"},
+		{ColumnName: QuarantineConstraintColumn, DataType: "varchar", CharacterMaxLength: &constraintLength, IsNullable: "YES"},
+		{ColumnName: QuarantineRunIDColumn, DataType: "varchar", CharacterMaxLength: &runIDLength, IsNullable: "YES"},
+		{ColumnName: QuarantinedAtColumn, DataType: "timestamp", IsNullable: "YES"},
+	}
+}
+
+// isQuarantineColumn reports whether columnName is one of the bookkeeping columns
+func isQuarantineColumn(columnName string) bool {
+	for _, column := range quarantineColumns() {
+		if column.ColumnName == columnName {
+			return true
+		}
+	}
+	return false
+}
+
+// QuarantineFilter selects rows of a quarantine table
+type QuarantineFilter struct {
+	RunID      string
+	Constraint string
+	// OlderThan selects rows quarantined at least this long ago
+	OlderThan time.Duration
+}
+
+// condition returns the predicate selecting the filtered rows, with its arguments. Columns
+// are prefixed with qualifier (e.g. "q."), which may be empty.
+func (f QuarantineFilter) condition(db *DB, qualifier string) (string, []interface{}) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if f.RunID != "" {
+		args = append(args, f.RunID)
+		conditions = append(conditions, fmt.Sprintf("%s%s = %s", qualifier, db.quoteIdentifier(QuarantineRunIDColumn), db.dialect.GetPlaceholder(len(args))))
+	}
+	if f.Constraint != "" {
+		args = append(args, f.Constraint)
+		conditions = append(conditions, fmt.Sprintf("%s%s = %s", qualifier, db.quoteIdentifier(QuarantineConstraintColumn), db.dialect.GetPlaceholder(len(args))))
+	}
+	if f.OlderThan > 0 {
+		args = append(args, time.Now().Add(-f.OlderThan).UTC())
+		conditions = append(conditions, fmt.Sprintf("%s%s < %s", qualifier, db.quoteIdentifier(QuarantinedAtColumn), db.dialect.GetPlaceholder(len(args))))
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// quarantineTableDDL returns the columns shared by tableName and its quarantine table, and
+// the CREATE TABLE statement for the quarantine table when it does not exist yet. The
+// quarantine table mirrors the introspected columns of tableName; every column is nullable
+// and unconstrained so any row can be moved there.
+func (db *DB) quarantineTableDDL(tableName string) ([]string, string, error) {
+	sourceColumns, err := db.getTableColumns(tableName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read columns of %s: %w", tableName, err)
+	}
+	if len(sourceColumns) == 0 {
+		return nil, "", fmt.Errorf("table %s does not exist", tableName)
+	}
+
+	quarantineTable := QuarantineTableName(tableName)
+	existingColumns, err := db.getTableColumns(quarantineTable)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read columns of %s: %w", quarantineTable, err)
+	}
+
+	ddl := ""
+	if len(existingColumns) == 0 {
+		var definitions []string
+		for _, column := range append(append([]models.Column{}, sourceColumns...), quarantineColumns()...) {
+			definitions = append(definitions, fmt.Sprintf("%s %s", db.quoteIdentifier(column.ColumnName), db.dialect.GetColumnDefinitionType(column)))
+		}
+		ddl = fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", db.quoteIdentifier(quarantineTable), strings.Join(definitions, ",\n\t"))
+		existingColumns = sourceColumns
+	}
+
+	// Columns added to the source table after the quarantine table was created are left out
+	existing := make(map[string]bool, len(existingColumns))
+	for _, column := range existingColumns {
+		existing[column.ColumnName] = true
+	}
+	var columns []string
+	for _, column := range sourceColumns {
+		if existing[column.ColumnName] {
+			columns = append(columns, column.ColumnName)
+		}
+	}
+	return columns, ddl, nil
+}
+
+// quarantineStatements builds the statements moving the rows of tableName matching where
+// into its quarantine table. A missing quarantine table is created right away, outside the
+// fix transaction (MySQL commits implicitly on DDL); dry runs, SQL scripts and plans get a
+// create statement instead.
+func (db *DB) quarantineStatements(tableName, where, reason, constraint string, opts FixOptions) ([]fixStatement, error) {
+	columns, ddl, err := db.quarantineTableDDL(tableName)
+	if err != nil {
+		return nil, err
+	}
+	quarantineTable := QuarantineTableName(tableName)
+
+	var stmts []fixStatement
+	if ddl != "" {
+		create := fixStatement{
+			Table:       quarantineTable,
+			ResultTable: tableName,
+			Description: fmt.Sprintf("CREATE TABLE %s mirroring %s", quarantineTable, tableName),
+			Operation:   fixOperationCreate,
+			Source:      ddl,
+			Uncounted:   true,
+		}
+		if opts.DryRun || opts.Script != nil || opts.Plan != nil {
+			stmts = append(stmts, create)
+		} else {
+			_, err := db.conn.Exec(ddl)
+			opts.audit(db.auditStatement(create, 0, err))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create quarantine table %s: %w", quarantineTable, err)
+			}
+		}
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = db.quoteIdentifier(column)
+	}
+	// The copies are restored by the source table's primary key; the quarantine table has none
+	primaryKey, err := db.getPrimaryKeyColumns(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read primary key of %s: %w", tableName, err)
+	}
+	keyColumn := ""
+	if len(primaryKey) == 1 {
+		keyColumn = primaryKey[0]
+	}
+	insertColumns := append(append([]string{}, columns...),
+		QuarantineReasonColumn, QuarantineConstraintColumn, QuarantineRunIDColumn, QuarantinedAtColumn)
+
+	return append(stmts,
+		fixStatement{
+			Table:       quarantineTable,
+			ResultTable: tableName,
+			Description: fmt.Sprintf("INSERT INTO %s rows of %s violating %s", quarantineTable, tableName, constraint),
+			Operation:   fixOperationInsert,
+			KeyColumn:   keyColumn,
+			Columns:     insertColumns,
+			Source: fmt.Sprintf(`SELECT %s, %s, %s, %s, CURRENT_TIMESTAMP
+		FROM %s
+		WHERE %s`,
+				strings.Join(quoted, ", "),
+				db.dialect.GetPlaceholder(1), db.dialect.GetPlaceholder(2), db.dialect.GetPlaceholder(3),
+				db.quoteIdentifier(tableName), where),
+			Args: []interface{}{reason, constraint, opts.RunID},
+			// The copy only counts once, as the delete below
+			Uncounted: true,
+			// Copy and delete must see the same rows, so neither is split into batches
+			SingleStatement: true,
+		},
+		fixStatement{
+			Table:           tableName,
+			Description:     fmt.Sprintf("DELETE FROM %s rows moved to %s", tableName, quarantineTable),
+			Operation:       fixOperationDelete,
+			Where:           where,
+			SingleStatement: true,
+		},
+	), nil
+}
+
+// isQuarantineCopy reports whether stmt copies rows into a quarantine table
+func isQuarantineCopy(stmt fixStatement) bool {
+	return stmt.Operation == fixOperationInsert && strings.HasSuffix(stmt.Table, QuarantineSuffix)
+}
+
+// ListQuarantine summarizes the quarantine tables, optionally only the one of tableName
+func (db *DB) ListQuarantine(tableName string) ([]models.QuarantineEntry, error) {
+	tables, err := db.getTables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+
+	var entries []models.QuarantineEntry
+	for _, quarantineTable := range tables {
+		if !strings.HasSuffix(quarantineTable, QuarantineSuffix) {
+			continue
+		}
+		sourceTable := strings.TrimSuffix(quarantineTable, QuarantineSuffix)
+		if tableName != "" && sourceTable != tableName {
+			continue
+		}
+
+		query := fmt.Sprintf(`
+		SELECT %s, %s, %s, COUNT(*), MIN(%s), MAX(%s)
+		FROM %s
+		GROUP BY %s, %s, %s
+		ORDER BY MIN(%s)`,
+			db.quoteIdentifier(QuarantineRunIDColumn), db.quoteIdentifier(QuarantineConstraintColumn), db.quoteIdentifier(QuarantineReasonColumn),
+			db.quoteIdentifier(QuarantinedAtColumn), db.quoteIdentifier(QuarantinedAtColumn),
+			db.quoteIdentifier(quarantineTable),
+			db.quoteIdentifier(QuarantineRunIDColumn), db.quoteIdentifier(QuarantineConstraintColumn), db.quoteIdentifier(QuarantineReasonColumn),
+			db.quoteIdentifier(QuarantinedAtColumn))
+
+		rows, err := db.conn.Query(query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read quarantine table %s: %w", quarantineTable, err)
+		}
+		for rows.Next() {
+			entry := models.QuarantineEntry{Table: quarantineTable, SourceTable: sourceTable}
+			var runID, constraint, reason, first, last sql.NullString
+			if err := rows.Scan(&runID, &constraint, &reason, &entry.Rows, &first, &last); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			entry.RunID, entry.Constraint, entry.Reason = runID.String, constraint.String, reason.String
+			entry.FirstQuarantined, entry.LastQuarantined = first.String, last.String
+			entries = append(entries, entry)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// RestoreQuarantine moves the filtered rows of the quarantine table of tableName back into
+// the table. Rows whose key has been re-used in the table are conflicts; unless
+// skipConflicts is set, any conflict aborts the restore without changes.
+func (db *DB) RestoreQuarantine(tableName string, filter QuarantineFilter, dryRun, skipConflicts bool) (*models.QuarantineRestoreResult, error) {
+	quarantineTable := QuarantineTableName(tableName)
+	columns, err := db.quarantinedColumns(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.QuarantineRestoreResult{Table: tableName, DryRun: dryRun}
+	condition, args := filter.condition(db, "q.")
+
+	keyColumn := db.getIdentifierColumn(tableName)
+	keyMatch := "1 = 0"
+	if keyColumn != "1" {
+		keyMatch = fmt.Sprintf("EXISTS (SELECT 1 FROM %s AS t WHERE t.%s = q.%s)",
+			db.quoteIdentifier(tableName), db.quoteIdentifier(keyColumn), db.quoteIdentifier(keyColumn))
+
+		conflictQuery := fmt.Sprintf("SELECT q.%s FROM %s AS q WHERE %s AND %s",
+			db.quoteIdentifier(keyColumn), db.quoteIdentifier(quarantineTable), condition, keyMatch)
+		rows, err := db.conn.Query(conflictQuery, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for conflicts: %w", err)
+		}
+		for rows.Next() {
+			var key sql.NullString
+			if err := rows.Scan(&key); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			result.ConflictKeys = append(result.ConflictKeys, key.String)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s AS q WHERE %s AND NOT %s",
+		db.quoteIdentifier(quarantineTable), condition, keyMatch)
+	if err := db.conn.QueryRow(countQuery, args...).Scan(&result.Restored); err != nil {
+		return nil, fmt.Errorf("failed to count quarantined rows: %w", err)
+	}
+
+	if dryRun || (len(result.ConflictKeys) > 0 && !skipConflicts) {
+		return result, nil
+	}
+
+	quoted := make([]string, len(columns))
+	selected := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = db.quoteIdentifier(column)
+		selected[i] = "q." + db.quoteIdentifier(column)
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	insertQuery := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s AS q WHERE %s AND NOT %s",
+		db.quoteIdentifier(tableName), strings.Join(quoted, ", "), strings.Join(selected, ", "),
+		db.quoteIdentifier(quarantineTable), condition, keyMatch)
+	if _, err := tx.Exec(insertQuery, args...); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to restore rows into %s: %w", tableName, err)
+	}
+
+	// Remove the restored rows; conflicting rows stay in quarantine
+	deleteCondition, deleteArgs := filter.condition(db, "")
+	if len(result.ConflictKeys) > 0 {
+		deleteCondition += fmt.Sprintf(" AND %s NOT IN (%s)",
+			db.quoteIdentifier(keyColumn), db.placeholderList(len(deleteArgs)+1, len(result.ConflictKeys)))
+		for _, key := range result.ConflictKeys {
+			deleteArgs = append(deleteArgs, key)
+		}
+	}
+	deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE %s", db.quoteIdentifier(quarantineTable), deleteCondition)
+	if _, err := tx.Exec(deleteQuery, deleteArgs...); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to remove restored rows from %s: %w", quarantineTable, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return result, nil
+}
+
+// PurgeQuarantine permanently deletes the filtered rows of the quarantine table of tableName
+// and returns how many there were. With drop and no filter the table itself is dropped.
+func (db *DB) PurgeQuarantine(tableName string, filter QuarantineFilter, drop, dryRun bool) (int64, error) {
+	quarantineTable := QuarantineTableName(tableName)
+	if _, err := db.quarantinedColumns(tableName); err != nil {
+		return 0, err
+	}
+	if drop && filter != (QuarantineFilter{}) {
+		return 0, fmt.Errorf("dropping %s would also remove rows outside the filter", quarantineTable)
+	}
+
+	condition, args := filter.condition(db, "q.")
+	var count int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s AS q WHERE %s", db.quoteIdentifier(quarantineTable), condition)
+	if err := db.conn.QueryRow(countQuery, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count quarantined rows: %w", err)
+	}
+	if dryRun {
+		return count, nil
+	}
+
+	if drop {
+		if _, err := db.conn.Exec(fmt.Sprintf("DROP TABLE %s", db.quoteIdentifier(quarantineTable))); err != nil {
+			return 0, fmt.Errorf("failed to drop %s: %w", quarantineTable, err)
+		}
+		return count, nil
+	}
+
+	condition, args = filter.condition(db, "")
+	if _, err := db.conn.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", db.quoteIdentifier(quarantineTable), condition), args...); err != nil {
+		return 0, fmt.Errorf("failed to purge %s: %w", quarantineTable, err)
+	}
+	return count, nil
+}
+
+// quarantinedColumns returns the columns of tableName kept in its quarantine table, or an
+// error if there is no quarantine table
+func (db *DB) quarantinedColumns(tableName string) ([]string, error) {
+	quarantineTable := QuarantineTableName(tableName)
+	quarantineColumns, err := db.getTableColumns(quarantineTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", quarantineTable, err)
+	}
+	if len(quarantineColumns) == 0 {
+		return nil, fmt.Errorf("table %s has no quarantine table", tableName)
+	}
+
+	sourceColumns, err := db.getTableColumns(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", tableName, err)
+	}
+	inSource := make(map[string]bool, len(sourceColumns))
+	for _, column := range sourceColumns {
+		inSource[column.ColumnName] = true
+	}
+
+	var columns []string
+	for _, column := range quarantineColumns {
+		if !isQuarantineColumn(column.ColumnName) && inSource[column.ColumnName] {
+			columns = append(columns, column.ColumnName)
+		}
+	}
+	return columns, nil
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordedExec is a statement executed on a recording connection with its bound arguments
+type recordedExec struct {
+	Query string
+	Args  []driver.Value
+}
+
+// recorder answers the queries of a recording connection and records what it executes
+type recorder struct {
+	mu    sync.Mutex
+	execs []recordedExec
+	// query returns the columns and rows of a query; nil returns no rows
+	query func(query string, args []driver.Value) ([]string, [][]driver.Value)
+	// rowsAffected returns the rows affected by a statement; nil affects one row
+	rowsAffected func(query string, args []driver.Value) int64
+	// fail returns the error a statement fails with; nil lets every statement succeed
+	fail func(query string, args []driver.Value) error
+}
+
+// updates returns the executed UPDATE statements
+func (r *recorder) updates() []recordedExec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var updates []recordedExec
+	for _, exec := range r.execs {
+		if strings.HasPrefix(strings.TrimSpace(exec.Query), "UPDATE") {
+			updates = append(updates, exec)
+		}
+	}
+	return updates
+}
+
+var (
+	recordersMu sync.Mutex
+	recorders   = make(map[string]*recorder)
+)
+
+func init() {
+	sql.Register("recording", recordingDriver{})
+}
+
+// newRecordingDB opens a DB of the given dialect whose statements are recorded by rec
+func newRecordingDB(t *testing.T, dbType DatabaseType, rec *recorder) *DB {
+	t.Helper()
+	recordersMu.Lock()
+	dsn := fmt.Sprintf("%s-%d", t.Name(), len(recorders))
+	recorders[dsn] = rec
+	recordersMu.Unlock()
+
+	conn, err := sql.Open("recording", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+
+	var dialect DatabaseDialect = &PostgreSQLDialect{}
+	if dbType == MySQL {
+		dialect = &MySQLDialect{}
+	}
+	return &DB{conn: conn, dbType: dbType, dialect: dialect}
+}
+
+type recordingDriver struct{}
+
+func (recordingDriver) Open(dsn string) (driver.Conn, error) {
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+	rec, ok := recorders[dsn]
+	if !ok {
+		return nil, fmt.Errorf("no recorder for %s", dsn)
+	}
+	return &recordingConn{rec: rec}, nil
+}
+
+type recordingConn struct {
+	rec *recorder
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{conn: c, query: query}, nil
+}
+
+func (c *recordingConn) Close() error              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) { return recordingTx{}, nil }
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, named []driver.NamedValue) (driver.Result, error) {
+	args := namedValues(named)
+	if c.rec.fail != nil {
+		if err := c.rec.fail(query, args); err != nil {
+			return nil, err
+		}
+	}
+	c.rec.mu.Lock()
+	c.rec.execs = append(c.rec.execs, recordedExec{Query: query, Args: args})
+	c.rec.mu.Unlock()
+
+	rowsAffected := int64(1)
+	if c.rec.rowsAffected != nil {
+		rowsAffected = c.rec.rowsAffected(query, args)
+	}
+	return driver.RowsAffected(rowsAffected), nil
+}
+
+func (c *recordingConn) QueryContext(_ context.Context, query string, named []driver.NamedValue) (driver.Rows, error) {
+	var columns []string
+	var rows [][]driver.Value
+	if c.rec.query != nil {
+		columns, rows = c.rec.query(query, namedValues(named))
+	}
+	if len(columns) == 0 {
+		columns = []string{"value"}
+	}
+	return &recordingRows{columns: columns, rows: rows}, nil
+}
+
+type recordingStmt struct {
+	conn  *recordingConn
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, valuesNamed(args))
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, valuesNamed(args))
+}
+
+type recordingTx struct{}
+
+func (recordingTx) Commit() error   { return nil }
+func (recordingTx) Rollback() error { return nil }
+
+type recordingRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *recordingRows) Columns() []string { return r.columns }
+func (r *recordingRows) Close() error      { return nil }
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+func namedValues(named []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(named))
+	for i, value := range named {
+		values[i] = value.Value
+	}
+	return values
+}
+
+func valuesNamed(values []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(values))
+	for i, value := range values {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: value}
+	}
+	return named
+}
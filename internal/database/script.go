@@ -0,0 +1,228 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLScript collects fix statements as a reviewable SQL script instead of executing them.
+// Statements are written in the order the fix would run them, wrapped in one transaction,
+// with their arguments inlined and each guarded by a row count assertion.
+type SQLScript struct {
+	db      *DB
+	header  []string
+	entries []scriptEntry
+	// modified records the tables changed by earlier statements of the script
+	modified map[string]bool
+	// created records the tables created by the script
+	created map[string]bool
+}
+
+// scriptEntry is one statement of a SQL script
+type scriptEntry struct {
+	description string
+	table       string
+	query       string
+	expected    int64
+	// asserted is false when earlier statements of the script may change the row count
+	asserted bool
+	ddl      bool
+}
+
+// NewSQLScript creates an empty script; header lines are written as comments at the top
+func (db *DB) NewSQLScript(header ...string) *SQLScript {
+	return &SQLScript{db: db, header: header, modified: make(map[string]bool), created: make(map[string]bool)}
+}
+
+// Len returns the number of statements in the script
+func (s *SQLScript) Len() int {
+	return len(s.entries)
+}
+
+// add appends a fix statement to the script and returns the number of rows it is expected
+// to change, counted against the current data. Nothing is modified.
+func (s *SQLScript) add(stmt fixStatement) (int, error) {
+	if stmt.Operation == fixOperationCreate {
+		// Fixes for several constraints of one table each ask for its quarantine table
+		if s.created[stmt.Table] {
+			return 0, nil
+		}
+		s.created[stmt.Table] = true
+		s.entries = append(s.entries, scriptEntry{description: stmt.Description, table: stmt.Table, query: stmt.Source, ddl: true})
+		return 0, nil
+	}
+
+	query, args := s.db.fixStatementSQL(stmt)
+	query = strings.TrimSpace(s.db.inlineArgs(query, args))
+
+	expected, err := s.db.countStatementRows(stmt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rows for %s: %w", stmt.Description, err)
+	}
+
+	// A count taken before earlier statements run is only exact if the statement does not
+	// read any table they change
+	asserted := !s.db.readsAnyTable(stmt, s.modified)
+
+	s.entries = append(s.entries, scriptEntry{
+		description: stmt.Description,
+		table:       stmt.Table,
+		query:       query,
+		expected:    expected,
+		asserted:    asserted,
+	})
+	s.modified[stmt.Table] = true
+	return int(expected), nil
+}
+
+// String renders the script
+func (s *SQLScript) String() string {
+	var b strings.Builder
+	for _, line := range s.header {
+		fmt.Fprintf(&b, "-- %s\n", line)
+	}
+	fmt.Fprintf(&b, "-- Generated: %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "-- Dialect: %s\n", s.db.dbType)
+	fmt.Fprintf(&b, "--\n")
+	fmt.Fprintf(&b, "-- All statements run in one transaction. A failed row count assertion raises an\n")
+	fmt.Fprintf(&b, "-- error; stop on that error and the transaction is never committed.\n\n")
+
+	if len(s.entries) == 0 {
+		fmt.Fprintf(&b, "-- Nothing to fix.\n")
+		return b.String()
+	}
+
+	// Tables are created before the transaction: MySQL commits implicitly on DDL
+	for i, entry := range s.entries {
+		if entry.ddl {
+			fmt.Fprintf(&b, "-- [%d] %s\n%s;\n\n", i+1, entry.description, entry.query)
+		}
+	}
+
+	fmt.Fprintf(&b, "%s;\n\n", s.db.dialect.GetBeginTransactionStatement())
+	for i, entry := range s.entries {
+		if entry.ddl {
+			continue
+		}
+		fmt.Fprintf(&b, "-- [%d] %s\n", i+1, entry.description)
+		switch {
+		case entry.asserted:
+			fmt.Fprintf(&b, "-- Expected rows: %d\n", entry.expected)
+			fmt.Fprintf(&b, "%s\n\n", s.db.dialect.GetRowCountAssertion(entry.query, entry.expected))
+		default:
+			fmt.Fprintf(&b, "-- Rows against the current data: %d. Earlier statements in this script change\n", entry.expected)
+			fmt.Fprintf(&b, "-- tables this statement reads, so the count is not asserted.\n")
+			fmt.Fprintf(&b, "%s;\n\n", entry.query)
+		}
+	}
+	fmt.Fprintf(&b, "COMMIT;\n")
+	return b.String()
+}
+
+// readsAnyTable reports whether a statement selects its rows or values from one of tables
+func (db *DB) readsAnyTable(stmt fixStatement, tables map[string]bool) bool {
+	reads := stmt.Source
+	if stmt.Operation != fixOperationInsert {
+		reads = strings.Join([]string{db.quoteIdentifier(stmt.Table), stmt.Where, stmt.Expression}, " ")
+	}
+	for table := range tables {
+		if strings.Contains(reads, db.quoteIdentifier(table)) {
+			return true
+		}
+	}
+	return false
+}
+
+// countStatementRows counts the rows a fix statement would change
+func (db *DB) countStatementRows(stmt fixStatement) (int64, error) {
+	if stmt.Operation == fixOperationInsert {
+		// Arguments are inlined: PostgreSQL cannot type parameters in a derived table's
+		// select list
+		query := fmt.Sprintf("SELECT COUNT(*) FROM (%s) fix_rows", db.inlineArgs(stmt.Source, stmt.Args))
+		var count int64
+		err := db.conn.QueryRow(query).Scan(&count)
+		return count, err
+	}
+	return db.countFixRows(stmt)
+}
+
+// inlineArgs replaces the placeholders of query with the arguments as SQL literals.
+// Placeholders inside quoted strings and identifiers are left alone.
+func (db *DB) inlineArgs(query string, args []interface{}) string {
+	if len(args) == 0 {
+		return query
+	}
+
+	var b strings.Builder
+	next := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if quote != 0 {
+			b.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			b.WriteByte(c)
+		case c == '?' && db.dialect.GetPlaceholder(1) == "?":
+			if next < len(args) {
+				b.WriteString(db.sqlLiteral(args[next]))
+				next++
+			} else {
+				b.WriteByte(c)
+			}
+		case c == '$' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			position, _ := strconv.Atoi(query[i+1 : j])
+			if position >= 1 && position <= len(args) {
+				b.WriteString(db.sqlLiteral(args[position-1]))
+			} else {
+				b.WriteString(query[i:j])
+			}
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// sqlLiteral renders a statement argument as an SQL literal
+func (db *DB) sqlLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999") + "'"
+	case []byte:
+		return db.quoteString(string(v))
+	default:
+		return db.quoteString(fmt.Sprint(v))
+	}
+}
+
+// quoteString quotes a string literal; MySQL also treats backslashes as escapes
+func (db *DB) quoteString(value string) string {
+	if db.dbType == MySQL {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,36 @@
+package database
+
+import "github.com/nkamuo/go-db-migration/internal/models"
+
+// IssueHandler receives validation issues as they are found. An error stops the
+// validation and is returned by it.
+type IssueHandler func(issue models.ValidationIssue) error
+
+// collectIssues returns a handler appending every issue to issues
+func collectIssues(issues *[]models.ValidationIssue) IssueHandler {
+	return func(issue models.ValidationIssue) error {
+		*issues = append(*issues, issue)
+		return nil
+	}
+}
+
+// handledIssues passes issues on to a handler and keeps its error apart from the errors
+// of the validation query, which are reported as issues themselves
+type handledIssues struct {
+	next IssueHandler
+	err  error
+}
+
+// handlerErrors wraps a handler so that its errors can be told apart
+func handlerErrors(next IssueHandler) *handledIssues {
+	return &handledIssues{next: next}
+}
+
+// handle passes an issue on and records the handler's error
+func (h *handledIssues) handle(issue models.ValidationIssue) error {
+	if err := h.next(issue); err != nil {
+		h.err = err
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,516 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// Commit policies for fix runs
+const (
+	// CommitAtomic runs the whole fix invocation in one transaction: any failure undoes everything
+	CommitAtomic = "atomic"
+	// CommitPerTable commits each table separately: a failure only undoes that table's changes
+	CommitPerTable = "per-table"
+	// CommitPerBatch commits every batch of a batched fix: a failure only undoes the failing batch
+	CommitPerBatch = "per-batch"
+)
+
+// FixOptions controls how fixes are applied
+type FixOptions struct {
+	DryRun       bool
+	CommitPolicy string
+	// RunID identifies the run in its manifest and backups; generated when empty
+	RunID string
+	// PlanID is the run ID of the fix plan being applied, recorded in the manifest
+	PlanID string
+	// ConnectionName is recorded in the run manifest
+	ConnectionName string
+	// BackupDir is where row backups and the run manifest are written
+	BackupDir string
+	// NoBackup disables copying affected rows before they are changed
+	NoBackup bool
+	// BatchSize splits every fix statement into batches of at most this many rows, walking
+	// the table's primary key; 0 runs each fix as a single statement
+	BatchSize int
+	// BatchSleep is the pause between batches
+	BatchSleep time.Duration
+	// MaxReplicationLag pauses batching while replicas lag behind by more than this
+	MaxReplicationLag time.Duration
+	// Progress, if set, is called after every batch and while waiting for replicas
+	Progress func(FixProgress)
+	// Script, if set, collects the statements as a SQL script instead of executing them
+	Script *SQLScript
+	// Plan, if set, records the statements into a fix plan instead of executing them
+	Plan *FixPlanRecorder
+	// Audit, if set, is called with every statement executed, for the audit log
+	Audit func(models.AuditStatement)
+}
+
+// GetCommitPolicy returns the commit policy, defaulting to per-batch for batched fixes and
+// atomic otherwise
+func (o FixOptions) GetCommitPolicy() string {
+	if o.CommitPolicy == "" {
+		if o.BatchSize > 0 {
+			return CommitPerBatch
+		}
+		return CommitAtomic
+	}
+	return o.CommitPolicy
+}
+
+// Fix statement operations
+const (
+	fixOperationDelete = "delete"
+	fixOperationUpdate = "update"
+	fixOperationInsert = "insert"
+	// fixOperationCreate creates a table (Source holds the DDL); only written to SQL scripts,
+	// fix runs create tables before their transaction starts
+	fixOperationCreate = "create"
+)
+
+// fixStatement is a single DELETE/UPDATE/INSERT issued by a fix
+type fixStatement struct {
+	Table       string
+	Description string
+	Operation   string
+	// ResultTable is the table whose FixResult the statement counts towards, when it differs
+	// from Table (e.g. parent rows inserted to fix a child table)
+	ResultTable string
+	// KeyColumn identifies the changed rows for undo; looked up when empty
+	KeyColumn string
+	// Columns and Source are the inserted columns and the SELECT producing their values
+	Columns []string
+	Source  string
+	// Column and Value are the column assigned by an update and its new value (nil sets NULL)
+	Column string
+	Value  interface{}
+	// Expression, when set, is assigned instead of Value
+	Expression string
+	// Where is the predicate selecting the affected rows, with Args bound to its placeholders
+	Where string
+	Args  []interface{}
+	// Uncounted statements do not add to RecordsAffected, e.g. copying rows into quarantine
+	// before deleting them
+	Uncounted bool
+	// SingleStatement prevents batching when splitting the statement would change its
+	// result, e.g. a self-referencing foreign key where earlier batches create new orphans
+	SingleStatement bool
+}
+
+// resultTable returns the table whose FixResult the statement counts towards
+func (s fixStatement) resultTable() string {
+	if s.ResultTable != "" {
+		return s.ResultTable
+	}
+	return s.Table
+}
+
+// execAll runs statements in order and returns the rows affected by the counted ones
+func (r *fixRun) execAll(stmts []fixStatement) (int, error) {
+	recordsAffected := 0
+	for _, stmt := range stmts {
+		rowsAffected, err := r.exec(stmt)
+		if !stmt.Uncounted {
+			recordsAffected += rowsAffected
+		}
+		if err != nil {
+			return recordsAffected, err
+		}
+	}
+	return recordsAffected, nil
+}
+
+// fixStatementSQL returns the DELETE/UPDATE/INSERT query for a fix statement
+func (db *DB) fixStatementSQL(stmt fixStatement) (string, []interface{}) {
+	if stmt.Operation == fixOperationInsert {
+		quoted := make([]string, len(stmt.Columns))
+		for i, column := range stmt.Columns {
+			quoted[i] = db.quoteIdentifier(column)
+		}
+		return fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		%s`, db.quoteIdentifier(stmt.Table), strings.Join(quoted, ", "), stmt.Source), stmt.Args
+	}
+
+	if stmt.Operation == fixOperationDelete {
+		return fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE %s`, db.quoteIdentifier(stmt.Table), stmt.Where), stmt.Args
+	}
+
+	if stmt.Expression != "" {
+		return fmt.Sprintf(`
+		UPDATE %s
+		SET %s = %s
+		WHERE %s`, db.quoteIdentifier(stmt.Table), db.quoteIdentifier(stmt.Column), stmt.Expression, stmt.Where), stmt.Args
+	}
+
+	if stmt.Value == nil {
+		return fmt.Sprintf(`
+		UPDATE %s
+		SET %s = NULL
+		WHERE %s`, db.quoteIdentifier(stmt.Table), db.quoteIdentifier(stmt.Column), stmt.Where), stmt.Args
+	}
+
+	// The new value is bound first, ahead of the predicate's arguments, since positional
+	// placeholders are bound in the order they appear
+	args := append([]interface{}{stmt.Value}, stmt.Args...)
+	return fmt.Sprintf(`
+		UPDATE %s
+		SET %s = %s
+		WHERE %s`, db.quoteIdentifier(stmt.Table), db.quoteIdentifier(stmt.Column), db.dialect.GetPlaceholder(1), db.shiftPlaceholders(stmt.Where, 1)),
+		args
+}
+
+// shiftPlaceholders renumbers the numbered placeholders of query by offset, so that the
+// query's arguments can follow others. Positional placeholders are left as they are.
+// Placeholders inside quoted strings and identifiers are left alone.
+func (db *DB) shiftPlaceholders(query string, offset int) string {
+	if db.dialect.GetPlaceholder(1) == "?" {
+		return query
+	}
+
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if quote != 0 {
+			b.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			b.WriteByte(c)
+		case c == '$' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			position, _ := strconv.Atoi(query[i+1 : j])
+			b.WriteString(db.dialect.GetPlaceholder(position + offset))
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// fixBackupSQL returns the query selecting the rows a fix statement is about to change
+// (for inserts, the rows it is about to insert)
+func (db *DB) fixBackupSQL(stmt fixStatement) (string, []interface{}) {
+	if stmt.Operation == fixOperationInsert {
+		return stmt.Source, stmt.Args
+	}
+	return fmt.Sprintf(`
+		SELECT *
+		FROM %s
+		WHERE %s`, db.quoteIdentifier(stmt.Table), stmt.Where), stmt.Args
+}
+
+// appliedStatement is a fix statement that has been executed inside the open transaction
+type appliedStatement struct {
+	fixStatement
+	RowsAffected int
+	// backupEntries are the indexes of the statement's entries in the run manifest, its
+	// own and those of the rows its referential actions change (none without backup)
+	backupEntries []int
+}
+
+// fixRun executes fix statements inside transactions according to the commit policy.
+// Every statement runs under its own savepoint so a failing constraint can be rolled back
+// precisely before the policy decides what else to undo.
+type fixRun struct {
+	db        *DB
+	results   models.FixResults
+	opts      FixOptions
+	command   string
+	action    string
+	policy    string
+	tx        *sql.Tx
+	applied   []appliedStatement
+	savepoint int
+	backup    *backupWriter
+	// committed counts the rows of each result table committed so far
+	committed map[string]int
+}
+
+// newFixRun creates a fix run; nothing is started until the first statement is executed
+func (db *DB) newFixRun(opts FixOptions, command, action string, results models.FixResults) (*fixRun, error) {
+	policy := opts.GetCommitPolicy()
+	switch policy {
+	case CommitAtomic, CommitPerTable:
+		if opts.BatchSize > 0 {
+			return nil, fmt.Errorf("batched fixes commit after every batch and cannot use the %s commit policy", policy)
+		}
+	case CommitPerBatch:
+		if opts.BatchSize <= 0 {
+			return nil, fmt.Errorf("the %s commit policy requires a batch size", policy)
+		}
+	default:
+		return nil, fmt.Errorf("unknown commit policy: %s (must be '%s', '%s' or '%s')", policy, CommitAtomic, CommitPerTable, CommitPerBatch)
+	}
+	if opts.Script != nil && opts.BatchSize > 0 {
+		return nil, fmt.Errorf("SQL scripts run in one transaction and cannot be batched")
+	}
+	if opts.Script != nil && opts.Plan != nil {
+		return nil, fmt.Errorf("a fix cannot be written to a SQL script and a plan at once")
+	}
+	if opts.MaxReplicationLag > 0 && db.dialect.GetReplicationLagQuery() == "" {
+		return nil, fmt.Errorf("a replication lag cap is not supported for %s", db.dbType)
+	}
+	if opts.RunID == "" {
+		opts.RunID = NewFixRunID()
+	}
+	return &fixRun{db: db, results: results, opts: opts, command: command, action: action, policy: policy}, nil
+}
+
+// startBackup creates the run manifest the first time a statement is executed
+func (r *fixRun) startBackup() error {
+	if r.opts.NoBackup || r.backup != nil {
+		return nil
+	}
+
+	database := ""
+	if r.db.config != nil {
+		database = r.db.config.Database
+	}
+	backup, err := newBackupWriter(r.opts.BackupDir, &models.FixRunManifest{
+		RunID:        r.opts.RunID,
+		PlanID:       r.opts.PlanID,
+		Connection:   r.opts.ConnectionName,
+		Database:     database,
+		Command:      r.command,
+		Action:       r.action,
+		CommitPolicy: r.policy,
+		StartedAt:    time.Now().UTC().Format(time.RFC3339),
+		Status:       RunStatusRunning,
+	})
+	if err != nil {
+		return err
+	}
+	r.backup = backup
+	return nil
+}
+
+// backupEntries returns the manifest entries of the given statements
+func backupEntries(statements []appliedStatement) []int {
+	indexes := make([]int, 0, len(statements))
+	for _, stmt := range statements {
+		indexes = append(indexes, stmt.backupEntries...)
+	}
+	return indexes
+}
+
+// exec runs a fix statement, in batches when a batch size is configured
+func (r *fixRun) exec(stmt fixStatement) (int, error) {
+	if r.opts.Script != nil {
+		return r.opts.Script.add(stmt)
+	}
+	if r.opts.Plan != nil {
+		return r.opts.Plan.add(stmt)
+	}
+
+	rowsAffected, err := r.run(stmt)
+	r.opts.audit(r.db.auditStatement(stmt, rowsAffected, err))
+	return rowsAffected, err
+}
+
+// run executes a fix statement as a single statement or in batches
+func (r *fixRun) run(stmt fixStatement) (int, error) {
+	if r.opts.BatchSize <= 0 {
+		return r.execOne(stmt)
+	}
+	if stmt.SingleStatement {
+		r.progress(FixProgress{
+			Table:       stmt.Table,
+			Description: stmt.Description,
+			Message:     "batching would change the result; running as a single statement",
+		})
+		return r.execOneCommitted(stmt)
+	}
+	return r.execBatched(stmt)
+}
+
+// execOne runs a statement inside the current transaction, opening one if needed. Unless
+// backups are disabled, the rows it changes are copied to the run's backup first.
+func (r *fixRun) execOne(stmt fixStatement) (int, error) {
+	if err := r.startBackup(); err != nil {
+		return 0, err
+	}
+
+	if r.tx == nil {
+		tx, err := r.db.conn.Begin()
+		if err != nil {
+			return 0, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		r.tx = tx
+		r.applied = nil
+	}
+
+	r.savepoint++
+	savepoint := fmt.Sprintf("fix_%d", r.savepoint)
+	if _, err := r.tx.Exec("SAVEPOINT " + savepoint); err != nil {
+		return 0, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	rowsAffected, entries, err := r.execStatement(stmt)
+	if err == nil {
+		if _, err := r.tx.Exec("RELEASE SAVEPOINT " + savepoint); err != nil {
+			return 0, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		r.applied = append(r.applied, appliedStatement{fixStatement: stmt, RowsAffected: rowsAffected, backupEntries: entries})
+		return rowsAffected, nil
+	}
+
+	if r.backup != nil && len(entries) > 0 {
+		r.backup.setEntryStatus(entries, EntryStatusRolledBack)
+	}
+	if _, rbErr := r.tx.Exec("ROLLBACK TO SAVEPOINT " + savepoint); rbErr != nil {
+		return 0, fmt.Errorf("%v (rollback to savepoint also failed: %v)", err, rbErr)
+	}
+	return 0, err
+}
+
+// execStatement backs up and executes a statement inside the open transaction
+func (r *fixRun) execStatement(stmt fixStatement) (int, []int, error) {
+	var entries []int
+	if r.backup != nil {
+		var err error
+		entries, err = r.backup.backup(r.tx, r.db, stmt)
+		if err != nil {
+			return 0, entries, fmt.Errorf("failed to back up rows before fixing: %w", err)
+		}
+	}
+
+	query, args := r.db.fixStatementSQL(stmt)
+	result, err := r.tx.Exec(query, args...)
+	if err != nil {
+		return 0, entries, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return int(rowsAffected), entries, err
+}
+
+// endTable commits the current transaction when committing per table
+func (r *fixRun) endTable() error {
+	if r.policy != CommitPerTable {
+		return nil
+	}
+	return r.commit()
+}
+
+// finish commits whatever is still open and records the run's final status
+func (r *fixRun) finish() error {
+	err := r.commit()
+	if r.backup != nil {
+		if finishErr := r.backup.finish(); finishErr != nil && err == nil {
+			err = finishErr
+		}
+	}
+	return err
+}
+
+// commit commits the open transaction, if any. A failed commit leaves nothing applied,
+// so its statements are recorded as undone.
+func (r *fixRun) commit() error {
+	if r.tx == nil {
+		return nil
+	}
+	applied := r.applied
+	err := r.tx.Commit()
+	r.tx = nil
+	r.applied = nil
+	if err != nil {
+		markUndone(r.results, applied)
+		if r.backup != nil {
+			r.backup.setEntryStatus(backupEntries(applied), EntryStatusRolledBack)
+		}
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if r.committed == nil {
+		r.committed = make(map[string]int)
+	}
+	for _, stmt := range applied {
+		if !stmt.Uncounted {
+			r.committed[stmt.resultTable()] += stmt.RowsAffected
+		}
+	}
+	if r.backup != nil {
+		return r.backup.setEntryStatus(backupEntries(applied), EntryStatusCommitted)
+	}
+	return nil
+}
+
+// rollback undoes the open transaction and returns the statements that were undone
+func (r *fixRun) rollback() ([]appliedStatement, error) {
+	if r.tx == nil {
+		return nil, nil
+	}
+	undone := r.applied
+	err := r.tx.Rollback()
+	r.tx = nil
+	r.applied = nil
+	if r.backup != nil {
+		r.backup.setEntryStatus(backupEntries(undone), EntryStatusRolledBack)
+	}
+	if err != nil {
+		return undone, fmt.Errorf("failed to roll back transaction: %w", err)
+	}
+	return undone, nil
+}
+
+// markUndone records rolled back statements on the affected tables' results. Uncounted
+// statements never added to RecordsAffected, so nothing is taken off for them.
+func markUndone(results models.FixResults, undone []appliedStatement) {
+	for _, stmt := range undone {
+		result := results[stmt.resultTable()]
+		if !stmt.Uncounted {
+			result.RecordsAffected -= stmt.RowsAffected
+		}
+		result.RolledBack = true
+		result.Success = false
+		result.UndoneStatements = append(result.UndoneStatements,
+			fmt.Sprintf("%s (%d rows)", stmt.Description, stmt.RowsAffected))
+		results[stmt.resultTable()] = result
+	}
+}
+
+// failFix rolls back after a failed statement according to the commit policy and records the
+// outcome. It returns true when the whole run must stop (atomic policy). Rows of the table
+// committed before the failure, e.g. by earlier batches, stay applied, so the table is only
+// reported as rolled back when none were.
+func (r *fixRun) failFix(tableName string, fixErr error) bool {
+	results := r.results
+	undone, rbErr := r.rollback()
+	markUndone(results, undone)
+
+	result := results[tableName]
+	result.Success = false
+	if r.committed[tableName] == 0 {
+		result.RolledBack = true
+	}
+	result.Error = fixErr.Error()
+	if rbErr != nil {
+		result.Error = fmt.Sprintf("%s; %v", result.Error, rbErr)
+	}
+	results[tableName] = result
+
+	if r.policy == CommitAtomic {
+		if r.backup != nil {
+			r.backup.finish()
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// UndoFixRun restores the rows changed by a fix run from its backups: deleted rows are
+// re-inserted, updated columns are set back to their old values and inserted rows are
+// deleted again. Rows whose data has changed since the fix are reported as conflicts;
+// unless skipConflicts is set, any conflict aborts the undo without changes. Everything is restored in one transaction.
+func (db *DB) UndoFixRun(baseDir, runID string, dryRun, skipConflicts bool) (*models.UndoResult, error) {
+	manifest, err := LoadFixManifest(baseDir, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch manifest.Status {
+	case RunStatusUndone:
+		return nil, fmt.Errorf("fix run %s has already been undone (at %s)", runID, manifest.UndoneAt)
+	case RunStatusRunning:
+		return nil, fmt.Errorf("fix run %s did not finish; check the database before restoring its backups manually", runID)
+	}
+	if db.config != nil && manifest.Database != "" && manifest.Database != db.config.Database {
+		return nil, fmt.Errorf("fix run %s was made on database '%s', not '%s'", runID, manifest.Database, db.config.Database)
+	}
+
+	result := &models.UndoResult{
+		RunID:    runID,
+		DryRun:   dryRun,
+		Restored: make(map[string]int),
+	}
+
+	// Load the backups of committed statements, newest first so that overlapping changes
+	// are reverted in reverse order
+	type pendingEntry struct {
+		index int
+		entry models.FixBackupEntry
+		rows  []map[string]interface{}
+	}
+	var pending []pendingEntry
+	for i := len(manifest.Entries) - 1; i >= 0; i-- {
+		entry := manifest.Entries[i]
+		if entry.Status != EntryStatusCommitted {
+			continue
+		}
+		rows, err := readBackupRows(baseDir, runID, entry.File)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, pendingEntry{index: i, entry: entry, rows: rows})
+	}
+
+	// Detect conflicts before touching anything
+	skip := make(map[int]map[int]bool)
+	for _, p := range pending {
+		conflicts, rowIndexes, err := db.findUndoConflicts(p.entry, runID, p.rows)
+		if err != nil {
+			return nil, err
+		}
+		result.Conflicts = append(result.Conflicts, conflicts...)
+		skip[p.index] = rowIndexes
+	}
+
+	if dryRun || (len(result.Conflicts) > 0 && !skipConflicts) {
+		for _, p := range pending {
+			result.Restored[p.entry.Table] += len(p.rows) - len(skip[p.index])
+		}
+		return result, nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	for _, p := range pending {
+		restored, err := db.restoreBackupEntry(tx, p.entry, runID, p.rows, skip[p.index])
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to restore %s: %w", p.entry.Description, err)
+		}
+		result.Restored[p.entry.Table] += restored
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit undo: %w", err)
+	}
+
+	for _, p := range pending {
+		manifest.Entries[p.index].Status = EntryStatusUndone
+	}
+	manifest.Status = RunStatusUndone
+	manifest.UndoneAt = time.Now().UTC().Format(time.RFC3339)
+	if err := SaveFixManifest(baseDir, manifest); err != nil {
+		return result, fmt.Errorf("rows were restored but the manifest could not be updated: %w", err)
+	}
+
+	return result, nil
+}
+
+// findUndoConflicts checks whether the backed up rows can still be restored. It returns the
+// conflicts and the indexes of the conflicting rows.
+func (db *DB) findUndoConflicts(entry models.FixBackupEntry, runID string, rows []map[string]interface{}) ([]models.UndoConflict, map[int]bool, error) {
+	var conflicts []models.UndoConflict
+	conflicting := make(map[int]bool)
+
+	if entry.KeyColumn == "" || entry.KeyColumn == "1" {
+		return nil, nil, fmt.Errorf("cannot restore %s: the backup has no primary key to identify its rows by", entry.Table)
+	}
+
+	for i, row := range rows {
+		key, ok := row[entry.KeyColumn]
+		if !ok || key == nil {
+			conflicts = append(conflicts, models.UndoConflict{
+				Table:  entry.Table,
+				Column: entry.KeyColumn,
+				Reason: "backed up row has no key value",
+			})
+			conflicting[i] = true
+			continue
+		}
+		keyString := fmt.Sprint(key)
+
+		condition, args := db.undoRowCondition(entry, runID, keyString)
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+			db.quoteIdentifier(columnOrKey(entry)),
+			db.quoteIdentifier(entry.Table),
+			condition)
+
+		var current sql.NullString
+		err := db.conn.QueryRow(query, args...).Scan(&current)
+		switch {
+		case err == sql.ErrNoRows:
+			if entry.Operation != fixOperationDelete {
+				conflicts = append(conflicts, models.UndoConflict{
+					Table:  entry.Table,
+					Key:    keyString,
+					Column: entry.Column,
+					Reason: "row no longer exists",
+				})
+				conflicting[i] = true
+			}
+		case err != nil:
+			return nil, nil, fmt.Errorf("failed to check %s %s=%s: %w", entry.Table, entry.KeyColumn, keyString, err)
+		case entry.Operation == fixOperationDelete:
+			conflicts = append(conflicts, models.UndoConflict{
+				Table:  entry.Table,
+				Key:    keyString,
+				Reason: "a row with the same key has been inserted since the fix",
+			})
+			conflicting[i] = true
+		case entry.Operation == fixOperationInsert:
+			// The inserted row is still there and can be removed
+		case entry.Expression != "":
+			// Expressions assign a different value per row, so later changes cannot be detected
+		case !matchesFixValue(current, entry.Value):
+			conflicts = append(conflicts, models.UndoConflict{
+				Table:  entry.Table,
+				Key:    keyString,
+				Column: entry.Column,
+				Reason: fmt.Sprintf("value has changed since the fix (now '%s')", current.String),
+			})
+			conflicting[i] = true
+		}
+	}
+
+	return conflicts, conflicting, nil
+}
+
+// columnOrKey returns the column an undo conflict check reads
+func columnOrKey(entry models.FixBackupEntry) string {
+	if entry.Operation == fixOperationUpdate {
+		return entry.Column
+	}
+	return entry.KeyColumn
+}
+
+// undoRowCondition returns the condition matching a backed up row by its key. Quarantine
+// copies are also matched by the run that made them, since earlier runs may have
+// quarantined rows with the same key.
+func (db *DB) undoRowCondition(entry models.FixBackupEntry, runID string, key interface{}) (string, []interface{}) {
+	if entry.RunIDColumn == "" {
+		return fmt.Sprintf("%s = %s", db.quoteIdentifier(entry.KeyColumn), db.dialect.GetPlaceholder(1)), []interface{}{key}
+	}
+	return fmt.Sprintf("%s = %s AND %s = %s",
+		db.quoteIdentifier(entry.RunIDColumn), db.dialect.GetPlaceholder(1),
+		db.quoteIdentifier(entry.KeyColumn), db.dialect.GetPlaceholder(2)), []interface{}{runID, key}
+}
+
+// matchesFixValue reports whether a column still holds the value the fix assigned
+func matchesFixValue(current sql.NullString, value interface{}) bool {
+	if value == nil {
+		return !current.Valid
+	}
+	return current.Valid && current.String == fmt.Sprint(value)
+}
+
+// restoreBackupEntry re-inserts deleted rows, reverts an updated column or deletes inserted
+// rows, skipping the rows in skip. Every row must match exactly one row of the table, so a
+// key that is not unique fails the restore instead of changing other rows.
+func (db *DB) restoreBackupEntry(tx *sql.Tx, entry models.FixBackupEntry, runID string, rows []map[string]interface{}, skip map[int]bool) (int, error) {
+	restored := 0
+	for i, row := range rows {
+		if skip[i] {
+			continue
+		}
+
+		var query string
+		var args []interface{}
+		if entry.Operation == fixOperationDelete {
+			columns := make([]string, 0, len(row))
+			for column := range row {
+				columns = append(columns, column)
+			}
+			sort.Strings(columns)
+
+			quoted := make([]string, len(columns))
+			for j, column := range columns {
+				quoted[j] = db.quoteIdentifier(column)
+				args = append(args, row[column])
+			}
+			query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+				db.quoteIdentifier(entry.Table),
+				strings.Join(quoted, ", "),
+				db.placeholderList(1, len(columns)))
+		} else if entry.Operation == fixOperationInsert {
+			var condition string
+			condition, args = db.undoRowCondition(entry, runID, fmt.Sprint(row[entry.KeyColumn]))
+			query = fmt.Sprintf("DELETE FROM %s WHERE %s", db.quoteIdentifier(entry.Table), condition)
+		} else {
+			query = fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s = %s",
+				db.quoteIdentifier(entry.Table),
+				db.quoteIdentifier(entry.Column),
+				db.dialect.GetPlaceholder(1),
+				db.quoteIdentifier(entry.KeyColumn),
+				db.dialect.GetPlaceholder(2))
+			args = []interface{}{row[entry.Column], fmt.Sprint(row[entry.KeyColumn])}
+		}
+
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return restored, err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return restored, err
+		}
+		if rowsAffected == 0 && entry.Operation == fixOperationUpdate && db.dbType == MySQL {
+			// MySQL counts changed rows only; the conflict check has found this one
+			rowsAffected = 1
+		}
+		if rowsAffected != 1 {
+			return restored, fmt.Errorf("%s %s=%v matched %d rows instead of one", entry.Table, entry.KeyColumn, row[entry.KeyColumn], rowsAffected)
+		}
+		restored++
+	}
+	return restored, nil
+}
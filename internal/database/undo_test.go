@@ -0,0 +1,63 @@
+package database
+
+import (
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+func TestUndoQuarantineDeletesOwnRunOnly(t *testing.T) {
+	for _, dbType := range []DatabaseType{MySQL, PostgreSQL} {
+		t.Run(string(dbType), func(t *testing.T) {
+			baseDir := t.TempDir()
+			manifest := &models.FixRunManifest{
+				RunID:  "run-2",
+				Status: RunStatusCommitted,
+				Entries: []models.FixBackupEntry{{
+					Table:       QuarantineTableName("orders"),
+					Description: "INSERT INTO orders__quarantine rows of orders violating fk_orders_customer",
+					Operation:   fixOperationInsert,
+					KeyColumn:   "id",
+					RunIDColumn: QuarantineRunIDColumn,
+					File:        "001-orders__quarantine.jsonl",
+					RowCount:    1,
+					Status:      EntryStatusCommitted,
+				}},
+			}
+			if err := os.MkdirAll(filepath.Join(baseDir, manifest.RunID), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := SaveFixManifest(baseDir, manifest); err != nil {
+				t.Fatal(err)
+			}
+			backup := filepath.Join(baseDir, manifest.RunID, manifest.Entries[0].File)
+			if err := os.WriteFile(backup, []byte(`{"id":3,"customer_id":7}`+"\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := &recorder{query: func(string, []driver.Value) ([]string, [][]driver.Value) {
+				return []string{"id"}, [][]driver.Value{{"3"}}
+			}}
+			db := newRecordingDB(t, dbType, rec)
+			if _, err := db.UndoFixRun(baseDir, manifest.RunID, false, false); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(rec.execs) != 1 || !strings.HasPrefix(rec.execs[0].Query, "DELETE") {
+				t.Fatalf("got %v, want one delete", rec.execs)
+			}
+			want := []driver.Value{"run-2", "3"}
+			if !reflect.DeepEqual(rec.execs[0].Args, want) {
+				t.Errorf("bound args = %v, want %v", rec.execs[0].Args, want)
+			}
+			if !strings.Contains(rec.execs[0].Query, QuarantineRunIDColumn) {
+				t.Errorf("delete is not restricted to the run:\n%s", rec.execs[0].Query)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package history
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// Kinds of recorded runs
+const (
+	KindValidation = "validation"
+	KindComparison = "comparison"
+)
+
+const (
+	// indexFile lists every recorded run, one JSON line each, so that listing and trends
+	// never read the full results
+	indexFile = "index.jsonl"
+	// runsDir holds the full result of every run in a file named after its ID
+	runsDir = "runs"
+)
+
+// Store is a history of validation and schema comparison runs kept in a directory
+type Store struct {
+	dir string
+}
+
+// NewStore opens the history store in dir. The directory is created when the first run is
+// recorded.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// HashSchema returns a hash identifying a schema, so that runs against different schemas
+// can be told apart
+func HashSchema(schema models.Schema) string {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newRunID returns a sortable, unique ID for a recorded run
+func newRunID(now time.Time) string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return now.Format("20060102T150405.000000Z")
+	}
+	return now.Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// RecordValidation records a validation report
+func (s *Store) RecordValidation(command, connection, schemaHash string, report *models.ValidationReport) (*models.HistoryEntry, error) {
+	entry := newEntry(KindValidation, command, connection, schemaHash)
+	entry.TotalIssues = report.Summary.TotalIssues
+	entry.ErrorCount = report.Summary.ErrorCount
+	entry.WarningCount = report.Summary.WarningCount
+	entry.IssuesByType = report.Summary.IssuesByType
+	return entry, s.record(models.HistoryRun{HistoryEntry: *entry, Report: report})
+}
+
+// RecordComparison records a schema comparison
+func (s *Store) RecordComparison(command, connection, schemaHash string, comparison *models.SchemaComparison) (*models.HistoryEntry, error) {
+	entry := newEntry(KindComparison, command, connection, schemaHash)
+	entry.MissingTables = len(comparison.MissingTables)
+	entry.ExtraTables = len(comparison.ExtraTables)
+	entry.ChangedTables = len(comparison.TableDifferences)
+	entry.TotalIssues = entry.MissingTables + entry.ExtraTables + entry.ChangedTables
+	return entry, s.record(models.HistoryRun{HistoryEntry: *entry, Comparison: comparison})
+}
+
+// newEntry starts the entry of a run recorded now
+func newEntry(kind, command, connection, schemaHash string) *models.HistoryEntry {
+	now := time.Now().UTC()
+	return &models.HistoryEntry{
+		ID:         newRunID(now),
+		Kind:       kind,
+		Command:    command,
+		Connection: connection,
+		SchemaHash: schemaHash,
+		Timestamp:  now.Format(time.RFC3339),
+	}
+}
+
+// record writes the full result of a run, then adds it to the index. A run is only listed
+// once its result is complete.
+func (s *Store) record(run models.HistoryRun) error {
+	if err := os.MkdirAll(filepath.Join(s.dir, runsDir), 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history run: %w", err)
+	}
+	if err := os.WriteFile(s.runPath(run.ID), data, 0600); err != nil {
+		return fmt.Errorf("failed to write history run: %w", err)
+	}
+
+	line, err := json.Marshal(run.HistoryEntry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(s.dir, indexFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history index: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history index: %w", err)
+	}
+	return file.Sync()
+}
+
+// runPath returns the file of the full result of a run
+func (s *Store) runPath(id string) string {
+	return filepath.Join(s.dir, runsDir, id+".json")
+}
+
+// List returns the recorded runs, oldest first. An empty store has no runs.
+func (s *Store) List() ([]models.HistoryEntry, error) {
+	file, err := os.Open(filepath.Join(s.dir, indexFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history index: %w", err)
+	}
+	defer file.Close()
+
+	var entries []models.HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry models.HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse history index line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history index: %w", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp < entries[j].Timestamp })
+	return entries, nil
+}
+
+// Get returns a recorded run with its full result. A unique prefix of the ID is enough.
+func (s *Store) Get(id string) (*models.HistoryRun, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		if entry.ID == id {
+			matches = []string{entry.ID}
+			break
+		}
+		if strings.HasPrefix(entry.ID, id) {
+			matches = append(matches, entry.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("run %s not found in history", id)
+	case 1:
+	default:
+		return nil, fmt.Errorf("run ID %s is ambiguous: it matches %d runs", id, len(matches))
+	}
+
+	data, err := os.ReadFile(s.runPath(matches[0]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history run: %w", err)
+	}
+	var run models.HistoryRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse history run: %w", err)
+	}
+	return &run, nil
+}
+
+// Prune removes recorded runs: every run beyond the keep most recent ones and every run
+// recorded before the given time. A keep of 0 and a zero time do not limit. With dryRun
+// nothing is removed. It returns the removed runs, oldest first.
+func (s *Store) Prune(keep int, before time.Time, dryRun bool) ([]models.HistoryEntry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var kept, removed []models.HistoryEntry
+	for i, entry := range entries {
+		expired := keep > 0 && i < len(entries)-keep
+		if !before.IsZero() {
+			if timestamp, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil && timestamp.Before(before) {
+				expired = true
+			}
+		}
+		if expired {
+			removed = append(removed, entry)
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+	if len(removed) == 0 || dryRun {
+		return removed, nil
+	}
+
+	// Rewrite the index first, so that a run is never listed without its result
+	var index []byte
+	for _, entry := range kept {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal history entry: %w", err)
+		}
+		index = append(append(index, line...), '\n')
+	}
+	indexPath := filepath.Join(s.dir, indexFile)
+	if err := os.WriteFile(indexPath+".tmp", index, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write history index: %w", err)
+	}
+	if err := os.Rename(indexPath+".tmp", indexPath); err != nil {
+		return nil, fmt.Errorf("failed to replace history index: %w", err)
+	}
+
+	for _, entry := range removed {
+		if err := os.Remove(s.runPath(entry.ID)); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove history run %s: %w", entry.ID, err)
+		}
+	}
+	return removed, nil
+}
+
+// Filter returns the entries of one kind and connection. An empty kind matches every kind.
+func Filter(entries []models.HistoryEntry, kind, connection string) []models.HistoryEntry {
+	var filtered []models.HistoryEntry
+	for _, entry := range entries {
+		if (kind == "" || entry.Kind == kind) && entry.Connection == connection {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// Trend turns runs of one kind, oldest first, into series of counts over time. Validation
+// runs give the number of issues, errors, warnings and issues of every type; comparison
+// runs the number of missing, extra and changed tables.
+func Trend(kind, connection string, entries []models.HistoryEntry) *models.HistoryTrend {
+	trend := &models.HistoryTrend{Connection: connection, Kind: kind, Runs: entries}
+	series := func(name string, value func(models.HistoryEntry) int) {
+		values := make([]int, len(entries))
+		for i, entry := range entries {
+			values[i] = value(entry)
+		}
+		trend.Series = append(trend.Series, models.TrendSeries{Name: name, Values: values})
+	}
+
+	if kind == KindComparison {
+		series("Missing tables", func(e models.HistoryEntry) int { return e.MissingTables })
+		series("Extra tables", func(e models.HistoryEntry) int { return e.ExtraTables })
+		series("Changed tables", func(e models.HistoryEntry) int { return e.ChangedTables })
+		return trend
+	}
+
+	series("Issues", func(e models.HistoryEntry) int { return e.TotalIssues })
+	series("Errors", func(e models.HistoryEntry) int { return e.ErrorCount })
+	series("Warnings", func(e models.HistoryEntry) int { return e.WarningCount })
+
+	typeSet := make(map[string]bool)
+	for _, entry := range entries {
+		for issueType := range entry.IssuesByType {
+			typeSet[issueType] = true
+		}
+	}
+	issueTypes := make([]string, 0, len(typeSet))
+	for issueType := range typeSet {
+		issueTypes = append(issueTypes, issueType)
+	}
+	sort.Strings(issueTypes)
+	for _, issueType := range issueTypes {
+		issueType := issueType
+		series(issueType, func(e models.HistoryEntry) int { return e.IssuesByType[issueType] })
+	}
+	return trend
+}
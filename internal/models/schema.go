@@ -12,6 +12,8 @@ type Column struct {
 	NumericPrecision   *int        `json:"NumericPrecision,omitempty"`
 	NumericScale       *int        `json:"NumericScale,omitempty"`
 	DatetimePrecision  *int        `json:"DatetimePrecision,omitempty"`
+	// Comment is the column comment, when the database has one
+	Comment string `json:"Comment,omitempty"`
 }
 
 // GetFullDataType returns the data type with size information
@@ -66,6 +68,56 @@ type Table struct {
 	TableName   string       `json:"TableName"`
 	Columns     []Column     `json:"Columns"`
 	ForeignKeys []ForeignKey `json:"ForeignKeys"`
+	// RowFilter is an optional SQL predicate (e.g. "deleted_at IS NULL") that limits
+	// validation and fixes to the rows it matches
+	RowFilter                 string                     `json:"RowFilter,omitempty"`
+	PolymorphicRelations      []PolymorphicRelation      `json:"PolymorphicRelations,omitempty"`
+	CrossConnectionReferences []CrossConnectionReference `json:"CrossConnectionReferences,omitempty"`
+	// Comment is the table comment, when the database has one
+	Comment string `json:"Comment,omitempty"`
+}
+
+// CrossConnectionReference represents a foreign key whose referenced table lives in
+// another database, identified by a named connection from the configuration
+type CrossConnectionReference struct {
+	ConstraintName   string `json:"ConstraintName"`
+	ColumnName       string `json:"ColumnName"`
+	Connection       string `json:"Connection"`
+	ReferencedTable  string `json:"ReferencedTable"`
+	ReferencedColumn string `json:"ReferencedColumn"`
+}
+
+// PolymorphicRelation represents a Rails-style polymorphic reference, where the value
+// of TypeColumn selects the table that IDColumn points to
+type PolymorphicRelation struct {
+	RelationName string              `json:"RelationName"`
+	TypeColumn   string              `json:"TypeColumn"`
+	IDColumn     string              `json:"IdColumn"`
+	Targets      []PolymorphicTarget `json:"Targets"`
+}
+
+// PolymorphicTarget maps a type column value to the table it references
+type PolymorphicTarget struct {
+	TypeValue        string `json:"TypeValue"`
+	ReferencedTable  string `json:"ReferencedTable"`
+	ReferencedColumn string `json:"ReferencedColumn,omitempty"`
+}
+
+// GetReferencedColumn returns the referenced column, defaulting to "id"
+func (t PolymorphicTarget) GetReferencedColumn() string {
+	if t.ReferencedColumn == "" {
+		return "id"
+	}
+	return t.ReferencedColumn
+}
+
+// GetTypeValues returns the type values mapped by the relation
+func (r PolymorphicRelation) GetTypeValues() []string {
+	values := make([]string, 0, len(r.Targets))
+	for _, target := range r.Targets {
+		values = append(values, target.TypeValue)
+	}
+	return values
 }
 
 // Schema represents the complete database schema
@@ -81,14 +133,44 @@ type ValidationIssue struct {
 	PrimaryKey string                 `json:"primary_key,omitempty" yaml:"primary_key,omitempty"`
 	Identifier string                 `json:"identifier,omitempty" yaml:"identifier,omitempty"`
 	Details    map[string]interface{} `json:"details,omitempty" yaml:"details,omitempty"`
+	// Check is the validate check that found the issue, e.g. foreign_keys
+	Check string `json:"check,omitempty" yaml:"check,omitempty"`
 }
 
 // ValidationReport represents a collection of validation issues
 type ValidationReport struct {
-	ConnectionName string            `json:"connection_name" yaml:"connection_name"`
-	Timestamp      string            `json:"timestamp" yaml:"timestamp"`
-	Issues         []ValidationIssue `json:"issues" yaml:"issues"`
-	Summary        ReportSummary     `json:"summary" yaml:"summary"`
+	ConnectionName string                   `json:"connection_name" yaml:"connection_name"`
+	Timestamp      string                   `json:"timestamp" yaml:"timestamp"`
+	Issues         []ValidationIssue        `json:"issues" yaml:"issues"`
+	Summary        ReportSummary            `json:"summary" yaml:"summary"`
+	Tables         []TableValidationSummary `json:"tables,omitempty" yaml:"tables,omitempty"`
+}
+
+// TableValidationSummary summarizes the validation of one table, so that a few violating
+// rows in a large table can be told apart from a table that is mostly broken
+type TableValidationSummary struct {
+	Table string `json:"table" yaml:"table"`
+	// TotalRows is the number of rows matching the row filter of the table, if it could be
+	// counted
+	TotalRows        *int64  `json:"total_rows" yaml:"total_rows"`
+	ViolatingRows    int     `json:"violating_rows" yaml:"violating_rows"`
+	ViolationPercent float64 `json:"violation_percent" yaml:"violation_percent"`
+	// ViolatingRowsLowerBound is set when the violating rows could not be counted exactly
+	// and were taken from issues that reached the per-table issue limit
+	ViolatingRowsLowerBound bool         `json:"violating_rows_lower_bound,omitempty" yaml:"violating_rows_lower_bound,omitempty"`
+	Issues                  int          `json:"issues" yaml:"issues"`
+	Seconds                 float64      `json:"seconds" yaml:"seconds"`
+	Checks                  []TableCheck `json:"checks" yaml:"checks"`
+}
+
+// TableCheck is the result of one check on one table
+type TableCheck struct {
+	Check         string  `json:"check" yaml:"check"`
+	ViolatingRows int     `json:"violating_rows" yaml:"violating_rows"`
+	Seconds       float64 `json:"seconds" yaml:"seconds"`
+	// ViolatingRowsLowerBound is set when the violating rows could not be counted exactly
+	// and were taken from issues that reached the per-table issue limit
+	ViolatingRowsLowerBound bool `json:"violating_rows_lower_bound,omitempty" yaml:"violating_rows_lower_bound,omitempty"`
 }
 
 // ReportSummary provides statistics about validation results
@@ -98,6 +180,118 @@ type ReportSummary struct {
 	WarningCount  int            `json:"warning_count" yaml:"warning_count"`
 	TablesCovered int            `json:"tables_covered" yaml:"tables_covered"`
 	IssuesByType  map[string]int `json:"issues_by_type" yaml:"issues_by_type"`
+	IssueCounts   []IssueCount   `json:"issue_counts,omitempty" yaml:"issue_counts,omitempty"`
+}
+
+// IssueCount counts the issues of one type and severity in one table
+type IssueCount struct {
+	Type     string `json:"type" yaml:"type"`
+	Severity string `json:"severity" yaml:"severity"`
+	Table    string `json:"table" yaml:"table"`
+	Count    int    `json:"count" yaml:"count"`
+}
+
+// CheckDuration is how long one check of a validation run took
+type CheckDuration struct {
+	Check   string  `json:"check" yaml:"check"`
+	Seconds float64 `json:"seconds" yaml:"seconds"`
+}
+
+// RunMetrics holds what a validation run exports as Prometheus metrics
+type RunMetrics struct {
+	Connection string
+	Timestamp  string
+	Summary    ReportSummary
+	Checks     []CheckDuration
+	// Tables are the tables checked by the run, with the checks that ran on each
+	Tables []TableValidationSummary
+	// Comparison is the drift of the database from the target schema, if it was compared
+	Comparison *SchemaComparison
+}
+
+// Trends of a report diff
+const (
+	TrendImproving = "improving"
+	TrendWorsening = "worsening"
+	TrendUnchanged = "unchanged"
+)
+
+// ReportDiff compares an older validation report with a newer one
+type ReportDiff struct {
+	Old             ReportDiffSide    `json:"old" yaml:"old"`
+	New             ReportDiffSide    `json:"new" yaml:"new"`
+	Summary         ReportDiffSummary `json:"summary" yaml:"summary"`
+	ByType          []CountDelta      `json:"by_type" yaml:"by_type"`
+	ByTable         []CountDelta      `json:"by_table" yaml:"by_table"`
+	NewIssues       []ValidationIssue `json:"new_issues" yaml:"new_issues"`
+	ResolvedIssues  []ValidationIssue `json:"resolved_issues" yaml:"resolved_issues"`
+	UnchangedIssues []ValidationIssue `json:"unchanged_issues" yaml:"unchanged_issues"`
+}
+
+// ReportDiffSide describes one of the compared validation reports
+type ReportDiffSide struct {
+	File           string        `json:"file" yaml:"file"`
+	ConnectionName string        `json:"connection_name" yaml:"connection_name"`
+	Timestamp      string        `json:"timestamp" yaml:"timestamp"`
+	Summary        ReportSummary `json:"summary" yaml:"summary"`
+}
+
+// ReportDiffSummary counts the changes between two validation reports
+type ReportDiffSummary struct {
+	NewIssues       int    `json:"new_issues" yaml:"new_issues"`
+	ResolvedIssues  int    `json:"resolved_issues" yaml:"resolved_issues"`
+	UnchangedIssues int    `json:"unchanged_issues" yaml:"unchanged_issues"`
+	TotalDelta      int    `json:"total_delta" yaml:"total_delta"`
+	ErrorDelta      int    `json:"error_delta" yaml:"error_delta"`
+	WarningDelta    int    `json:"warning_delta" yaml:"warning_delta"`
+	Trend           string `json:"trend" yaml:"trend"`
+}
+
+// CountDelta is the change of an issue count, such as the issues of one type or table
+type CountDelta struct {
+	Name  string `json:"name" yaml:"name"`
+	Old   int    `json:"old" yaml:"old"`
+	New   int    `json:"new" yaml:"new"`
+	Delta int    `json:"delta" yaml:"delta"`
+}
+
+// HistoryEntry describes one recorded validation or schema comparison run
+type HistoryEntry struct {
+	ID           string         `json:"id" yaml:"id"`
+	Kind         string         `json:"kind" yaml:"kind"`
+	Command      string         `json:"command" yaml:"command"`
+	Connection   string         `json:"connection" yaml:"connection"`
+	SchemaHash   string         `json:"schema_hash" yaml:"schema_hash"`
+	Timestamp    string         `json:"timestamp" yaml:"timestamp"`
+	TotalIssues  int            `json:"total_issues" yaml:"total_issues"`
+	ErrorCount   int            `json:"error_count" yaml:"error_count"`
+	WarningCount int            `json:"warning_count" yaml:"warning_count"`
+	IssuesByType map[string]int `json:"issues_by_type,omitempty" yaml:"issues_by_type,omitempty"`
+	// Counts of schema comparison runs
+	MissingTables int `json:"missing_tables,omitempty" yaml:"missing_tables,omitempty"`
+	ExtraTables   int `json:"extra_tables,omitempty" yaml:"extra_tables,omitempty"`
+	ChangedTables int `json:"changed_tables,omitempty" yaml:"changed_tables,omitempty"`
+}
+
+// HistoryRun is a recorded run with its full result
+type HistoryRun struct {
+	HistoryEntry `yaml:",inline"`
+	Report       *ValidationReport `json:"report,omitempty" yaml:"report,omitempty"`
+	Comparison   *SchemaComparison `json:"comparison,omitempty" yaml:"comparison,omitempty"`
+}
+
+// HistoryTrend holds counts of recorded runs over time, oldest first
+type HistoryTrend struct {
+	Connection string         `json:"connection" yaml:"connection"`
+	Kind       string         `json:"kind" yaml:"kind"`
+	Runs       []HistoryEntry `json:"runs" yaml:"runs"`
+	Series     []TrendSeries  `json:"series" yaml:"series"`
+}
+
+// TrendSeries is one count over the runs of a trend, such as the number of errors
+type TrendSeries struct {
+	Name   string `json:"name" yaml:"name"`
+	Values []int  `json:"values" yaml:"values"`
 }
 
 // SchemaInfo represents schema information for display
@@ -156,6 +350,81 @@ func (s Schema) GetTable(tableName string) *Table {
 	return nil
 }
 
+// OrderedByDependency returns the tables with every table after the tables it references,
+// so parents are handled before their children. Self-references are ignored and cycles are
+// broken in schema order.
+func (s Schema) OrderedByDependency() Schema {
+	index := make(map[string]int, len(s))
+	for i, table := range s {
+		index[table.TableName] = i
+	}
+
+	ordered := make(Schema, 0, len(s))
+	state := make(map[int]int, len(s)) // 1 = visiting, 2 = done
+	var visit func(i int)
+	visit = func(i int) {
+		if state[i] != 0 {
+			return
+		}
+		state[i] = 1
+		for _, fk := range s[i].ForeignKeys {
+			if parent, ok := index[fk.ReferencedTable]; ok && parent != i {
+				visit(parent)
+			}
+		}
+		state[i] = 2
+		ordered = append(ordered, s[i])
+	}
+	for i := range s {
+		visit(i)
+	}
+	return ordered
+}
+
+// Neighborhood returns the given tables and every table within depth foreign key hops of
+// them, following references in both directions, in schema order
+func (s Schema) Neighborhood(tableNames []string, depth int) (Schema, error) {
+	neighbors := make(map[string][]string)
+	for _, table := range s {
+		for _, fk := range table.ForeignKeys {
+			neighbors[table.TableName] = append(neighbors[table.TableName], fk.ReferencedTable)
+			neighbors[fk.ReferencedTable] = append(neighbors[fk.ReferencedTable], table.TableName)
+		}
+	}
+
+	included := make(map[string]bool)
+	var frontier []string
+	for _, tableName := range tableNames {
+		if s.GetTable(tableName) == nil {
+			return nil, fmt.Errorf("table %s is not in the schema", tableName)
+		}
+		if !included[tableName] {
+			included[tableName] = true
+			frontier = append(frontier, tableName)
+		}
+	}
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		var next []string
+		for _, tableName := range frontier {
+			for _, neighbor := range neighbors[tableName] {
+				if !included[neighbor] {
+					included[neighbor] = true
+					next = append(next, neighbor)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	var neighborhood Schema
+	for _, table := range s {
+		if included[table.TableName] {
+			neighborhood = append(neighborhood, table)
+		}
+	}
+	return neighborhood, nil
+}
+
 // GetColumn returns a column by name from the table
 func (t *Table) GetColumn(columnName string) *Column {
 	for _, column := range t.Columns {
@@ -188,6 +457,19 @@ func (t *Table) GetPrimaryKeyColumns() []Column {
 	return pkColumns
 }
 
+// ForeignKeySuggestion represents an undeclared relationship inferred from naming
+// conventions, column types and the data itself
+type ForeignKeySuggestion struct {
+	ForeignKey     ForeignKey `json:"foreign_key" yaml:"foreign_key"`
+	Confidence     float64    `json:"confidence" yaml:"confidence"`
+	NameScore      float64    `json:"name_score" yaml:"name_score"`
+	TypeScore      float64    `json:"type_score" yaml:"type_score"`
+	MatchRate      float64    `json:"match_rate" yaml:"match_rate"`
+	DistinctValues int64      `json:"distinct_values" yaml:"distinct_values"`
+	MatchedValues  int64      `json:"matched_values" yaml:"matched_values"`
+	Reason         string     `json:"reason" yaml:"reason"`
+}
+
 // FixResult represents the result of a fix operation
 type FixResult struct {
 	IssuesFound     int    `json:"issues_found"`
@@ -195,7 +477,177 @@ type FixResult struct {
 	Success         bool   `json:"success"`
 	Error           string `json:"error,omitempty"`
 	Details         string `json:"details,omitempty"`
+	// RolledBack is set when changes to this table were undone by a transaction rollback
+	RolledBack       bool     `json:"rolled_back,omitempty"`
+	UndoneStatements []string `json:"undone_statements,omitempty"`
+	// Impact lists, for dry runs, the rows of other tables the fix would change or be
+	// blocked by through foreign key ON DELETE / ON UPDATE rules
+	Impact []CascadeImpact `json:"impact,omitempty"`
+}
+
+// CascadeImpact counts the rows of one table affected by the referential actions a fix
+// triggers
+type CascadeImpact struct {
+	Table   string `json:"table"`
+	Deleted int64  `json:"deleted,omitempty"`
+	Nulled  int64  `json:"nulled,omitempty"`
+	// Updated rows have their key changed by ON UPDATE CASCADE or reset by SET DEFAULT
+	Updated int64 `json:"updated,omitempty"`
+	// Blocked rows still reference changed rows through a RESTRICT / NO ACTION foreign
+	// key, which makes the fix fail
+	Blocked   int64    `json:"blocked,omitempty"`
+	BlockedBy []string `json:"blocked_by,omitempty"`
 }
 
 // FixResults represents results for multiple tables
 type FixResults map[string]FixResult
+
+// FixRunManifest describes a modifying fix run and where the rows it changed were backed up
+type FixRunManifest struct {
+	RunID string `json:"run_id" yaml:"run_id"`
+	// PlanID is the run ID of the fix plan the run applied
+	PlanID       string           `json:"plan_id,omitempty" yaml:"plan_id,omitempty"`
+	Connection   string           `json:"connection" yaml:"connection"`
+	Database     string           `json:"database" yaml:"database"`
+	Command      string           `json:"command" yaml:"command"`
+	Action       string           `json:"action" yaml:"action"`
+	CommitPolicy string           `json:"commit_policy" yaml:"commit_policy"`
+	StartedAt    string           `json:"started_at" yaml:"started_at"`
+	FinishedAt   string           `json:"finished_at,omitempty" yaml:"finished_at,omitempty"`
+	UndoneAt     string           `json:"undone_at,omitempty" yaml:"undone_at,omitempty"`
+	Status       string           `json:"status" yaml:"status"`
+	Entries      []FixBackupEntry `json:"entries" yaml:"entries"`
+}
+
+// FixBackupEntry describes one fix statement and the file holding the rows it changed,
+// as they were before the change
+type FixBackupEntry struct {
+	Table       string      `json:"table" yaml:"table"`
+	Description string      `json:"description" yaml:"description"`
+	Operation   string      `json:"operation" yaml:"operation"`
+	Column      string      `json:"column,omitempty" yaml:"column,omitempty"`
+	Value       interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+	Expression  string      `json:"expression,omitempty" yaml:"expression,omitempty"`
+	KeyColumn   string      `json:"key_column" yaml:"key_column"`
+	File        string      `json:"file" yaml:"file"`
+	RowCount    int         `json:"row_count" yaml:"row_count"`
+	Status      string      `json:"status" yaml:"status"`
+	// RunIDColumn holds the run ID on the rows a quarantine copy inserted; undo only
+	// deletes the rows of its own run
+	RunIDColumn string `json:"run_id_column,omitempty" yaml:"run_id_column,omitempty"`
+}
+
+// FixRunPlan records the operations a fix would run, so that exactly the reviewed
+// operations are executed later by 'fix apply'
+type FixRunPlan struct {
+	Version int `json:"version" yaml:"version"`
+	// RunID identifies the plan; every apply runs under a run ID of its own and records
+	// this one as its plan ID
+	RunID        string `json:"run_id" yaml:"run_id"`
+	CreatedAt    string `json:"created_at" yaml:"created_at"`
+	Connection   string `json:"connection" yaml:"connection"`
+	Database     string `json:"database" yaml:"database"`
+	DatabaseType string `json:"database_type" yaml:"database_type"`
+	Command      string `json:"command" yaml:"command"`
+	Action       string `json:"action" yaml:"action"`
+	// SchemaHash and ConfigHash are SHA-256 hashes of the target schema file and of the
+	// validation configuration (including row filters) the plan was made with
+	SchemaHash string             `json:"schema_hash" yaml:"schema_hash"`
+	ConfigHash string             `json:"config_hash" yaml:"config_hash"`
+	Operations []PlannedOperation `json:"operations" yaml:"operations"`
+	Results    FixResults         `json:"results" yaml:"results"`
+}
+
+// PlannedOperation is one statement of a fix plan with the rows it changes
+type PlannedOperation struct {
+	Table           string        `json:"table" yaml:"table"`
+	ResultTable     string        `json:"result_table,omitempty" yaml:"result_table,omitempty"`
+	Description     string        `json:"description" yaml:"description"`
+	Operation       string        `json:"operation" yaml:"operation"`
+	KeyColumn       string        `json:"key_column,omitempty" yaml:"key_column,omitempty"`
+	Columns         []string      `json:"columns,omitempty" yaml:"columns,omitempty"`
+	Source          string        `json:"source,omitempty" yaml:"source,omitempty"`
+	Column          string        `json:"column,omitempty" yaml:"column,omitempty"`
+	Value           interface{}   `json:"value,omitempty" yaml:"value,omitempty"`
+	Expression      string        `json:"expression,omitempty" yaml:"expression,omitempty"`
+	Where           string        `json:"where,omitempty" yaml:"where,omitempty"`
+	Args            []interface{} `json:"args,omitempty" yaml:"args,omitempty"`
+	Uncounted       bool          `json:"uncounted,omitempty" yaml:"uncounted,omitempty"`
+	SingleStatement bool          `json:"single_statement,omitempty" yaml:"single_statement,omitempty"`
+	// RowCount is the number of rows the operation changes
+	RowCount int64 `json:"row_count" yaml:"row_count"`
+	// Keys are the key values of the deleted or updated rows, or of the inserted rows
+	Keys []string `json:"keys,omitempty" yaml:"keys,omitempty"`
+	// Exact is false when earlier operations of the plan change tables this operation
+	// reads, so that it may change fewer rows than recorded
+	Exact bool `json:"exact" yaml:"exact"`
+}
+
+// AuditRecord describes one run that modified data: who ran what, where, and with which
+// outcome
+type AuditRecord struct {
+	RunID string `json:"run_id" yaml:"run_id"`
+	// PlanID is the run ID of the fix plan the run applied
+	PlanID       string           `json:"plan_id,omitempty" yaml:"plan_id,omitempty"`
+	StartedAt    string           `json:"started_at" yaml:"started_at"`
+	FinishedAt   string           `json:"finished_at" yaml:"finished_at"`
+	DurationMs   int64            `json:"duration_ms" yaml:"duration_ms"`
+	User         string           `json:"user" yaml:"user"`
+	Host         string           `json:"host" yaml:"host"`
+	Connection   string           `json:"connection" yaml:"connection"`
+	Database     string           `json:"database" yaml:"database"`
+	Command      string           `json:"command" yaml:"command"`
+	CommandLine  string           `json:"command_line" yaml:"command_line"`
+	Action       string           `json:"action,omitempty" yaml:"action,omitempty"`
+	Outcome      string           `json:"outcome" yaml:"outcome"`
+	Error        string           `json:"error,omitempty" yaml:"error,omitempty"`
+	RowsAffected int64            `json:"rows_affected" yaml:"rows_affected"`
+	Statements   []AuditStatement `json:"statements,omitempty" yaml:"statements,omitempty"`
+}
+
+// AuditStatement is one statement executed by an audited run
+type AuditStatement struct {
+	Table       string `json:"table" yaml:"table"`
+	Description string `json:"description" yaml:"description"`
+	SQL         string `json:"sql,omitempty" yaml:"sql,omitempty"`
+	Rows        int64  `json:"rows" yaml:"rows"`
+	Error       string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// UndoResult represents the outcome of undoing a fix run
+type UndoResult struct {
+	RunID     string         `json:"run_id" yaml:"run_id"`
+	DryRun    bool           `json:"dry_run" yaml:"dry_run"`
+	Restored  map[string]int `json:"restored" yaml:"restored"`
+	Conflicts []UndoConflict `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+}
+
+// UndoConflict is a backed up row that can no longer be restored safely because the data
+// has changed since the fix
+type UndoConflict struct {
+	Table  string `json:"table" yaml:"table"`
+	Key    string `json:"key" yaml:"key"`
+	Column string `json:"column,omitempty" yaml:"column,omitempty"`
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+// QuarantineEntry summarizes the rows of a quarantine table moved there by one fix run for
+// one constraint
+type QuarantineEntry struct {
+	Table            string `json:"table" yaml:"table"`
+	SourceTable      string `json:"source_table" yaml:"source_table"`
+	RunID            string `json:"run_id" yaml:"run_id"`
+	Constraint       string `json:"constraint" yaml:"constraint"`
+	Reason           string `json:"reason" yaml:"reason"`
+	Rows             int64  `json:"rows" yaml:"rows"`
+	FirstQuarantined string `json:"first_quarantined" yaml:"first_quarantined"`
+	LastQuarantined  string `json:"last_quarantined" yaml:"last_quarantined"`
+}
+
+// QuarantineRestoreResult represents the outcome of moving quarantined rows back
+type QuarantineRestoreResult struct {
+	Table        string   `json:"table" yaml:"table"`
+	DryRun       bool     `json:"dry_run" yaml:"dry_run"`
+	Restored     int64    `json:"restored" yaml:"restored"`
+	ConflictKeys []string `json:"conflict_keys,omitempty" yaml:"conflict_keys,omitempty"`
+}
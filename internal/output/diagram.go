@@ -0,0 +1,334 @@
+package output
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// Diagram formats
+const (
+	FormatMermaid  OutputFormat = "mermaid"
+	FormatDOT      OutputFormat = "dot"
+	FormatPlantUML OutputFormat = "plantuml"
+)
+
+// Kinds of highlighted tables in a schema diagram
+const (
+	HighlightMissing = "missing"
+	HighlightExtra   = "extra"
+	HighlightChanged = "changed"
+)
+
+// highlightColors are the fill colors of highlighted tables
+var highlightColors = map[string]string{
+	HighlightMissing: "#f8d7da",
+	HighlightExtra:   "#fff3cd",
+	HighlightChanged: "#d6e9f8",
+}
+
+// DiagramOptions controls what a schema diagram shows
+type DiagramOptions struct {
+	// Highlight maps table names to the kind of difference they are highlighted with
+	Highlight map[string]string
+	// HideColumns draws tables without their columns
+	HideColumns bool
+}
+
+// diagramTable is a table of a diagram with the markers of its columns
+type diagramTable struct {
+	models.Table
+	primaryKeys map[string]bool
+	foreignKeys map[string]bool
+}
+
+// diagramEdge is a foreign key between two tables of a diagram
+type diagramEdge struct {
+	models.ForeignKey
+	// optional is true when the referencing column is nullable
+	optional bool
+}
+
+// FormatSchemaDiagram renders a schema as an entity-relationship diagram in Mermaid,
+// Graphviz DOT or PlantUML. The table format, the default, is rendered as Mermaid.
+// Foreign keys to tables outside the schema are left out.
+func (f *Formatter) FormatSchemaDiagram(schema models.Schema, opts DiagramOptions) (string, error) {
+	tables, edges := diagramGraph(schema)
+	switch f.format {
+	case FormatMermaid, FormatTable:
+		return formatMermaidDiagram(tables, edges, opts), nil
+	case FormatDOT:
+		return formatDOTDiagram(tables, edges, opts), nil
+	case FormatPlantUML:
+		return formatPlantUMLDiagram(tables, edges, opts), nil
+	default:
+		return "", fmt.Errorf("unsupported output format for schema diagram: %s (mermaid, dot and plantuml supported)", f.format)
+	}
+}
+
+// diagramGraph collects the tables of a schema with their key markers, and the foreign
+// keys between them
+func diagramGraph(schema models.Schema) ([]diagramTable, []diagramEdge) {
+	inSchema := make(map[string]bool, len(schema))
+	for _, table := range schema {
+		inSchema[table.TableName] = true
+	}
+
+	var tables []diagramTable
+	var edges []diagramEdge
+	for _, table := range schema {
+		dt := diagramTable{Table: table, primaryKeys: make(map[string]bool), foreignKeys: make(map[string]bool)}
+		for _, column := range table.GetPrimaryKeyColumns() {
+			dt.primaryKeys[column.ColumnName] = true
+		}
+		for _, fk := range table.ForeignKeys {
+			if fk.TableName == "" {
+				fk.TableName = table.TableName
+			}
+			dt.foreignKeys[fk.ColumnName] = true
+			if !inSchema[fk.ReferencedTable] {
+				continue
+			}
+			edge := diagramEdge{ForeignKey: fk}
+			if column := table.GetColumn(fk.ColumnName); column != nil && !column.IsNotNull() {
+				edge.optional = true
+			}
+			edges = append(edges, edge)
+		}
+		tables = append(tables, dt)
+	}
+	return tables, edges
+}
+
+// columnMarkers returns the key markers of a column, such as PK or FK
+func (t diagramTable) columnMarkers(columnName string) []string {
+	var markers []string
+	if t.primaryKeys[columnName] {
+		markers = append(markers, "PK")
+	}
+	if t.foreignKeys[columnName] {
+		markers = append(markers, "FK")
+	}
+	return markers
+}
+
+// unsafeIdentifierChars matches the characters diagram identifiers cannot contain
+var unsafeIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// uniqueIdentifiers gives every name an identifier made of letters, digits and
+// underscores, unique among the names
+func uniqueIdentifiers(names []string) map[string]string {
+	ids := make(map[string]string, len(names))
+	used := make(map[string]bool, len(names))
+	for _, name := range names {
+		id := unsafeIdentifierChars.ReplaceAllString(name, "_")
+		if id == "" || (id[0] >= '0' && id[0] <= '9') {
+			id = "t_" + id
+		}
+		for base, n := id, 2; used[id]; n++ {
+			id = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[id] = true
+		ids[name] = id
+	}
+	return ids
+}
+
+// diagramIdentifiers gives every table of a diagram a unique identifier
+func diagramIdentifiers(tables []diagramTable) map[string]string {
+	names := make([]string, len(tables))
+	for i, table := range tables {
+		names[i] = table.TableName
+	}
+	return uniqueIdentifiers(names)
+}
+
+// mermaidTypeChars matches the characters a Mermaid attribute type cannot contain
+var mermaidTypeChars = regexp.MustCompile(`[^A-Za-z0-9_()\[\]-]`)
+
+// formatMermaidDiagram renders the diagram as a Mermaid erDiagram
+func formatMermaidDiagram(tables []diagramTable, edges []diagramEdge, opts DiagramOptions) string {
+	ids := diagramIdentifiers(tables)
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+
+	for _, table := range tables {
+		id := ids[table.TableName]
+		if id != table.TableName {
+			b.WriteString(fmt.Sprintf("    %s[\"%s\"]", id, strings.ReplaceAll(table.TableName, `"`, "'")))
+		} else {
+			b.WriteString("    " + id)
+		}
+		if opts.HideColumns || len(table.Columns) == 0 {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(" {\n")
+		for _, column := range table.Columns {
+			dataType := mermaidTypeChars.ReplaceAllString(column.GetFullDataType(), "_")
+			line := fmt.Sprintf("        %s %s", dataType, unsafeIdentifierChars.ReplaceAllString(column.ColumnName, "_"))
+			if markers := table.columnMarkers(column.ColumnName); len(markers) > 0 {
+				line += " " + strings.Join(markers, ",")
+			}
+			if !column.IsNotNull() {
+				line += ` "nullable"`
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("    }\n")
+	}
+
+	for _, edge := range edges {
+		// The referenced table has exactly one row per reference, or none for a nullable column
+		parent := "||"
+		if edge.optional {
+			parent = "|o"
+		}
+		b.WriteString(fmt.Sprintf("    %s %s--o{ %s : \"%s\"\n",
+			ids[edge.ReferencedTable], parent, ids[edge.TableName], strings.ReplaceAll(edge.ColumnName, `"`, "'")))
+	}
+
+	if len(opts.Highlight) > 0 {
+		for _, kind := range []string{HighlightMissing, HighlightExtra, HighlightChanged} {
+			var highlighted []string
+			for _, table := range tables {
+				if opts.Highlight[table.TableName] == kind {
+					highlighted = append(highlighted, ids[table.TableName])
+				}
+			}
+			if len(highlighted) > 0 {
+				b.WriteString(fmt.Sprintf("    classDef %s fill:%s\n", kind, highlightColors[kind]))
+				b.WriteString(fmt.Sprintf("    class %s %s\n", strings.Join(highlighted, ","), kind))
+			}
+		}
+	}
+	return b.String()
+}
+
+// escapeDOTLabel escapes text for an HTML-like Graphviz label
+func escapeDOTLabel(text string) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+	return replacer.Replace(text)
+}
+
+// formatDOTDiagram renders the diagram as a Graphviz DOT digraph, with foreign keys
+// pointing from the referencing column to the referenced column
+func formatDOTDiagram(tables []diagramTable, edges []diagramEdge, opts DiagramOptions) string {
+	ids := diagramIdentifiers(tables)
+	ports := make(map[string]map[string]string, len(tables))
+
+	var b strings.Builder
+	b.WriteString("digraph schema {\n")
+	b.WriteString("    graph [rankdir=LR, fontname=\"Helvetica\"];\n")
+	b.WriteString("    node [shape=plaintext, fontname=\"Helvetica\", fontsize=10];\n")
+	b.WriteString("    edge [fontname=\"Helvetica\", fontsize=9, color=\"#607080\"];\n\n")
+
+	for _, table := range tables {
+		header := "#dce6f0"
+		if color, ok := highlightColors[opts.Highlight[table.TableName]]; ok {
+			header = color
+		}
+		ports[table.TableName] = make(map[string]string)
+
+		b.WriteString(fmt.Sprintf("    %s [label=<<table border=\"0\" cellborder=\"1\" cellspacing=\"0\" cellpadding=\"4\">\n", ids[table.TableName]))
+		title := "<b>" + escapeDOTLabel(table.TableName) + "</b>"
+		if kind := opts.Highlight[table.TableName]; kind != "" {
+			title += " <i>(" + kind + ")</i>"
+		}
+		b.WriteString(fmt.Sprintf("        <tr><td bgcolor=\"%s\" colspan=\"2\">%s</td></tr>\n", header, title))
+		if !opts.HideColumns {
+			for i, column := range table.Columns {
+				port := fmt.Sprintf("c%d", i)
+				ports[table.TableName][column.ColumnName] = port
+				name := escapeDOTLabel(column.ColumnName)
+				if markers := table.columnMarkers(column.ColumnName); len(markers) > 0 {
+					name += " <i>" + strings.Join(markers, ",") + "</i>"
+				}
+				dataType := escapeDOTLabel(column.GetFullDataType())
+				if !column.IsNotNull() {
+					dataType += "?"
+				}
+				b.WriteString(fmt.Sprintf("        <tr><td port=\"%s\" align=\"left\">%s</td><td align=\"left\">%s</td></tr>\n", port, name, dataType))
+			}
+		}
+		b.WriteString("    </table>>];\n")
+	}
+
+	b.WriteString("\n")
+	for _, edge := range edges {
+		from, to := ids[edge.TableName], ids[edge.ReferencedTable]
+		if port, ok := ports[edge.TableName][edge.ColumnName]; ok {
+			from += ":" + port
+		}
+		if port, ok := ports[edge.ReferencedTable][edge.ReferencedColumn]; ok {
+			to += ":" + port
+		}
+		style := ""
+		if edge.optional {
+			style = ", style=dashed"
+		}
+		b.WriteString(fmt.Sprintf("    %s -> %s [label=\"%s\"%s];\n", from, to, escapeDOTLabel(edge.ConstraintName), style))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// formatPlantUMLDiagram renders the diagram as a PlantUML entity-relationship diagram
+func formatPlantUMLDiagram(tables []diagramTable, edges []diagramEdge, opts DiagramOptions) string {
+	ids := diagramIdentifiers(tables)
+	var b strings.Builder
+	b.WriteString("@startuml\n")
+	b.WriteString("hide circle\n")
+	b.WriteString("skinparam linetype ortho\n\n")
+
+	for _, table := range tables {
+		line := fmt.Sprintf("entity \"%s\" as %s", strings.ReplaceAll(table.TableName, `"`, "'"), ids[table.TableName])
+		if color, ok := highlightColors[opts.Highlight[table.TableName]]; ok {
+			line += " " + color
+		}
+		b.WriteString(line + " {\n")
+		if !opts.HideColumns {
+			// Key columns go above the separator
+			var keys, others []models.Column
+			for _, column := range table.Columns {
+				if table.primaryKeys[column.ColumnName] {
+					keys = append(keys, column)
+				} else {
+					others = append(others, column)
+				}
+			}
+			writeColumn := func(column models.Column) {
+				prefix := "  "
+				if column.IsNotNull() {
+					prefix = "  * "
+				}
+				line := fmt.Sprintf("%s%s : %s", prefix, column.ColumnName, column.GetFullDataType())
+				for _, marker := range table.columnMarkers(column.ColumnName) {
+					line += " <<" + marker + ">>"
+				}
+				b.WriteString(line + "\n")
+			}
+			for _, column := range keys {
+				writeColumn(column)
+			}
+			b.WriteString("  --\n")
+			for _, column := range others {
+				writeColumn(column)
+			}
+		}
+		b.WriteString("}\n")
+	}
+
+	b.WriteString("\n")
+	for _, edge := range edges {
+		parent := "||"
+		if edge.optional {
+			parent = "|o"
+		}
+		b.WriteString(fmt.Sprintf("%s }o--%s %s : %s\n", ids[edge.TableName], parent, ids[edge.ReferencedTable], edge.ColumnName))
+	}
+	b.WriteString("@enduml\n")
+	return b.String()
+}
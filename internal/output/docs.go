@@ -0,0 +1,377 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// docReference is a foreign key shown on a data dictionary page. On the referencing
+// table it points to the referenced table, on the referenced table back to the
+// referencing one.
+type docReference struct {
+	Constraint  string
+	Table       string
+	Link        string
+	Column      string
+	LocalColumn string
+	DeleteRule  string
+	UpdateRule  string
+}
+
+// docColumn is a column of a data dictionary page
+type docColumn struct {
+	Name       string
+	Type       string
+	Nullable   bool
+	Default    string
+	Comment    string
+	PrimaryKey bool
+	References []docReference
+}
+
+// docTable is the data of one table page of a data dictionary
+type docTable struct {
+	Title     string
+	Name      string
+	Comment   string
+	RowFilter string
+	IndexLink string
+	Columns   []docColumn
+	Outbound  []docReference
+	Inbound   []docReference
+}
+
+// docIndex is the data of the index page of a data dictionary
+type docIndex struct {
+	Title     string
+	Info      *models.SchemaInfo
+	DataTypes []htmlCount
+	Tables    []docIndexEntry
+}
+
+// docIndexEntry is a table listed on the index page
+type docIndexEntry struct {
+	Name        string
+	Link        string
+	Comment     string
+	Columns     int
+	ForeignKeys int
+	Referenced  int
+}
+
+// docReferenceList is the data of the references template of a data dictionary page
+type docReferenceList struct {
+	Direction  string
+	References []docReference
+}
+
+// newDocReferenceList pairs references with the heading of their target column
+func newDocReferenceList(direction string, references []docReference) docReferenceList {
+	return docReferenceList{Direction: direction, References: references}
+}
+
+// FormatDataDictionary generates a browsable data dictionary of a schema: an index page
+// with the schema statistics and one page per table, with its columns and foreign keys
+// in both directions. It returns the content of every page by file path, relative to
+// the output directory. The table format, the default, generates Markdown.
+func (f *Formatter) FormatDataDictionary(schemaFile string, schema models.Schema) (map[string]string, error) {
+	var extension string
+	switch f.format {
+	case FormatMarkdown, FormatTable:
+		extension = ".md"
+	case FormatHTML:
+		extension = ".html"
+	default:
+		return nil, fmt.Errorf("unsupported output format for data dictionary: %s (markdown and html supported)", f.format)
+	}
+
+	names := make([]string, len(schema))
+	for i, table := range schema {
+		names[i] = table.TableName
+	}
+	files := uniqueIdentifiers(names)
+	for name, file := range files {
+		files[name] = file + extension
+	}
+	index, tables := dataDictionary(schemaFile, schema, files, "../index"+extension)
+
+	pages := make(map[string]string, len(tables)+1)
+	for _, table := range tables {
+		var content string
+		if extension == ".html" {
+			var buf bytes.Buffer
+			if err := htmlTemplates.ExecuteTemplate(&buf, "data-dictionary-table", table); err != nil {
+				return nil, fmt.Errorf("failed to render data dictionary page of %s as HTML: %w", table.Name, err)
+			}
+			content = buf.String()
+		} else {
+			content = formatDocTableAsMarkdown(table)
+		}
+		pages["tables/"+files[table.Name]] = content
+	}
+
+	if extension == ".html" {
+		var buf bytes.Buffer
+		if err := htmlTemplates.ExecuteTemplate(&buf, "data-dictionary-index", index); err != nil {
+			return nil, fmt.Errorf("failed to render data dictionary index as HTML: %w", err)
+		}
+		pages["index.html"] = buf.String()
+	} else {
+		pages["index.md"] = formatDocIndexAsMarkdown(index)
+	}
+	return pages, nil
+}
+
+// dataDictionary collects the pages of a data dictionary. files holds the page file name
+// of every table; table pages are in a directory below the index page.
+func dataDictionary(schemaFile string, schema models.Schema, files map[string]string, indexLink string) (docIndex, []docTable) {
+	// Inbound references are found on the referencing tables
+	inbound := make(map[string][]docReference)
+	for _, table := range schema {
+		for _, fk := range table.ForeignKeys {
+			inbound[fk.ReferencedTable] = append(inbound[fk.ReferencedTable], docReference{
+				Constraint:  fk.ConstraintName,
+				Table:       table.TableName,
+				Link:        files[table.TableName],
+				Column:      fk.ColumnName,
+				LocalColumn: fk.ReferencedColumn,
+				DeleteRule:  fk.DeleteRule,
+				UpdateRule:  fk.UpdateRule,
+			})
+		}
+	}
+
+	info := CreateSchemaInfo(schemaFile, schema)
+	index := docIndex{
+		Title:     "Data Dictionary",
+		Info:      info,
+		DataTypes: countChart(info.DataTypeCounts),
+	}
+
+	tables := make([]docTable, 0, len(schema))
+	for _, table := range schema {
+		page := docTable{
+			Title:     table.TableName + " · Data Dictionary",
+			Name:      table.TableName,
+			Comment:   table.Comment,
+			RowFilter: table.RowFilter,
+			IndexLink: indexLink,
+			Inbound:   inbound[table.TableName],
+		}
+
+		outbound := make(map[string][]docReference)
+		for _, fk := range table.ForeignKeys {
+			ref := docReference{
+				Constraint:  fk.ConstraintName,
+				Table:       fk.ReferencedTable,
+				Link:        files[fk.ReferencedTable],
+				Column:      fk.ReferencedColumn,
+				LocalColumn: fk.ColumnName,
+				DeleteRule:  fk.DeleteRule,
+				UpdateRule:  fk.UpdateRule,
+			}
+			page.Outbound = append(page.Outbound, ref)
+			outbound[fk.ColumnName] = append(outbound[fk.ColumnName], ref)
+		}
+
+		primaryKeys := make(map[string]bool)
+		for _, column := range table.GetPrimaryKeyColumns() {
+			primaryKeys[column.ColumnName] = true
+		}
+		for _, column := range table.Columns {
+			col := docColumn{
+				Name:       column.ColumnName,
+				Type:       column.GetFullDataType(),
+				Nullable:   !column.IsNotNull(),
+				Comment:    column.Comment,
+				PrimaryKey: primaryKeys[column.ColumnName],
+				References: outbound[column.ColumnName],
+			}
+			if column.DefaultValue != nil {
+				col.Default = fmt.Sprint(column.DefaultValue)
+			}
+			page.Columns = append(page.Columns, col)
+		}
+		tables = append(tables, page)
+
+		index.Tables = append(index.Tables, docIndexEntry{
+			Name:        table.TableName,
+			Link:        "tables/" + files[table.TableName],
+			Comment:     table.Comment,
+			Columns:     len(table.Columns),
+			ForeignKeys: len(table.ForeignKeys),
+			Referenced:  len(inbound[table.TableName]),
+		})
+	}
+	sort.Slice(index.Tables, func(i, j int) bool { return index.Tables[i].Name < index.Tables[j].Name })
+	return index, tables
+}
+
+// markdownLink formats a link for a markdown table cell, or the plain text without a target
+func markdownLink(text, target string) string {
+	text = strings.NewReplacer("[", "\\[", "]", "\\]").Replace(text)
+	if target == "" {
+		return text
+	}
+	return fmt.Sprintf("[%s](%s)", text, target)
+}
+
+// formatDocIndexAsMarkdown formats the index page of a data dictionary as markdown
+func formatDocIndexAsMarkdown(index docIndex) string {
+	var b strings.Builder
+	b.WriteString("# 📚 Data Dictionary\n\n")
+	if index.Info.SchemaFile != "" {
+		b.WriteString(fmt.Sprintf("Generated from `%s`.\n\n", index.Info.SchemaFile))
+	}
+
+	b.WriteString("## Summary\n\n")
+	b.WriteString(markdownRow("Metric", "Value"))
+	b.WriteString("|---|---:|\n")
+	b.WriteString(markdownRow("Tables", fmt.Sprintf("%d", index.Info.TotalTables)))
+	b.WriteString(markdownRow("Columns", fmt.Sprintf("%d", index.Info.TotalColumns)))
+	b.WriteString(markdownRow("Foreign Keys", fmt.Sprintf("%d", index.Info.TotalForeignKeys)))
+	b.WriteString(markdownRow("NOT NULL Columns", fmt.Sprintf("%d", index.Info.NotNullColumns)))
+	b.WriteString(markdownRow("Nullable Columns", fmt.Sprintf("%d", index.Info.NullableColumns)))
+	b.WriteString("\n")
+
+	if len(index.Tables) > 0 {
+		b.WriteString("## Tables\n\n")
+		b.WriteString(markdownRow("Table", "Columns", "Foreign Keys", "Referenced By", "Description"))
+		b.WriteString("|---|---:|---:|---:|---|\n")
+		for _, table := range index.Tables {
+			b.WriteString(markdownRow(markdownLink(table.Name, table.Link),
+				fmt.Sprintf("%d", table.Columns), fmt.Sprintf("%d", table.ForeignKeys),
+				fmt.Sprintf("%d", table.Referenced), table.Comment))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(index.DataTypes) > 0 {
+		b.WriteString("## Data Types\n\n")
+		b.WriteString(markdownRow("Data Type", "Count"))
+		b.WriteString("|---|---:|\n")
+		for _, count := range index.DataTypes {
+			b.WriteString(markdownRow(count.Name, fmt.Sprintf("%d", count.Count)))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// formatDocTableAsMarkdown formats the page of a table of a data dictionary as markdown
+func formatDocTableAsMarkdown(table docTable) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("# 📋 %s\n\n", table.Name))
+	b.WriteString(fmt.Sprintf("[← All tables](%s)\n\n", table.IndexLink))
+	if table.Comment != "" {
+		b.WriteString(table.Comment + "\n\n")
+	}
+	if table.RowFilter != "" {
+		b.WriteString(fmt.Sprintf("**Row filter:** `%s`\n\n", table.RowFilter))
+	}
+
+	b.WriteString("## Columns\n\n")
+	b.WriteString(markdownRow("Column", "Type", "Nullable", "Default", "References", "Description"))
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, column := range table.Columns {
+		name := column.Name
+		if column.PrimaryKey {
+			name += " 🔑"
+		}
+		nullable := "NO"
+		if column.Nullable {
+			nullable = "YES"
+		}
+		var references []string
+		for _, ref := range column.References {
+			references = append(references, markdownLink(ref.Table+"."+ref.Column, ref.Link))
+		}
+		b.WriteString(markdownRow(name, column.Type, nullable, column.Default, strings.Join(references, ", "), column.Comment))
+	}
+	b.WriteString("\n")
+
+	writeReferences := func(title, direction string, refs []docReference) {
+		if len(refs) == 0 {
+			return
+		}
+		b.WriteString(fmt.Sprintf("## %s\n\n", title))
+		b.WriteString(markdownRow("Column", direction, "Constraint", "On Delete", "On Update"))
+		b.WriteString("|---|---|---|---|---|\n")
+		for _, ref := range refs {
+			b.WriteString(markdownRow(ref.LocalColumn, markdownLink(ref.Table+"."+ref.Column, ref.Link),
+				ref.Constraint, ref.DeleteRule, ref.UpdateRule))
+		}
+		b.WriteString("\n")
+	}
+	writeReferences("References", "References", table.Outbound)
+	writeReferences("Referenced By", "Referenced By", table.Inbound)
+	return b.String()
+}
+
+// htmlDataDictionaryTemplate renders the index and table pages of a data dictionary
+const htmlDataDictionaryTemplate = `{{define "data-dictionary-index"}}{{template "head" .}}
+<header>
+<h1>📚 Data Dictionary</h1>
+<p>{{if .Info.SchemaFile}}Generated from {{.Info.SchemaFile}}{{else}}Generated by migrator{{end}}</p>
+</header>
+<main>
+<h2>Summary</h2>
+<div class="cards">
+<div class="card"><div class="value">{{.Info.TotalTables}}</div><div class="label">Tables</div></div>
+<div class="card"><div class="value">{{.Info.TotalColumns}}</div><div class="label">Columns</div></div>
+<div class="card"><div class="value">{{.Info.TotalForeignKeys}}</div><div class="label">Foreign keys</div></div>
+<div class="card"><div class="value">{{.Info.NotNullColumns}}</div><div class="label">NOT NULL columns</div></div>
+<div class="card"><div class="value">{{.Info.NullableColumns}}</div><div class="label">Nullable columns</div></div>
+</div>
+{{if .Tables}}
+<h2>Tables</h2>
+<div class="controls">
+<input type="search" placeholder="Filter tables..." data-filter="tables">
+<span class="shown" id="tables-shown"></span>
+</div>
+<table id="tables">
+<thead><tr><th class="sortable">Table</th><th class="sortable">Columns</th><th class="sortable">Foreign keys</th><th class="sortable">Referenced by</th><th>Description</th></tr></thead>
+<tbody>
+{{range .Tables}}<tr><td><a href="{{.Link}}">{{.Name}}</a></td><td>{{.Columns}}</td><td>{{.ForeignKeys}}</td><td>{{.Referenced}}</td><td>{{.Comment}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}{{if .DataTypes}}
+<h2>Data Types</h2>
+<div class="chart">
+{{range .DataTypes}}<div class="bar"><span class="name" title="{{.Name}}">{{.Name}}</span><span class="track"><div class="fill" style="width: {{.Percent}}%"></div></span><span>{{.Count}}</span></div>
+{{end}}</div>
+{{end}}
+</main>
+{{template "foot" .}}{{end}}{{define "data-dictionary-references"}}
+<table>
+<thead><tr><th class="sortable">Column</th><th class="sortable">{{.Direction}}</th><th class="sortable">Constraint</th><th>On delete</th><th>On update</th></tr></thead>
+<tbody>
+{{range .References}}<tr><td>{{.LocalColumn}}</td><td>{{if .Link}}<a href="{{.Link}}">{{.Table}}</a>{{else}}{{.Table}}{{end}}.{{.Column}}</td><td>{{.Constraint}}</td><td>{{.DeleteRule}}</td><td>{{.UpdateRule}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}{{define "data-dictionary-table"}}{{template "head" .}}
+<header>
+<h1>📋 {{.Name}}</h1>
+<p><a href="{{.IndexLink}}" style="color: #bcccdc">← All tables</a></p>
+</header>
+<main>
+{{if .Comment}}<p>{{.Comment}}</p>
+{{end}}{{if .RowFilter}}<p class="muted">Row filter: <code>{{.RowFilter}}</code></p>
+{{end}}
+<h2>Columns</h2>
+<table>
+<thead><tr><th class="sortable">Column</th><th class="sortable">Type</th><th class="sortable">Nullable</th><th>Default</th><th>References</th><th>Description</th></tr></thead>
+<tbody>
+{{range .Columns}}<tr><td>{{.Name}}{{if .PrimaryKey}} 🔑{{end}}</td><td>{{.Type}}</td><td>{{if .Nullable}}YES{{else}}NO{{end}}</td><td>{{if .Default}}<code>{{.Default}}</code>{{end}}</td><td>{{range $i, $ref := .References}}{{if $i}}, {{end}}{{if $ref.Link}}<a href="{{$ref.Link}}">{{$ref.Table}}</a>{{else}}{{$ref.Table}}{{end}}.{{$ref.Column}}{{end}}</td><td>{{.Comment}}</td></tr>
+{{end}}</tbody>
+</table>
+{{if .Outbound}}<h2>References</h2>
+{{template "data-dictionary-references" (references "References" .Outbound)}}{{end}}
+{{if .Inbound}}<h2>Referenced By</h2>
+{{template "data-dictionary-references" (references "Referenced by" .Inbound)}}{{end}}
+</main>
+{{template "foot" .}}{{end}}`
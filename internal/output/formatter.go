@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,6 +24,13 @@ const (
 	FormatJSON  OutputFormat = "json"
 	FormatYAML  OutputFormat = "yaml"
 	FormatCSV   OutputFormat = "csv"
+	FormatHTML  OutputFormat = "html"
+	// FormatMarkdown is GitHub-flavored markdown, sized for pull request comments
+	FormatMarkdown OutputFormat = "markdown"
+	FormatJUnit    OutputFormat = "junit"
+	FormatSARIF    OutputFormat = "sarif"
+	// FormatNDJSON writes one JSON record per line and can be streamed with NDJSONWriter
+	FormatNDJSON OutputFormat = "ndjson"
 )
 
 // Formatter handles different output formats
@@ -45,6 +54,16 @@ func (f *Formatter) FormatValidationReport(report *models.ValidationReport) (str
 		return f.formatValidationReportAsYAML(report)
 	case FormatCSV:
 		return f.formatValidationReportAsCSV(report)
+	case FormatHTML:
+		return f.formatValidationReportAsHTML(report)
+	case FormatMarkdown:
+		return f.formatValidationReportAsMarkdown(report), nil
+	case FormatJUnit:
+		return f.formatValidationReportAsJUnit(report)
+	case FormatSARIF:
+		return f.formatValidationReportAsSARIF(report)
+	case FormatNDJSON:
+		return f.formatValidationReportAsNDJSON(report)
 	default:
 		return "", fmt.Errorf("unsupported output format: %s", f.format)
 	}
@@ -59,6 +78,8 @@ func (f *Formatter) FormatSchemaInfo(info *models.SchemaInfo) (string, error) {
 		return f.formatSchemaInfoAsJSON(info)
 	case FormatYAML:
 		return f.formatSchemaInfoAsYAML(info)
+	case FormatMarkdown:
+		return f.formatSchemaInfoAsMarkdown(info), nil
 	default:
 		return "", fmt.Errorf("unsupported output format for schema info: %s", f.format)
 	}
@@ -73,6 +94,10 @@ func (f *Formatter) FormatSchemaComparison(comparison *models.SchemaComparison)
 		return f.formatSchemaComparisonAsJSON(comparison)
 	case FormatYAML:
 		return f.formatSchemaComparisonAsYAML(comparison)
+	case FormatHTML:
+		return f.formatSchemaComparisonAsHTML(comparison)
+	case FormatMarkdown:
+		return f.formatSchemaComparisonAsMarkdown(comparison), nil
 	default:
 		return "", fmt.Errorf("unsupported output format for schema comparison: %s", f.format)
 	}
@@ -106,10 +131,194 @@ func (f *Formatter) FormatSchemaSnapshot(schema models.Schema) (string, error) {
 	}
 }
 
+// FormatForeignKeySuggestions formats inferred foreign key suggestions in the specified format
+func (f *Formatter) FormatForeignKeySuggestions(suggestions []models.ForeignKeySuggestion) (string, error) {
+	switch f.format {
+	case FormatTable:
+		return f.formatForeignKeySuggestionsAsTable(suggestions), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(suggestions, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal foreign key suggestions to JSON: %w", err)
+		}
+		return string(data), nil
+	case FormatYAML:
+		data, err := yaml.Marshal(suggestions)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal foreign key suggestions to YAML: %w", err)
+		}
+		return string(data), nil
+	default:
+		return "", fmt.Errorf("unsupported output format for foreign key suggestions: %s", f.format)
+	}
+}
+
+// formatForeignKeySuggestionsAsTable formats foreign key suggestions as a table
+func (f *Formatter) formatForeignKeySuggestionsAsTable(suggestions []models.ForeignKeySuggestion) string {
+	if len(suggestions) == 0 {
+		return "✅ No undeclared foreign keys found!\n"
+	}
+
+	var buf bytes.Buffer
+	table := tablewriter.NewWriter(&buf)
+	table.Header("Column", "References", "Confidence", "Match Rate", "Values", "Reason")
+
+	for _, suggestion := range suggestions {
+		fk := suggestion.ForeignKey
+		matchRate := "-"
+		if suggestion.DistinctValues > 0 {
+			matchRate = fmt.Sprintf("%.1f%%", suggestion.MatchRate*100)
+		}
+		table.Append([]string{
+			fmt.Sprintf("%s.%s", fk.TableName, fk.ColumnName),
+			fmt.Sprintf("%s.%s", fk.ReferencedTable, fk.ReferencedColumn),
+			fmt.Sprintf("%.0f%%", suggestion.Confidence*100),
+			matchRate,
+			fmt.Sprintf("%d/%d", suggestion.MatchedValues, suggestion.DistinctValues),
+			suggestion.Reason,
+		})
+	}
+	table.Render()
+
+	return fmt.Sprintf("🔗 Suggested Foreign Keys (%d)\n%s", len(suggestions), buf.String())
+}
+
+// FormatQuarantineEntries formats quarantine table summaries in the specified format
+func (f *Formatter) FormatQuarantineEntries(entries []models.QuarantineEntry) (string, error) {
+	switch f.format {
+	case FormatTable:
+		return f.formatQuarantineEntriesAsTable(entries), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal quarantine entries to JSON: %w", err)
+		}
+		return string(data), nil
+	case FormatYAML:
+		data, err := yaml.Marshal(entries)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal quarantine entries to YAML: %w", err)
+		}
+		return string(data), nil
+	default:
+		return "", fmt.Errorf("unsupported output format for quarantine entries: %s", f.format)
+	}
+}
+
+// formatQuarantineEntriesAsTable formats quarantine table summaries as a table
+func (f *Formatter) formatQuarantineEntriesAsTable(entries []models.QuarantineEntry) string {
+	if len(entries) == 0 {
+		return "✅ No quarantined rows found!\n"
+	}
+
+	var buf bytes.Buffer
+	table := tablewriter.NewWriter(&buf)
+	table.Header("Table", "Run ID", "Constraint", "Rows", "Quarantined", "Reason")
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Rows
+		table.Append([]string{
+			entry.SourceTable,
+			entry.RunID,
+			entry.Constraint,
+			fmt.Sprintf("%d", entry.Rows),
+			entry.LastQuarantined,
+			entry.Reason,
+		})
+	}
+	table.Render()
+
+	return fmt.Sprintf("🧪 Quarantined Rows (%d)\n%s", total, buf.String())
+}
+
+// FormatAuditRecords formats audited runs; the table format lists one run per row
+func (f *Formatter) FormatAuditRecords(records []models.AuditRecord) (string, error) {
+	switch f.format {
+	case FormatTable:
+		return f.formatAuditRecordsAsTable(records), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal audit records to JSON: %w", err)
+		}
+		return string(data), nil
+	case FormatYAML:
+		data, err := yaml.Marshal(records)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal audit records to YAML: %w", err)
+		}
+		return string(data), nil
+	default:
+		return "", fmt.Errorf("unsupported output format for audit records: %s", f.format)
+	}
+}
+
+// formatAuditRecordsAsTable formats audited runs as a table
+func (f *Formatter) formatAuditRecordsAsTable(records []models.AuditRecord) string {
+	if len(records) == 0 {
+		return "✅ No audited runs found!\n"
+	}
+
+	var buf bytes.Buffer
+	table := tablewriter.NewWriter(&buf)
+	table.Header("Run ID", "Started", "User", "Host", "Connection", "Command", "Action", "Rows", "Duration", "Outcome")
+
+	for _, record := range records {
+		table.Append([]string{
+			record.RunID,
+			record.StartedAt,
+			record.User,
+			record.Host,
+			record.Connection,
+			record.Command,
+			record.Action,
+			fmt.Sprintf("%d", record.RowsAffected),
+			fmt.Sprintf("%dms", record.DurationMs),
+			record.Outcome,
+		})
+	}
+	table.Render()
+
+	return fmt.Sprintf("📜 Fix History (%d runs)\n%s", len(records), buf.String())
+}
+
+// FormatAuditRecord formats one audited run with the statements it executed
+func (f *Formatter) FormatAuditRecord(record *models.AuditRecord) (string, error) {
+	if f.format != FormatTable {
+		return f.FormatAuditRecords([]models.AuditRecord{*record})
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📜 Fix Run %s\n", record.RunID)
+	fmt.Fprintf(&b, "   Command: %s\n", record.CommandLine)
+	if record.PlanID != "" {
+		fmt.Fprintf(&b, "   Plan: %s\n", record.PlanID)
+	}
+	fmt.Fprintf(&b, "   User: %s@%s\n", record.User, record.Host)
+	fmt.Fprintf(&b, "   Connection: %s (database %s)\n", record.Connection, record.Database)
+	fmt.Fprintf(&b, "   Started: %s, took %dms\n", record.StartedAt, record.DurationMs)
+	fmt.Fprintf(&b, "   Outcome: %s, %d rows affected\n", record.Outcome, record.RowsAffected)
+	if record.Error != "" {
+		fmt.Fprintf(&b, "   ❌ Error: %s\n", record.Error)
+	}
+
+	for i, stmt := range record.Statements {
+		fmt.Fprintf(&b, "\n-- [%d] %s (%d rows)\n", i+1, stmt.Description, stmt.Rows)
+		if stmt.Error != "" {
+			fmt.Fprintf(&b, "-- ❌ %s\n", stmt.Error)
+		}
+		if stmt.SQL != "" {
+			fmt.Fprintf(&b, "%s;\n", stmt.SQL)
+		}
+	}
+	return b.String(), nil
+}
+
 // formatValidationReportAsTable formats the validation report as a table
 func (f *Formatter) formatValidationReportAsTable(report *models.ValidationReport) string {
 	if len(report.Issues) == 0 {
-		return "✅ No validation issues found!\n"
+		return "✅ No validation issues found!\n" + formatTableSummaryAsTable(report.Tables)
 	}
 
 	var buf bytes.Buffer
@@ -146,7 +355,27 @@ func (f *Formatter) formatValidationReportAsTable(report *models.ValidationRepor
 	})
 
 	table.Render()
-	return buf.String()
+	return buf.String() + formatTableSummaryAsTable(report.Tables)
+}
+
+// formatTableSummaryAsTable formats the summary of the tables with issues as a table
+func formatTableSummaryAsTable(tables []models.TableValidationSummary) string {
+	if len(tables) == 0 {
+		return ""
+	}
+	rows, _ := tableSummaryRows(tables)
+	if len(rows) == 0 {
+		return fmt.Sprintf("📊 Checked %d tables, none with issues\n", len(tables))
+	}
+
+	var buf bytes.Buffer
+	table := tablewriter.NewWriter(&buf)
+	table.Header(tableSummaryHeader)
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.Render()
+	return fmt.Sprintf("\n📊 %s\n%s", tableSummaryTitle(rows, tables), buf.String())
 }
 
 // formatValidationReportAsJSON formats the validation report as JSON
@@ -170,10 +399,25 @@ func (f *Formatter) formatValidationReportAsYAML(report *models.ValidationReport
 // formatValidationReportAsCSV formats the validation report as CSV
 func (f *Formatter) formatValidationReportAsCSV(report *models.ValidationReport) (string, error) {
 	records := [][]string{
-		{"Severity", "Type", "Table", "Column", "Message", "Identifier", "PrimaryKey"},
+		{"Severity", "Type", "Table", "Column", "Message", "Identifier", "PrimaryKey",
+			"TableRows", "TableViolatingRows", "TableViolationPercent"},
+	}
+
+	// Every issue carries the summary of its table, so rows can be weighed against its size
+	tables := make(map[string]models.TableValidationSummary, len(report.Tables))
+	for _, summary := range report.Tables {
+		tables[summary.Table] = summary
 	}
 
 	for _, issue := range report.Issues {
+		var rows, violatingRows, violationPercent string
+		if summary, ok := tables[issue.Table]; ok {
+			violatingRows = fmt.Sprintf("%d", summary.ViolatingRows)
+			if summary.TotalRows != nil {
+				rows = fmt.Sprintf("%d", *summary.TotalRows)
+				violationPercent = strconv.FormatFloat(summary.ViolationPercent, 'f', -1, 64)
+			}
+		}
 		records = append(records, []string{
 			issue.Severity,
 			issue.Type,
@@ -182,6 +426,9 @@ func (f *Formatter) formatValidationReportAsCSV(report *models.ValidationReport)
 			issue.Message,
 			issue.Identifier,
 			issue.PrimaryKey,
+			rows,
+			violatingRows,
+			violationPercent,
 		})
 	}
 
@@ -383,33 +630,72 @@ func WriteToFile(content, filename string) error {
 
 // CreateValidationReport creates a validation report with summary
 func CreateValidationReport(connectionName string, issues []models.ValidationIssue) *models.ValidationReport {
-	summary := models.ReportSummary{
-		TotalIssues:  len(issues),
-		IssuesByType: make(map[string]int),
-	}
-
-	tables := make(map[string]bool)
+	summary := newSummaryCounter()
 	for _, issue := range issues {
-		if issue.Severity == "error" {
-			summary.ErrorCount++
-		} else if issue.Severity == "warning" {
-			summary.WarningCount++
-		}
-
-		summary.IssuesByType[issue.Type]++
-		if issue.Table != "" {
-			tables[issue.Table] = true
-		}
+		summary.add(issue)
 	}
 
-	summary.TablesCovered = len(tables)
-
 	return &models.ValidationReport{
 		ConnectionName: connectionName,
 		Timestamp:      time.Now().Format(time.RFC3339),
 		Issues:         issues,
-		Summary:        summary,
+		Summary:        summary.result(),
+	}
+}
+
+// summaryCounter builds the summary of a validation report one issue at a time
+type summaryCounter struct {
+	summary models.ReportSummary
+	tables  map[string]bool
+	// counts counts the issues by type, severity and table; keys have no count
+	counts map[models.IssueCount]int
+}
+
+// newSummaryCounter creates a summary counter without issues
+func newSummaryCounter() *summaryCounter {
+	return &summaryCounter{
+		summary: models.ReportSummary{IssuesByType: make(map[string]int)},
+		tables:  make(map[string]bool),
+		counts:  make(map[models.IssueCount]int),
+	}
+}
+
+// add counts an issue
+func (c *summaryCounter) add(issue models.ValidationIssue) {
+	c.summary.TotalIssues++
+	if issue.Severity == "error" {
+		c.summary.ErrorCount++
+	} else if issue.Severity == "warning" {
+		c.summary.WarningCount++
+	}
+
+	c.summary.IssuesByType[issue.Type]++
+	c.counts[models.IssueCount{Type: issue.Type, Severity: issue.Severity, Table: issue.Table}]++
+	if issue.Table != "" {
+		c.tables[issue.Table] = true
+	}
+}
+
+// result returns the summary of the issues counted so far
+func (c *summaryCounter) result() models.ReportSummary {
+	summary := c.summary
+	summary.TablesCovered = len(c.tables)
+	summary.IssueCounts = make([]models.IssueCount, 0, len(c.counts))
+	for key, count := range c.counts {
+		key.Count = count
+		summary.IssueCounts = append(summary.IssueCounts, key)
 	}
+	sort.Slice(summary.IssueCounts, func(i, j int) bool {
+		a, b := summary.IssueCounts[i], summary.IssueCounts[j]
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Severity < b.Severity
+	})
+	return summary
 }
 
 // SaveReportToFile saves a report to a file with the specified format
@@ -0,0 +1,116 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// issueGroup holds the validation issues of one table
+type issueGroup struct {
+	Name     string
+	Anchor   string
+	Errors   int
+	Warnings int
+	Issues   []models.ValidationIssue
+}
+
+// groupIssuesByTable groups validation issues by table, ordered by table name. Each group
+// gets an anchor for linking to it.
+func groupIssuesByTable(issues []models.ValidationIssue) []issueGroup {
+	groups := make(map[string]*issueGroup)
+	for _, issue := range issues {
+		group, ok := groups[issue.Table]
+		if !ok {
+			group = &issueGroup{Name: issue.Table}
+			groups[issue.Table] = group
+		}
+		switch issue.Severity {
+		case "error":
+			group.Errors++
+		case "warning":
+			group.Warnings++
+		}
+		group.Issues = append(group.Issues, issue)
+	}
+
+	sorted := make([]issueGroup, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, *group)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	for i := range sorted {
+		sorted[i].Anchor = fmt.Sprintf("table-%d", i+1)
+	}
+	return sorted
+}
+
+// schemaChange is one difference of a table between the current and the target schema
+type schemaChange struct {
+	Table   string
+	Anchor  string
+	Change  string
+	Name    string
+	Details string
+}
+
+// tableChanges holds the differences of one table, column changes first
+type tableChanges struct {
+	Name          string
+	Anchor        string
+	ColumnChanges int
+	Changes       []schemaChange
+}
+
+// groupSchemaChanges lists the column and foreign key differences of every changed table,
+// ordered by table name
+func groupSchemaChanges(comparison *models.SchemaComparison) []tableChanges {
+	tableNames := make([]string, 0, len(comparison.TableDifferences))
+	for tableName := range comparison.TableDifferences {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+
+	var groups []tableChanges
+	for _, tableName := range tableNames {
+		diff := comparison.TableDifferences[tableName]
+		group := tableChanges{Name: tableName, Anchor: fmt.Sprintf("table-%d", len(groups)+1)}
+		add := func(change, name, details string) {
+			group.Changes = append(group.Changes, schemaChange{
+				Table: tableName, Anchor: group.Anchor, Change: change, Name: name, Details: details,
+			})
+		}
+
+		for _, col := range diff.MissingColumns {
+			add("missing column", col.ColumnName, fmt.Sprintf("%s, %s", col.GetFullDataType(), col.IsNullable))
+		}
+		for _, col := range diff.ExtraColumns {
+			add("extra column", col.ColumnName, fmt.Sprintf("%s, %s", col.GetFullDataType(), col.IsNullable))
+		}
+		colNames := make([]string, 0, len(diff.ModifiedColumns))
+		for colName := range diff.ModifiedColumns {
+			colNames = append(colNames, colName)
+		}
+		sort.Strings(colNames)
+		for _, colName := range colNames {
+			colDiff := diff.ModifiedColumns[colName]
+			add("modified column", colName, fmt.Sprintf("Current: %s (%s) → Target: %s (%s)",
+				colDiff.Current.GetFullDataType(), colDiff.Current.IsNullable,
+				colDiff.Target.GetFullDataType(), colDiff.Target.IsNullable))
+		}
+		group.ColumnChanges = len(group.Changes)
+
+		for _, fk := range diff.ForeignKeyDiffs.Missing {
+			add("missing foreign key", fk.ConstraintName, fmt.Sprintf("%s → %s.%s", fk.ColumnName, fk.ReferencedTable, fk.ReferencedColumn))
+		}
+		for _, fk := range diff.ForeignKeyDiffs.Extra {
+			add("extra foreign key", fk.ConstraintName, fmt.Sprintf("%s → %s.%s", fk.ColumnName, fk.ReferencedTable, fk.ReferencedColumn))
+		}
+
+		if len(group.Changes) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
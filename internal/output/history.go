@@ -0,0 +1,224 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// sparkBlocks are the bars of a text sparkline, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as a text sparkline scaled between their minimum and maximum
+func sparkline(values []int) string {
+	if len(values) == 0 {
+		return ""
+	}
+	min, max := values[0], values[0]
+	for _, value := range values {
+		if value < min {
+			min = value
+		}
+		if value > max {
+			max = value
+		}
+	}
+
+	var line strings.Builder
+	for _, value := range values {
+		level := 0
+		if max > min {
+			level = (value - min) * (len(sparkBlocks) - 1) / (max - min)
+		} else if value > 0 {
+			// A flat line that is not zero is drawn halfway up
+			level = len(sparkBlocks) / 2
+		}
+		line.WriteRune(sparkBlocks[level])
+	}
+	return line.String()
+}
+
+// shortHash shortens a schema hash for display
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// connectionLabel names the connection of a recorded run; runs without --connection use
+// the default connection
+func connectionLabel(connection string) string {
+	if connection == "" {
+		return "(default)"
+	}
+	return connection
+}
+
+// FormatHistoryEntries formats recorded runs in the specified format
+func (f *Formatter) FormatHistoryEntries(entries []models.HistoryEntry) (string, error) {
+	switch f.format {
+	case FormatTable:
+		return f.formatHistoryEntriesAsTable(entries), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal history to JSON: %w", err)
+		}
+		return string(data), nil
+	case FormatYAML:
+		data, err := yaml.Marshal(entries)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal history to YAML: %w", err)
+		}
+		return string(data), nil
+	case FormatCSV:
+		records := [][]string{{"ID", "Timestamp", "Kind", "Connection", "SchemaHash", "Command",
+			"TotalIssues", "Errors", "Warnings", "MissingTables", "ExtraTables", "ChangedTables"}}
+		for _, entry := range entries {
+			records = append(records, []string{
+				entry.ID, entry.Timestamp, entry.Kind, entry.Connection, entry.SchemaHash, entry.Command,
+				fmt.Sprintf("%d", entry.TotalIssues), fmt.Sprintf("%d", entry.ErrorCount), fmt.Sprintf("%d", entry.WarningCount),
+				fmt.Sprintf("%d", entry.MissingTables), fmt.Sprintf("%d", entry.ExtraTables), fmt.Sprintf("%d", entry.ChangedTables),
+			})
+		}
+		return formatCSVRecords(records, "history")
+	default:
+		return "", fmt.Errorf("unsupported output format for history: %s", f.format)
+	}
+}
+
+// formatHistoryEntriesAsTable formats recorded runs as a table
+func (f *Formatter) formatHistoryEntriesAsTable(entries []models.HistoryEntry) string {
+	if len(entries) == 0 {
+		return "📝 No recorded runs found\n"
+	}
+
+	var buf bytes.Buffer
+	table := tablewriter.NewWriter(&buf)
+	table.Header("ID", "Timestamp", "Kind", "Connection", "Schema", "Command", "Result")
+
+	for _, entry := range entries {
+		result := fmt.Sprintf("%d issues (%d errors, %d warnings)", entry.TotalIssues, entry.ErrorCount, entry.WarningCount)
+		if entry.Kind == "comparison" {
+			result = fmt.Sprintf("%d missing, %d extra, %d changed tables", entry.MissingTables, entry.ExtraTables, entry.ChangedTables)
+		}
+		table.Append([]string{
+			entry.ID,
+			entry.Timestamp,
+			entry.Kind,
+			connectionLabel(entry.Connection),
+			shortHash(entry.SchemaHash),
+			entry.Command,
+			result,
+		})
+	}
+	table.Render()
+
+	return fmt.Sprintf("🕒 Run History (%d runs)\n%s", len(entries), buf.String())
+}
+
+// FormatHistoryTrend formats counts of recorded runs over time. The table format draws
+// every count as a text sparkline; CSV has one row per run, for plotting elsewhere.
+func (f *Formatter) FormatHistoryTrend(trend *models.HistoryTrend) (string, error) {
+	switch f.format {
+	case FormatTable:
+		return f.formatHistoryTrendAsTable(trend), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(trend, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal trend to JSON: %w", err)
+		}
+		return string(data), nil
+	case FormatYAML:
+		data, err := yaml.Marshal(trend)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal trend to YAML: %w", err)
+		}
+		return string(data), nil
+	case FormatCSV:
+		header := []string{"Timestamp", "ID", "SchemaHash"}
+		for _, series := range trend.Series {
+			header = append(header, series.Name)
+		}
+		records := [][]string{header}
+		for i, run := range trend.Runs {
+			record := []string{run.Timestamp, run.ID, run.SchemaHash}
+			for _, series := range trend.Series {
+				record = append(record, fmt.Sprintf("%d", series.Values[i]))
+			}
+			records = append(records, record)
+		}
+		return formatCSVRecords(records, "trend")
+	default:
+		return "", fmt.Errorf("unsupported output format for trend: %s (table, csv, json and yaml supported)", f.format)
+	}
+}
+
+// formatHistoryTrendAsTable draws the counts of a trend as text sparklines
+func (f *Formatter) formatHistoryTrendAsTable(trend *models.HistoryTrend) string {
+	if len(trend.Runs) == 0 {
+		return fmt.Sprintf("📝 No recorded %s runs found for connection %s\n", trend.Kind, connectionLabel(trend.Connection))
+	}
+
+	var b strings.Builder
+	first, last := trend.Runs[0], trend.Runs[len(trend.Runs)-1]
+	kind := trend.Kind
+	if kind != "" {
+		kind = strings.ToUpper(kind[:1]) + kind[1:]
+	}
+	fmt.Fprintf(&b, "📈 %s Trend for %s (%d runs)\n", kind, connectionLabel(trend.Connection), len(trend.Runs))
+	fmt.Fprintf(&b, "   From %s to %s\n", first.Timestamp, last.Timestamp)
+
+	schemas := make(map[string]bool)
+	for _, run := range trend.Runs {
+		schemas[run.SchemaHash] = true
+	}
+	if len(schemas) > 1 {
+		fmt.Fprintf(&b, "   ⚠️  The runs used %d different schemas\n", len(schemas))
+	}
+	b.WriteString("\n")
+
+	var buf bytes.Buffer
+	table := tablewriter.NewWriter(&buf)
+	table.Header("Count", "Trend", "First", "Last", "Min", "Max")
+	for _, series := range trend.Series {
+		min, max := series.Values[0], series.Values[0]
+		for _, value := range series.Values {
+			if value < min {
+				min = value
+			}
+			if value > max {
+				max = value
+			}
+		}
+		table.Append([]string{
+			series.Name,
+			sparkline(series.Values),
+			fmt.Sprintf("%d", series.Values[0]),
+			fmt.Sprintf("%d", series.Values[len(series.Values)-1]),
+			fmt.Sprintf("%d", min),
+			fmt.Sprintf("%d", max),
+		})
+	}
+	table.Render()
+	b.WriteString(buf.String())
+	return b.String()
+}
+
+// formatCSVRecords writes records as CSV
+func formatCSVRecords(records [][]string, what string) (string, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.WriteAll(records); err != nil {
+		return "", fmt.Errorf("failed to write %s as CSV: %w", what, err)
+	}
+	return buf.String(), nil
+}
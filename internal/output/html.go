@@ -0,0 +1,373 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"sort"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// htmlCount is one bar of a count chart in an HTML report
+type htmlCount struct {
+	Name    string
+	Count   int
+	Percent int
+}
+
+// htmlValidationReport is the data of the HTML validation report template
+type htmlValidationReport struct {
+	Title  string
+	Report *models.ValidationReport
+	ByType []htmlCount
+	Types  []string
+	Tables []issueGroup
+	// TableSummary holds the rows of the table summary, under TableSummaryTitle
+	TableSummaryTitle  string
+	TableSummaryHeader []string
+	TableSummary       [][]string
+}
+
+// htmlSchemaComparison is the data of the HTML schema comparison template
+type htmlSchemaComparison struct {
+	Title         string
+	MissingTables []string
+	ExtraTables   []string
+	ColumnChanges int
+	FKChanges     int
+	Changes       []schemaChange
+	Tables        []tableChanges
+}
+
+// htmlTemplates renders the HTML reports. They are self-contained: styles and scripts are
+// inlined, so the files work offline and can be shared as they are.
+var htmlTemplates = template.Must(template.New("html").Funcs(template.FuncMap{
+	"details":    formatIssueDetails,
+	"references": newDocReferenceList,
+	"delta":      formatDelta,
+}).Parse(htmlLayout + htmlValidationReportTemplate + htmlSchemaComparisonTemplate + htmlDataDictionaryTemplate +
+	htmlReportDiffTemplate))
+
+// formatIssueDetails renders the details of a validation issue as indented JSON
+func formatIssueDetails(details map[string]interface{}) string {
+	data, err := json.MarshalIndent(details, "", "  ")
+	if err != nil {
+		return fmt.Sprint(details)
+	}
+	return string(data)
+}
+
+// countChart turns counts by name into chart bars, largest first
+func countChart(counts map[string]int) []htmlCount {
+	max := 0
+	for _, count := range counts {
+		if count > max {
+			max = count
+		}
+	}
+
+	bars := make([]htmlCount, 0, len(counts))
+	for name, count := range counts {
+		bar := htmlCount{Name: name, Count: count}
+		if max > 0 {
+			bar.Percent = count * 100 / max
+		}
+		bars = append(bars, bar)
+	}
+	sort.Slice(bars, func(i, j int) bool {
+		if bars[i].Count != bars[j].Count {
+			return bars[i].Count > bars[j].Count
+		}
+		return bars[i].Name < bars[j].Name
+	})
+	return bars
+}
+
+// formatValidationReportAsHTML formats the validation report as a self-contained HTML page
+func (f *Formatter) formatValidationReportAsHTML(report *models.ValidationReport) (string, error) {
+	data := htmlValidationReport{
+		Title:  "Validation Report",
+		Report: report,
+		ByType: countChart(report.Summary.IssuesByType),
+		Tables: groupIssuesByTable(report.Issues),
+	}
+	data.TableSummary, _ = tableSummaryRows(report.Tables)
+	data.TableSummaryTitle = tableSummaryTitle(data.TableSummary, report.Tables)
+	data.TableSummaryHeader = tableSummaryHeader
+	for issueType := range report.Summary.IssuesByType {
+		data.Types = append(data.Types, issueType)
+	}
+	sort.Strings(data.Types)
+
+	var buf bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&buf, "validation-report", data); err != nil {
+		return "", fmt.Errorf("failed to render validation report as HTML: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// formatSchemaComparisonAsHTML formats the schema comparison as a self-contained HTML page
+func (f *Formatter) formatSchemaComparisonAsHTML(comparison *models.SchemaComparison) (string, error) {
+	data := htmlSchemaComparison{
+		Title:         "Schema Comparison",
+		MissingTables: comparison.MissingTables,
+		ExtraTables:   comparison.ExtraTables,
+		Tables:        groupSchemaChanges(comparison),
+	}
+	for _, group := range data.Tables {
+		data.Changes = append(data.Changes, group.Changes...)
+		data.ColumnChanges += group.ColumnChanges
+		data.FKChanges += len(group.Changes) - group.ColumnChanges
+	}
+
+	var buf bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&buf, "schema-comparison", data); err != nil {
+		return "", fmt.Errorf("failed to render schema comparison as HTML: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// htmlLayout holds the page head, with the inlined styles and scripts shared by the reports
+const htmlLayout = `{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, Helvetica, Arial, sans-serif; margin: 0; color: #1f2933; background: #f5f7fa; }
+header { background: #243b53; color: #fff; padding: 20px 32px; }
+header h1 { margin: 0 0 4px; font-size: 24px; }
+header p { margin: 0; color: #bcccdc; }
+main { padding: 24px 32px; max-width: 1400px; }
+h2 { font-size: 18px; margin: 32px 0 12px; }
+.cards { display: flex; flex-wrap: wrap; gap: 16px; }
+.card { background: #fff; border-radius: 6px; padding: 16px 20px; min-width: 150px; box-shadow: 0 1px 3px rgba(0,0,0,.12); }
+.card .value { font-size: 28px; font-weight: 600; }
+.card .label { color: #627d98; font-size: 13px; }
+.card.error .value { color: #cf1124; }
+.card.warning .value { color: #cb6e17; }
+.card.ok .value { color: #18794e; }
+.chart { background: #fff; border-radius: 6px; padding: 12px 20px; box-shadow: 0 1px 3px rgba(0,0,0,.12); }
+.bar { display: flex; align-items: center; margin: 6px 0; font-size: 13px; }
+.bar .name { width: 220px; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+.bar .track { flex: 1; background: #e4e7eb; border-radius: 3px; height: 14px; margin: 0 12px; }
+.bar .fill { background: #486581; height: 14px; border-radius: 3px; }
+.controls { display: flex; flex-wrap: wrap; gap: 8px; margin-bottom: 8px; }
+.controls input, .controls select { padding: 6px 8px; border: 1px solid #bcccdc; border-radius: 4px; font-size: 13px; }
+.controls input { min-width: 280px; }
+.controls .shown { color: #627d98; font-size: 13px; align-self: center; }
+table { border-collapse: collapse; width: 100%; background: #fff; font-size: 13px; box-shadow: 0 1px 3px rgba(0,0,0,.12); }
+th, td { text-align: left; padding: 6px 10px; border-bottom: 1px solid #e4e7eb; vertical-align: top; }
+th { background: #f0f4f8; position: sticky; top: 0; }
+th.sortable { cursor: pointer; user-select: none; }
+th.sortable::after { content: " ⇅"; color: #9fb3c8; }
+th.asc::after { content: " ▲"; color: #243b53; }
+th.desc::after { content: " ▼"; color: #243b53; }
+tr:hover td { background: #f7f9fb; }
+.severity { font-weight: 600; text-transform: uppercase; font-size: 11px; }
+.severity.error { color: #cf1124; }
+.severity.warning { color: #cb6e17; }
+.severity.info { color: #2680c2; }
+details { background: #fff; border-radius: 6px; margin: 8px 0; box-shadow: 0 1px 3px rgba(0,0,0,.12); }
+details > summary { cursor: pointer; padding: 10px 16px; font-weight: 600; }
+details > .content { padding: 0 16px 12px; }
+details details { box-shadow: none; margin: 0; background: transparent; }
+details details > summary { padding: 2px 0; font-weight: normal; color: #486581; }
+pre { background: #f0f4f8; padding: 8px; border-radius: 4px; overflow-x: auto; margin: 4px 0; }
+ul.names { background: #fff; border-radius: 6px; padding: 12px 32px; box-shadow: 0 1px 3px rgba(0,0,0,.12); columns: 3; }
+a { color: #2680c2; }
+.muted { color: #627d98; }
+footer { color: #9fb3c8; font-size: 12px; padding: 24px 32px; }
+</style>
+<script>
+// Sort a table by the clicked column, numerically where both cells are numbers
+function sortTable(th) {
+  var table = th.closest("table"), body = table.tBodies[0];
+  var index = Array.prototype.indexOf.call(th.parentNode.children, th);
+  var asc = !th.classList.contains("asc");
+  Array.prototype.forEach.call(th.parentNode.children, function (h) { h.classList.remove("asc", "desc"); });
+  th.classList.add(asc ? "asc" : "desc");
+  var rows = Array.prototype.slice.call(body.rows);
+  rows.sort(function (a, b) {
+    var x = a.cells[index].textContent.trim(), y = b.cells[index].textContent.trim();
+    var nx = parseFloat(x), ny = parseFloat(y);
+    var cmp = (!isNaN(nx) && !isNaN(ny)) ? nx - ny : x.localeCompare(y);
+    return asc ? cmp : -cmp;
+  });
+  rows.forEach(function (row) { body.appendChild(row); });
+}
+
+// Show only the rows of a table matching its text and select filters
+function filterTable(id) {
+  var table = document.getElementById(id);
+  var controls = document.querySelectorAll("[data-filter='" + id + "']");
+  var shown = 0;
+  Array.prototype.forEach.call(table.tBodies[0].rows, function (row) {
+    var visible = true;
+    Array.prototype.forEach.call(controls, function (control) {
+      var value = control.value.toLowerCase();
+      if (!value) { return; }
+      if (control.dataset.field) {
+        visible = visible && row.dataset[control.dataset.field].toLowerCase() === value;
+      } else {
+        visible = visible && row.textContent.toLowerCase().indexOf(value) >= 0;
+      }
+    });
+    row.style.display = visible ? "" : "none";
+    if (visible) { shown++; }
+  });
+  document.getElementById(id + "-shown").textContent = shown + " of " + table.tBodies[0].rows.length + " shown";
+}
+
+document.addEventListener("DOMContentLoaded", function () {
+  document.querySelectorAll("th.sortable").forEach(function (th) {
+    th.addEventListener("click", function () { sortTable(th); });
+  });
+  document.querySelectorAll("[data-filter]").forEach(function (control) {
+    control.addEventListener("input", function () { filterTable(control.dataset.filter); });
+    filterTable(control.dataset.filter);
+  });
+  // Open the drill-down a link points to
+  document.querySelectorAll("a[href^='#table-']").forEach(function (link) {
+    link.addEventListener("click", function () {
+      var target = document.getElementById(link.getAttribute("href").substring(1));
+      if (target) { target.open = true; }
+    });
+  });
+});
+</script>
+</head>
+<body>
+{{end}}{{define "foot"}}<footer>Generated by migrator</footer>
+</body>
+</html>
+{{end}}`
+
+// htmlValidationReportTemplate renders a validation report
+const htmlValidationReportTemplate = `{{define "validation-report"}}{{template "head" .}}{{$report := .Report}}
+<header>
+<h1>Validation Report</h1>
+<p>Connection: {{$report.ConnectionName}} · Generated {{$report.Timestamp}}</p>
+</header>
+<main>
+<h2>Summary</h2>
+<div class="cards">
+<div class="card{{if eq $report.Summary.TotalIssues 0}} ok{{end}}"><div class="value">{{$report.Summary.TotalIssues}}</div><div class="label">Issues</div></div>
+<div class="card error"><div class="value">{{$report.Summary.ErrorCount}}</div><div class="label">Errors</div></div>
+<div class="card warning"><div class="value">{{$report.Summary.WarningCount}}</div><div class="label">Warnings</div></div>
+<div class="card"><div class="value">{{$report.Summary.TablesCovered}}</div><div class="label">Tables with issues</div></div>
+</div>
+{{if .ByType}}
+<h2>Issues by Type</h2>
+<div class="chart">
+{{range .ByType}}<div class="bar"><span class="name" title="{{.Name}}">{{.Name}}</span><span class="track"><span class="fill" style="display:block;width:{{.Percent}}%"></span></span><span>{{.Count}}</span></div>
+{{end}}</div>
+{{end}}
+{{if .TableSummary}}
+<h2>{{.TableSummaryTitle}}</h2>
+<table>
+<thead><tr>{{range .TableSummaryHeader}}<th class="sortable">{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .TableSummary}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{if not $report.Issues}}
+<h2>✅ No validation issues found!</h2>
+{{if $report.Tables}}<p class="muted">Checked {{len $report.Tables}} tables.</p>{{end}}
+{{else}}
+<h2>Tables</h2>
+<table>
+<thead><tr><th class="sortable">Table</th><th class="sortable">Errors</th><th class="sortable">Warnings</th><th class="sortable">Issues</th></tr></thead>
+<tbody>
+{{range .Tables}}<tr><td><a href="#{{.Anchor}}">{{if .Name}}{{.Name}}{{else}}(no table){{end}}</a></td><td>{{.Errors}}</td><td>{{.Warnings}}</td><td>{{len .Issues}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>All Issues</h2>
+<div class="controls">
+<input type="search" placeholder="Filter issues..." data-filter="issues">
+<select data-filter="issues" data-field="severity"><option value="">All severities</option><option value="error">Error</option><option value="warning">Warning</option><option value="info">Info</option></select>
+<select data-filter="issues" data-field="type"><option value="">All types</option>{{range .Types}}<option value="{{.}}">{{.}}</option>{{end}}</select>
+<span class="shown" id="issues-shown"></span>
+</div>
+<table id="issues">
+<thead><tr><th class="sortable">Severity</th><th class="sortable">Type</th><th class="sortable">Table</th><th class="sortable">Column</th><th class="sortable">Message</th><th class="sortable">Identifier</th></tr></thead>
+<tbody>
+{{range $report.Issues}}<tr data-severity="{{.Severity}}" data-type="{{.Type}}"><td class="severity {{.Severity}}">{{.Severity}}</td><td>{{.Type}}</td><td>{{.Table}}</td><td>{{.Column}}</td><td>{{.Message}}</td><td>{{.Identifier}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Issues per Table</h2>
+{{range .Tables}}<details id="{{.Anchor}}">
+<summary>{{if .Name}}{{.Name}}{{else}}(no table){{end}} <span class="muted">· {{len .Issues}} issues, {{.Errors}} errors, {{.Warnings}} warnings</span></summary>
+<div class="content">
+<table>
+<thead><tr><th class="sortable">Severity</th><th class="sortable">Type</th><th class="sortable">Column</th><th>Issue</th></tr></thead>
+<tbody>
+{{range .Issues}}<tr><td class="severity {{.Severity}}">{{.Severity}}</td><td>{{.Type}}</td><td>{{.Column}}</td><td>{{.Message}}{{if or .PrimaryKey .Identifier .Details}}
+<details><summary>Details</summary>
+{{if .Identifier}}<div>Identifier: {{.Identifier}}</div>{{end}}{{if .PrimaryKey}}<div>Primary key: {{.PrimaryKey}}</div>{{end}}{{if .Details}}<pre>{{details .Details}}</pre>{{end}}
+</details>{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+</div>
+</details>
+{{end}}{{end}}
+</main>
+{{template "foot" .}}{{end}}`
+
+// htmlSchemaComparisonTemplate renders a schema comparison
+const htmlSchemaComparisonTemplate = `{{define "schema-comparison"}}{{template "head" .}}
+<header>
+<h1>Schema Comparison</h1>
+<p>Current database compared with the target schema</p>
+</header>
+<main>
+<h2>Summary</h2>
+<div class="cards">
+<div class="card error"><div class="value">{{len .MissingTables}}</div><div class="label">Missing tables</div></div>
+<div class="card warning"><div class="value">{{len .ExtraTables}}</div><div class="label">Extra tables</div></div>
+<div class="card"><div class="value">{{len .Tables}}</div><div class="label">Tables with differences</div></div>
+<div class="card"><div class="value">{{.ColumnChanges}}</div><div class="label">Column changes</div></div>
+<div class="card"><div class="value">{{.FKChanges}}</div><div class="label">Foreign key changes</div></div>
+</div>
+{{if and (not .MissingTables) (not .ExtraTables) (not .Tables)}}
+<h2>✅ No schema differences found!</h2>
+{{else}}
+{{if .MissingTables}}<h2>Missing Tables</h2>
+<ul class="names">{{range .MissingTables}}<li>{{.}}</li>{{end}}</ul>
+{{end}}{{if .ExtraTables}}<h2>Extra Tables</h2>
+<ul class="names">{{range .ExtraTables}}<li>{{.}}</li>{{end}}</ul>
+{{end}}{{if .Changes}}
+<h2>All Changes</h2>
+<div class="controls">
+<input type="search" placeholder="Filter changes..." data-filter="changes">
+<select data-filter="changes" data-field="change"><option value="">All changes</option><option value="missing column">Missing column</option><option value="extra column">Extra column</option><option value="modified column">Modified column</option><option value="missing foreign key">Missing foreign key</option><option value="extra foreign key">Extra foreign key</option></select>
+<span class="shown" id="changes-shown"></span>
+</div>
+<table id="changes">
+<thead><tr><th class="sortable">Table</th><th class="sortable">Change</th><th class="sortable">Name</th><th class="sortable">Details</th></tr></thead>
+<tbody>
+{{range .Changes}}<tr data-change="{{.Change}}"><td><a href="#{{.Anchor}}">{{.Table}}</a></td><td>{{.Change}}</td><td>{{.Name}}</td><td>{{.Details}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Changes per Table</h2>
+{{range .Tables}}<details id="{{.Anchor}}">
+<summary>{{.Name}} <span class="muted">· {{len .Changes}} changes</span></summary>
+<div class="content">
+<table>
+<thead><tr><th class="sortable">Change</th><th class="sortable">Name</th><th>Details</th></tr></thead>
+<tbody>
+{{range .Changes}}<tr><td>{{.Change}}</td><td>{{.Name}}</td><td>{{.Details}}</td></tr>
+{{end}}</tbody>
+</table>
+</div>
+</details>
+{{end}}{{end}}{{end}}
+</main>
+{{template "foot" .}}{{end}}`
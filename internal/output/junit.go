@@ -0,0 +1,183 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"timestamp,attr,omitempty"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the test cases of one check
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+// junitProperty is a name/value property of a test suite
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitTestCase is one check of one table
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+// junitFailure holds every issue one check found in one table
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitCase collects the issues of one check of one table
+type junitCase struct {
+	table  string
+	issues []models.ValidationIssue
+}
+
+// formatValidationReportAsJUnit formats the validation report as JUnit XML. Every check is
+// a test suite and every table it checked a test case, which passes when the check found
+// nothing there and otherwise has one failure holding all of its issues. Issues of checks
+// that did not run table by table, or of reports without a table summary, get a test case
+// per table under their check, or their issue type when the check is unknown. A report
+// without checks or issues is a single passing test case.
+func (f *Formatter) formatValidationReportAsJUnit(report *models.ValidationReport) (string, error) {
+	root := junitTestSuites{Name: "migrator validation", Time: report.Timestamp}
+	properties := []junitProperty{{Name: "connection", Value: report.ConnectionName}}
+	for _, summary := range report.Tables {
+		if summary.ViolatingRows == 0 {
+			continue
+		}
+		value := fmt.Sprintf("%s rows violating", formatViolatingRows(summary.ViolatingRows, summary.ViolatingRowsLowerBound))
+		if summary.TotalRows != nil {
+			value = fmt.Sprintf("%s of %d rows violating (%s)", formatViolatingRows(summary.ViolatingRows, summary.ViolatingRowsLowerBound), *summary.TotalRows, formatViolationPercent(summary))
+		}
+		properties = append(properties, junitProperty{Name: "table." + summary.Table, Value: value})
+	}
+
+	// Every check of every table that ran, in the order they ran
+	var suiteOrder []string
+	suites := make(map[string][]*junitCase)
+	cases := make(map[string]map[string]*junitCase)
+	testCase := func(suite, table string) *junitCase {
+		if cases[suite] == nil {
+			cases[suite] = make(map[string]*junitCase)
+			suiteOrder = append(suiteOrder, suite)
+		}
+		c, ok := cases[suite][table]
+		if !ok {
+			c = &junitCase{table: table}
+			cases[suite][table] = c
+			suites[suite] = append(suites[suite], c)
+		}
+		return c
+	}
+	for _, summary := range report.Tables {
+		for _, check := range summary.Checks {
+			testCase(check.Check, summary.Table)
+		}
+	}
+	for _, issue := range report.Issues {
+		suite := issue.Check
+		if suite == "" {
+			suite = issue.Type
+		}
+		c := testCase(suite, issue.Table)
+		c.issues = append(c.issues, issue)
+	}
+
+	if len(suiteOrder) == 0 {
+		suiteOrder = []string{"validation"}
+		suites["validation"] = []*junitCase{{table: "all checks"}}
+	}
+
+	for _, name := range suiteOrder {
+		suite := junitTestSuite{Name: name, Time: report.Timestamp, Properties: properties}
+		for _, c := range suites[name] {
+			tableName := c.table
+			if tableName == "" {
+				tableName = "(no table)"
+			}
+			result := junitTestCase{Name: tableName, ClassName: name}
+			if len(c.issues) > 0 {
+				result.Failure = junitFailureOf(c.issues)
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, result)
+		}
+		suite.Tests = len(suite.Cases)
+		root.Suites = append(root.Suites, suite)
+	}
+
+	for _, suite := range root.Suites {
+		root.Tests += suite.Tests
+		root.Failures += suite.Failures
+	}
+
+	data, err := xml.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal validation report to JUnit XML: %w", err)
+	}
+	return xml.Header + string(data) + "\n", nil
+}
+
+// junitFailureOf folds the issues of one test case into a single failure, since most JUnit
+// readers only show the first failure of a test case. Its type is the most severe
+// severity among them.
+func junitFailureOf(issues []models.ValidationIssue) *junitFailure {
+	failure := &junitFailure{Message: issues[0].Message, Type: issues[0].Severity}
+	if len(issues) > 1 {
+		failure.Message = fmt.Sprintf("%d issues, first: %s", len(issues), issues[0].Message)
+	}
+
+	texts := make([]string, len(issues))
+	for i, issue := range issues {
+		if issue.Severity == "error" {
+			failure.Type = "error"
+		}
+		texts[i] = issue.Message + "\n" + junitFailureText(issue)
+	}
+	failure.Text = strings.Join(texts, "\n\n")
+	return failure
+}
+
+// junitFailureText describes where an issue was found, for the body of its failure
+func junitFailureText(issue models.ValidationIssue) string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("Severity: %s", issue.Severity))
+	if issue.Table != "" {
+		lines = append(lines, fmt.Sprintf("Table: %s", issue.Table))
+	}
+	if issue.Column != "" {
+		lines = append(lines, fmt.Sprintf("Column: %s", issue.Column))
+	}
+	if issue.Identifier != "" {
+		lines = append(lines, fmt.Sprintf("Identifier: %s", issue.Identifier))
+	}
+	if issue.PrimaryKey != "" {
+		lines = append(lines, fmt.Sprintf("Primary Key: %s", issue.PrimaryKey))
+	}
+	if len(issue.Details) > 0 {
+		lines = append(lines, fmt.Sprintf("Details: %s", formatIssueDetails(issue.Details)))
+	}
+	return strings.Join(lines, "\n")
+}
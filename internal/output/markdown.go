@@ -0,0 +1,258 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+const (
+	// markdownMaxLength keeps markdown reports under the 65536 character limit of GitHub
+	// comments, with room for a note added by whoever posts them
+	markdownMaxLength = 60000
+	// markdownReserve is kept free for closing a section and the truncation note
+	markdownReserve = 400
+	// markdownCollapseAfter is the number of rows above which a list is collapsed
+	markdownCollapseAfter = 10
+)
+
+// markdownBuilder writes a markdown report, keeping track of the space left under
+// markdownMaxLength
+type markdownBuilder struct {
+	strings.Builder
+	omitted int
+}
+
+// fits reports whether text can be added while leaving room for closing the report
+func (b *markdownBuilder) fits(text string) bool {
+	return b.Len()+len(text)+markdownReserve <= markdownMaxLength
+}
+
+// markdownSection starts a titled table with the given header, inside a collapsible
+// <details> section when it has more than markdownCollapseAfter rows
+func markdownSection(title string, rows int, header ...string) string {
+	var section strings.Builder
+	if rows > markdownCollapseAfter {
+		// GitHub does not render markdown inside <summary>
+		section.WriteString(fmt.Sprintf("<details>\n<summary><b>%s</b></summary>\n\n", escapeMarkdownCell(title)))
+	} else {
+		section.WriteString(fmt.Sprintf("#### %s\n\n", title))
+	}
+	section.WriteString(markdownRow(header...))
+	separators := make([]string, len(header))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	section.WriteString("|" + strings.Join(separators, "|") + "|\n")
+	return section.String()
+}
+
+// closeSection ends a section started by markdownSection
+func (b *markdownBuilder) closeSection(rows int) {
+	if rows > markdownCollapseAfter {
+		b.WriteString("\n</details>\n")
+	}
+	b.WriteString("\n")
+}
+
+// writeRows writes a section of table rows for as long as they fit. Rows that do not fit
+// are counted as omitted, and once anything is omitted later sections are omitted whole.
+func (b *markdownBuilder) writeRows(title string, header []string, rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+	section := markdownSection(title, len(rows), header...)
+	if b.omitted > 0 || !b.fits(section) {
+		b.omitted += len(rows)
+		return
+	}
+
+	b.WriteString(section)
+	for i, row := range rows {
+		line := markdownRow(row...)
+		if !b.fits(line) {
+			b.omitted += len(rows) - i
+			break
+		}
+		b.WriteString(line)
+	}
+	b.closeSection(len(rows))
+}
+
+// writeTruncationNote notes how many rows were left out to stay under the size limit
+func (b *markdownBuilder) writeTruncationNote(what string) {
+	if b.omitted > 0 {
+		b.WriteString(fmt.Sprintf("> ⚠️ %d more %s not shown to stay under the comment size limit. "+
+			"Use `--format json` or `--format html` for the full report.\n", b.omitted, what))
+	}
+}
+
+// markdownRow formats one row of a GitHub-flavored markdown table
+func markdownRow(cells ...string) string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = escapeMarkdownCell(cell)
+	}
+	return "| " + strings.Join(escaped, " | ") + " |\n"
+}
+
+// escapeMarkdownCell keeps a value inside its table cell
+func escapeMarkdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", "\\|")
+	value = strings.ReplaceAll(value, "<", "&lt;")
+	value = strings.ReplaceAll(value, "\r\n", "<br>")
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
+
+// formatValidationReportAsMarkdown formats the validation report as markdown for pull
+// request comments
+func (f *Formatter) formatValidationReportAsMarkdown(report *models.ValidationReport) string {
+	var b markdownBuilder
+
+	status := "✅"
+	if report.Summary.ErrorCount > 0 {
+		status = "❌"
+	} else if report.Summary.WarningCount > 0 {
+		status = "⚠️"
+	}
+	b.WriteString(fmt.Sprintf("## %s Validation Report\n\n", status))
+	b.WriteString(fmt.Sprintf("**Connection:** %s · **Generated:** %s\n\n", report.ConnectionName, report.Timestamp))
+
+	if len(report.Issues) == 0 {
+		b.WriteString("No validation issues found!\n")
+		if len(report.Tables) > 0 {
+			b.WriteString(fmt.Sprintf("\nChecked %d tables.\n", len(report.Tables)))
+		}
+		return b.String()
+	}
+
+	b.WriteString(markdownRow("Issues", "Errors", "Warnings", "Tables"))
+	b.WriteString("|---:|---:|---:|---:|\n")
+	b.WriteString(markdownRow(
+		fmt.Sprintf("%d", report.Summary.TotalIssues),
+		fmt.Sprintf("%d", report.Summary.ErrorCount),
+		fmt.Sprintf("%d", report.Summary.WarningCount),
+		fmt.Sprintf("%d", report.Summary.TablesCovered),
+	))
+	b.WriteString("\n")
+
+	byType := countChart(report.Summary.IssuesByType)
+	typeRows := make([][]string, len(byType))
+	for i, count := range byType {
+		typeRows[i] = []string{count.Name, fmt.Sprintf("%d", count.Count)}
+	}
+	b.writeRows("Issues by Type", []string{"Type", "Count"}, typeRows)
+
+	tableRows, _ := tableSummaryRows(report.Tables)
+	b.writeRows(tableSummaryTitle(tableRows, report.Tables), tableSummaryHeader, tableRows)
+
+	b.WriteString("### Issues per Table\n\n")
+	for _, group := range groupIssuesByTable(report.Issues) {
+		name := group.Name
+		if name == "" {
+			name = "(no table)"
+		}
+		title := fmt.Sprintf("%s · %d issues (%d errors, %d warnings)", name, len(group.Issues), group.Errors, group.Warnings)
+
+		rows := make([][]string, len(group.Issues))
+		for i, issue := range group.Issues {
+			rows[i] = []string{strings.ToUpper(issue.Severity), issue.Type, issue.Column, issue.Message, issue.Identifier}
+		}
+		b.writeRows(title, []string{"Severity", "Type", "Column", "Message", "Identifier"}, rows)
+	}
+
+	b.writeTruncationNote("issues")
+	return b.String()
+}
+
+// formatSchemaComparisonAsMarkdown formats the schema comparison as markdown for pull
+// request comments
+func (f *Formatter) formatSchemaComparisonAsMarkdown(comparison *models.SchemaComparison) string {
+	var b markdownBuilder
+
+	groups := groupSchemaChanges(comparison)
+	columnChanges, fkChanges := 0, 0
+	for _, group := range groups {
+		columnChanges += group.ColumnChanges
+		fkChanges += len(group.Changes) - group.ColumnChanges
+	}
+
+	if len(comparison.MissingTables) == 0 && len(comparison.ExtraTables) == 0 && len(groups) == 0 {
+		b.WriteString("## ✅ Schema Comparison\n\nNo schema differences found!\n")
+		return b.String()
+	}
+
+	b.WriteString("## 🔍 Schema Comparison\n\n")
+	b.WriteString(markdownRow("Missing Tables", "Extra Tables", "Changed Tables", "Column Changes", "Foreign Key Changes"))
+	b.WriteString("|---:|---:|---:|---:|---:|\n")
+	b.WriteString(markdownRow(
+		fmt.Sprintf("%d", len(comparison.MissingTables)),
+		fmt.Sprintf("%d", len(comparison.ExtraTables)),
+		fmt.Sprintf("%d", len(groups)),
+		fmt.Sprintf("%d", columnChanges),
+		fmt.Sprintf("%d", fkChanges),
+	))
+	b.WriteString("\n")
+
+	tableRows := func(names []string) [][]string {
+		rows := make([][]string, len(names))
+		for i, name := range names {
+			rows[i] = []string{name}
+		}
+		return rows
+	}
+	b.writeRows(fmt.Sprintf("Missing Tables (%d)", len(comparison.MissingTables)), []string{"Table"}, tableRows(comparison.MissingTables))
+	b.writeRows(fmt.Sprintf("Extra Tables (%d)", len(comparison.ExtraTables)), []string{"Table"}, tableRows(comparison.ExtraTables))
+
+	if len(groups) > 0 {
+		b.WriteString("### Changed Tables\n\n")
+	}
+	for _, group := range groups {
+		rows := make([][]string, len(group.Changes))
+		for i, change := range group.Changes {
+			rows[i] = []string{change.Change, change.Name, change.Details}
+		}
+		title := fmt.Sprintf("%s · %d changes", group.Name, len(group.Changes))
+		b.writeRows(title, []string{"Change", "Name", "Details"}, rows)
+	}
+
+	b.writeTruncationNote("differences")
+	return b.String()
+}
+
+// formatSchemaInfoAsMarkdown formats the schema info as markdown for pull request comments
+func (f *Formatter) formatSchemaInfoAsMarkdown(info *models.SchemaInfo) string {
+	var b markdownBuilder
+
+	b.WriteString("## 📊 Schema Summary\n\n")
+	b.WriteString(markdownRow("Metric", "Value"))
+	b.WriteString("|---|---:|\n")
+	b.WriteString(markdownRow("Schema File", info.SchemaFile))
+	b.WriteString(markdownRow("Tables", fmt.Sprintf("%d", info.TotalTables)))
+	b.WriteString(markdownRow("Columns", fmt.Sprintf("%d", info.TotalColumns)))
+	b.WriteString(markdownRow("Foreign Keys", fmt.Sprintf("%d", info.TotalForeignKeys)))
+	b.WriteString(markdownRow("NOT NULL Columns", fmt.Sprintf("%d", info.NotNullColumns)))
+	b.WriteString(markdownRow("Nullable Columns", fmt.Sprintf("%d", info.NullableColumns)))
+	b.WriteString("\n")
+
+	dataTypes := countChart(info.DataTypeCounts)
+	typeRows := make([][]string, len(dataTypes))
+	for i, count := range dataTypes {
+		typeRows[i] = []string{count.Name, fmt.Sprintf("%d", count.Count)}
+	}
+	b.writeRows("Data Types", []string{"Data Type", "Count"}, typeRows)
+
+	tables := make([]models.TableSummary, len(info.Tables))
+	copy(tables, info.Tables)
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	tableRows := make([][]string, len(tables))
+	for i, table := range tables {
+		tableRows[i] = []string{table.Name, fmt.Sprintf("%d", table.ColumnCount), fmt.Sprintf("%d", table.ForeignKeyCount)}
+	}
+	b.writeRows(fmt.Sprintf("Tables (%d)", len(tables)), []string{"Table", "Columns", "Foreign Keys"}, tableRows)
+
+	b.writeTruncationNote("rows")
+	return b.String()
+}
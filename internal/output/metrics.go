@@ -0,0 +1,140 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// metricsLabelEscaper escapes label values in the Prometheus text format
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// checkIssueTypes are the row-level issue types every per-table check can report, with
+// their severity. A table that a check ran on reports zero for each of them, so that the
+// series of a fixed issue does not vanish.
+var checkIssueTypes = map[string][]models.IssueCount{
+	"foreign_keys":     {{Type: "foreign_key_violation", Severity: "error"}},
+	"not_null":         {{Type: "null_constraint_violation", Severity: "error"}},
+	"polymorphic":      {{Type: "polymorphic_violation", Severity: "error"}, {Type: "polymorphic_unknown_type", Severity: "warning"}},
+	"cross_connection": {{Type: "foreign_key_violation", Severity: "error"}},
+}
+
+// metricsBuilder writes metric families in the Prometheus text format
+type metricsBuilder struct {
+	b strings.Builder
+}
+
+// family starts a metric family with its help text and type
+func (m *metricsBuilder) family(name, help, kind string) {
+	fmt.Fprintf(&m.b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes a sample of the current family; labels are name and value pairs
+func (m *metricsBuilder) sample(name string, value float64, labels ...string) {
+	m.b.WriteString(name)
+	if len(labels) > 0 {
+		m.b.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				m.b.WriteString(",")
+			}
+			fmt.Fprintf(&m.b, `%s="%s"`, labels[i], metricsLabelEscaper.Replace(labels[i+1]))
+		}
+		m.b.WriteString("}")
+	}
+	m.b.WriteString(" " + strconv.FormatFloat(value, 'f', -1, 64) + "\n")
+}
+
+// FormatMetrics formats the metrics of a validation run in the Prometheus text format, as
+// read by the node_exporter textfile collector
+func FormatMetrics(metrics *models.RunMetrics) string {
+	var m metricsBuilder
+	connection := metrics.Connection
+	if connection == "" {
+		connection = "default"
+	}
+
+	m.family("migrator_validation_issues", "Issues found by the last validation run, by type, severity and table.", "gauge")
+	written := make(map[models.IssueCount]bool)
+	for _, count := range metrics.Summary.IssueCounts {
+		m.sample("migrator_validation_issues", float64(count.Count),
+			"connection", connection, "type", count.Type, "severity", count.Severity, "table", count.Table)
+		written[models.IssueCount{Type: count.Type, Severity: count.Severity, Table: count.Table}] = true
+	}
+	for _, table := range metrics.Tables {
+		for _, check := range table.Checks {
+			for _, issueType := range checkIssueTypes[check.Check] {
+				key := models.IssueCount{Type: issueType.Type, Severity: issueType.Severity, Table: table.Table}
+				if written[key] {
+					continue
+				}
+				written[key] = true
+				m.sample("migrator_validation_issues", 0,
+					"connection", connection, "type", key.Type, "severity", key.Severity, "table", key.Table)
+			}
+		}
+	}
+
+	// The totals are always written, so that a run without issues still reports zero
+	m.family("migrator_validation_run_issues", "Total number of issues found by the last validation run.", "gauge")
+	m.sample("migrator_validation_run_issues", float64(metrics.Summary.TotalIssues), "connection", connection)
+	m.family("migrator_validation_run_errors", "Number of error issues found by the last validation run.", "gauge")
+	m.sample("migrator_validation_run_errors", float64(metrics.Summary.ErrorCount), "connection", connection)
+	m.family("migrator_validation_run_warnings", "Number of warning issues found by the last validation run.", "gauge")
+	m.sample("migrator_validation_run_warnings", float64(metrics.Summary.WarningCount), "connection", connection)
+	m.family("migrator_validation_tables_with_issues", "Number of tables with issues in the last validation run.", "gauge")
+	m.sample("migrator_validation_tables_with_issues", float64(metrics.Summary.TablesCovered), "connection", connection)
+
+	if len(metrics.Checks) > 0 {
+		m.family("migrator_validation_check_duration_seconds", "Duration of each check of the last validation run.", "gauge")
+		for _, check := range metrics.Checks {
+			m.sample("migrator_validation_check_duration_seconds", check.Seconds, "connection", connection, "check", check.Check)
+		}
+	}
+
+	timestamp := time.Now()
+	if parsed, err := time.Parse(time.RFC3339, metrics.Timestamp); err == nil {
+		timestamp = parsed
+	}
+	m.family("migrator_validation_last_run_timestamp_seconds", "Unix time of the last validation run.", "gauge")
+	m.sample("migrator_validation_last_run_timestamp_seconds", float64(timestamp.Unix()), "connection", connection)
+
+	if comparison := metrics.Comparison; comparison != nil {
+		var missingColumns, extraColumns, changedColumns int
+		for _, diff := range comparison.TableDifferences {
+			missingColumns += len(diff.MissingColumns)
+			extraColumns += len(diff.ExtraColumns)
+			changedColumns += len(diff.ModifiedColumns)
+		}
+
+		m.family("migrator_schema_drift_tables", "Tables of the database that differ from the target schema, by kind of drift.", "gauge")
+		m.sample("migrator_schema_drift_tables", float64(len(comparison.MissingTables)), "connection", connection, "kind", "missing")
+		m.sample("migrator_schema_drift_tables", float64(len(comparison.ExtraTables)), "connection", connection, "kind", "extra")
+		m.sample("migrator_schema_drift_tables", float64(len(comparison.TableDifferences)), "connection", connection, "kind", "changed")
+		m.family("migrator_schema_drift_columns", "Columns of the database that differ from the target schema, by kind of drift.", "gauge")
+		m.sample("migrator_schema_drift_columns", float64(missingColumns), "connection", connection, "kind", "missing")
+		m.sample("migrator_schema_drift_columns", float64(extraColumns), "connection", connection, "kind", "extra")
+		m.sample("migrator_schema_drift_columns", float64(changedColumns), "connection", connection, "kind", "changed")
+	}
+
+	return m.b.String()
+}
+
+// WriteMetricsFile writes the metrics of a validation run to filename. The file is
+// replaced in one step, so that the textfile collector never reads half of it.
+func WriteMetricsFile(filename string, metrics *models.RunMetrics) error {
+	temp := filename + ".tmp"
+	if err := os.WriteFile(temp, []byte(FormatMetrics(metrics)), 0644); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := os.Rename(temp, filename); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("failed to replace metrics file: %w", err)
+	}
+	return nil
+}
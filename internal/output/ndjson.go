@@ -0,0 +1,126 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// Record types of the NDJSON format
+const (
+	NDJSONRecordIssue   = "issue"
+	NDJSONRecordSummary = "summary"
+	NDJSONRecordError   = "error"
+)
+
+// ndjsonIssue is an issue line of the NDJSON format
+type ndjsonIssue struct {
+	Record string `json:"record"`
+	models.ValidationIssue
+}
+
+// ndjsonSummary is the trailing summary line of the NDJSON format
+type ndjsonSummary struct {
+	Record         string                          `json:"record"`
+	ConnectionName string                          `json:"connection_name"`
+	Timestamp      string                          `json:"timestamp"`
+	Summary        models.ReportSummary            `json:"summary"`
+	Tables         []models.TableValidationSummary `json:"tables,omitempty"`
+}
+
+// NDJSONWriter streams a validation report as newline-delimited JSON: every issue is
+// written on its own line as soon as it is added, and Close writes the summary as the
+// trailing line. Only the summary is kept in memory.
+type NDJSONWriter struct {
+	out            *bufio.Writer
+	encoder        *json.Encoder
+	connectionName string
+	summary        *summaryCounter
+}
+
+// NewNDJSONWriter creates a writer streaming the validation report of connectionName to w
+func NewNDJSONWriter(w io.Writer, connectionName string) *NDJSONWriter {
+	out := bufio.NewWriter(w)
+	return &NDJSONWriter{
+		out:            out,
+		encoder:        json.NewEncoder(out),
+		connectionName: connectionName,
+		summary:        newSummaryCounter(),
+	}
+}
+
+// WriteIssue writes an issue line and flushes it, so that readers of the stream see every
+// issue as soon as it is found
+func (w *NDJSONWriter) WriteIssue(issue models.ValidationIssue) error {
+	w.summary.add(issue)
+	if err := w.encoder.Encode(ndjsonIssue{Record: NDJSONRecordIssue, ValidationIssue: issue}); err != nil {
+		return fmt.Errorf("failed to write issue: %w", err)
+	}
+	if err := w.out.Flush(); err != nil {
+		return fmt.Errorf("failed to flush issue: %w", err)
+	}
+	return nil
+}
+
+// WriteRecord writes any other record, such as an error, as a line of its own
+func (w *NDJSONWriter) WriteRecord(record interface{}) error {
+	if err := w.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	return w.out.Flush()
+}
+
+// Summary returns the summary of the issues written so far
+func (w *NDJSONWriter) Summary() models.ReportSummary {
+	return w.summary.result()
+}
+
+// Close writes the summary line, with the summary of every table, and flushes the output.
+// It returns the report of the written issues, without the issues themselves.
+func (w *NDJSONWriter) Close(tables []models.TableValidationSummary) (*models.ValidationReport, error) {
+	report := &models.ValidationReport{
+		ConnectionName: w.connectionName,
+		Timestamp:      time.Now().Format(time.RFC3339),
+		Summary:        w.Summary(),
+		Tables:         tables,
+	}
+	if err := w.encoder.Encode(ndjsonSummaryRecord(report)); err != nil {
+		return nil, fmt.Errorf("failed to write summary: %w", err)
+	}
+	if err := w.out.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to flush output: %w", err)
+	}
+	return report, nil
+}
+
+// ndjsonSummaryRecord creates the summary line of a report
+func ndjsonSummaryRecord(report *models.ValidationReport) ndjsonSummary {
+	return ndjsonSummary{
+		Record:         NDJSONRecordSummary,
+		ConnectionName: report.ConnectionName,
+		Timestamp:      report.Timestamp,
+		Summary:        report.Summary,
+		Tables:         report.Tables,
+	}
+}
+
+// formatValidationReportAsNDJSON formats a complete validation report as newline-delimited
+// JSON, the same way NDJSONWriter streams it
+func (f *Formatter) formatValidationReportAsNDJSON(report *models.ValidationReport) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, issue := range report.Issues {
+		if err := encoder.Encode(ndjsonIssue{Record: NDJSONRecordIssue, ValidationIssue: issue}); err != nil {
+			return "", fmt.Errorf("failed to marshal validation issue to NDJSON: %w", err)
+		}
+	}
+	if err := encoder.Encode(ndjsonSummaryRecord(report)); err != nil {
+		return "", fmt.Errorf("failed to marshal validation summary to NDJSON: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,351 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// Statuses of an issue in a report diff
+const (
+	DiffStatusNew       = "new"
+	DiffStatusResolved  = "resolved"
+	DiffStatusUnchanged = "unchanged"
+)
+
+// diffIssue is an issue of a report diff with its status
+type diffIssue struct {
+	Status string `json:"status"`
+	models.ValidationIssue
+}
+
+// diffIssues lists the issues of a report diff with their status: new, then resolved, then
+// unchanged
+func diffIssues(diff *models.ReportDiff) []diffIssue {
+	issues := make([]diffIssue, 0, len(diff.NewIssues)+len(diff.ResolvedIssues)+len(diff.UnchangedIssues))
+	for _, issue := range diff.NewIssues {
+		issues = append(issues, diffIssue{Status: DiffStatusNew, ValidationIssue: issue})
+	}
+	for _, issue := range diff.ResolvedIssues {
+		issues = append(issues, diffIssue{Status: DiffStatusResolved, ValidationIssue: issue})
+	}
+	for _, issue := range diff.UnchangedIssues {
+		issues = append(issues, diffIssue{Status: DiffStatusUnchanged, ValidationIssue: issue})
+	}
+	return issues
+}
+
+// trendLabel describes the trend of a report diff with an icon
+func trendLabel(trend string) string {
+	switch trend {
+	case models.TrendImproving:
+		return "✅ Improving"
+	case models.TrendWorsening:
+		return "❌ Worsening"
+	default:
+		return "➖ Unchanged"
+	}
+}
+
+// formatDelta formats a change in a count with its sign
+func formatDelta(delta int) string {
+	if delta > 0 {
+		return fmt.Sprintf("+%d", delta)
+	}
+	return fmt.Sprintf("%d", delta)
+}
+
+// FormatReportDiff formats the comparison of two validation reports in the specified format
+func (f *Formatter) FormatReportDiff(diff *models.ReportDiff) (string, error) {
+	switch f.format {
+	case FormatTable:
+		return f.formatReportDiffAsTable(diff), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal report diff to JSON: %w", err)
+		}
+		return string(data), nil
+	case FormatYAML:
+		data, err := yaml.Marshal(diff)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal report diff to YAML: %w", err)
+		}
+		return string(data), nil
+	case FormatCSV:
+		return f.formatReportDiffAsCSV(diff)
+	case FormatHTML:
+		return f.formatReportDiffAsHTML(diff)
+	case FormatMarkdown:
+		return f.formatReportDiffAsMarkdown(diff), nil
+	case FormatJUnit:
+		// Only new issues fail, so a build breaks on regressions but not on known issues
+		return f.formatValidationReportAsJUnit(&models.ValidationReport{
+			ConnectionName: diff.New.ConnectionName,
+			Timestamp:      diff.New.Timestamp,
+			Issues:         diff.NewIssues,
+		})
+	case FormatSARIF:
+		return f.formatReportDiffAsSARIF(diff)
+	case FormatNDJSON:
+		return f.formatReportDiffAsNDJSON(diff)
+	default:
+		return "", fmt.Errorf("unsupported output format for report diff: %s", f.format)
+	}
+}
+
+// formatReportDiffAsTable formats the report diff as tables
+func (f *Formatter) formatReportDiffAsTable(diff *models.ReportDiff) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📈 Validation Trend: %s\n", trendLabel(diff.Summary.Trend))
+	fmt.Fprintf(&b, "   Old: %s (%s, %d issues)\n", diff.Old.File, diff.Old.Timestamp, diff.Old.Summary.TotalIssues)
+	fmt.Fprintf(&b, "   New: %s (%s, %d issues)\n", diff.New.File, diff.New.Timestamp, diff.New.Summary.TotalIssues)
+	fmt.Fprintf(&b, "   %d new, %d resolved, %d unchanged\n\n",
+		diff.Summary.NewIssues, diff.Summary.ResolvedIssues, diff.Summary.UnchangedIssues)
+
+	var buf bytes.Buffer
+	summary := tablewriter.NewWriter(&buf)
+	summary.Header("Metric", "Old", "New", "Delta")
+	summary.Append([]string{"Issues", fmt.Sprintf("%d", diff.Old.Summary.TotalIssues), fmt.Sprintf("%d", diff.New.Summary.TotalIssues), formatDelta(diff.Summary.TotalDelta)})
+	summary.Append([]string{"Errors", fmt.Sprintf("%d", diff.Old.Summary.ErrorCount), fmt.Sprintf("%d", diff.New.Summary.ErrorCount), formatDelta(diff.Summary.ErrorDelta)})
+	summary.Append([]string{"Warnings", fmt.Sprintf("%d", diff.Old.Summary.WarningCount), fmt.Sprintf("%d", diff.New.Summary.WarningCount), formatDelta(diff.Summary.WarningDelta)})
+	summary.Render()
+	b.WriteString(buf.String())
+
+	writeDeltas := func(title, header string, deltas []models.CountDelta) {
+		var changed []models.CountDelta
+		for _, delta := range deltas {
+			if delta.Delta != 0 {
+				changed = append(changed, delta)
+			}
+		}
+		if len(changed) == 0 {
+			return
+		}
+		var buf bytes.Buffer
+		table := tablewriter.NewWriter(&buf)
+		table.Header(header, "Old", "New", "Delta")
+		for _, delta := range changed {
+			table.Append([]string{delta.Name, fmt.Sprintf("%d", delta.Old), fmt.Sprintf("%d", delta.New), formatDelta(delta.Delta)})
+		}
+		table.Render()
+		fmt.Fprintf(&b, "\n%s\n%s", title, buf.String())
+	}
+	writeDeltas("📊 Changes by Type", "Type", diff.ByType)
+	writeDeltas("📋 Changes by Table", "Table", diff.ByTable)
+
+	writeIssues := func(title string, issues []models.ValidationIssue) {
+		if len(issues) == 0 {
+			return
+		}
+		var buf bytes.Buffer
+		table := tablewriter.NewWriter(&buf)
+		table.Header("Severity", "Type", "Table", "Column", "Message", "Identifier")
+		for _, issue := range issues {
+			table.Append([]string{strings.ToUpper(issue.Severity), issue.Type, issue.Table, issue.Column, issue.Message, issue.Identifier})
+		}
+		table.Render()
+		fmt.Fprintf(&b, "\n%s (%d)\n%s", title, len(issues), buf.String())
+	}
+	writeIssues("🆕 New Issues", diff.NewIssues)
+	writeIssues("✅ Resolved Issues", diff.ResolvedIssues)
+
+	return b.String()
+}
+
+// formatReportDiffAsCSV formats the issues of the report diff as CSV, one row per issue
+// with its status
+func (f *Formatter) formatReportDiffAsCSV(diff *models.ReportDiff) (string, error) {
+	records := [][]string{{"Status", "Severity", "Type", "Table", "Column", "Message", "Identifier", "PrimaryKey"}}
+	for _, issue := range diffIssues(diff) {
+		records = append(records, []string{issue.Status, issue.Severity, issue.Type, issue.Table, issue.Column, issue.Message, issue.Identifier, issue.PrimaryKey})
+	}
+	return formatCSVRecords(records, "report diff")
+}
+
+// formatReportDiffAsMarkdown formats the report diff as markdown for pull request comments
+func (f *Formatter) formatReportDiffAsMarkdown(diff *models.ReportDiff) string {
+	var b markdownBuilder
+
+	b.WriteString(fmt.Sprintf("## 📈 Validation Trend: %s\n\n", trendLabel(diff.Summary.Trend)))
+	b.WriteString(fmt.Sprintf("**Old:** %s (%s) · **New:** %s (%s)\n\n", diff.Old.File, diff.Old.Timestamp, diff.New.File, diff.New.Timestamp))
+	b.WriteString(markdownRow("", "Old", "New", "Delta"))
+	b.WriteString("|---|---:|---:|---:|\n")
+	b.WriteString(markdownRow("Issues", fmt.Sprintf("%d", diff.Old.Summary.TotalIssues), fmt.Sprintf("%d", diff.New.Summary.TotalIssues), formatDelta(diff.Summary.TotalDelta)))
+	b.WriteString(markdownRow("Errors", fmt.Sprintf("%d", diff.Old.Summary.ErrorCount), fmt.Sprintf("%d", diff.New.Summary.ErrorCount), formatDelta(diff.Summary.ErrorDelta)))
+	b.WriteString(markdownRow("Warnings", fmt.Sprintf("%d", diff.Old.Summary.WarningCount), fmt.Sprintf("%d", diff.New.Summary.WarningCount), formatDelta(diff.Summary.WarningDelta)))
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("🆕 **%d** new · ✅ **%d** resolved · ➖ **%d** unchanged\n\n",
+		diff.Summary.NewIssues, diff.Summary.ResolvedIssues, diff.Summary.UnchangedIssues))
+
+	deltaRows := func(deltas []models.CountDelta) [][]string {
+		var rows [][]string
+		for _, delta := range deltas {
+			if delta.Delta != 0 {
+				rows = append(rows, []string{delta.Name, fmt.Sprintf("%d", delta.Old), fmt.Sprintf("%d", delta.New), formatDelta(delta.Delta)})
+			}
+		}
+		return rows
+	}
+	b.writeRows("Changes by Type", []string{"Type", "Old", "New", "Delta"}, deltaRows(diff.ByType))
+	b.writeRows("Changes by Table", []string{"Table", "Old", "New", "Delta"}, deltaRows(diff.ByTable))
+
+	issueRows := func(issues []models.ValidationIssue) [][]string {
+		rows := make([][]string, len(issues))
+		for i, issue := range issues {
+			rows[i] = []string{strings.ToUpper(issue.Severity), issue.Type, issue.Table, issue.Column, issue.Message, issue.Identifier}
+		}
+		return rows
+	}
+	header := []string{"Severity", "Type", "Table", "Column", "Message", "Identifier"}
+	b.writeRows(fmt.Sprintf("New Issues (%d)", len(diff.NewIssues)), header, issueRows(diff.NewIssues))
+	b.writeRows(fmt.Sprintf("Resolved Issues (%d)", len(diff.ResolvedIssues)), header, issueRows(diff.ResolvedIssues))
+
+	b.writeTruncationNote("rows")
+	return b.String()
+}
+
+// formatReportDiffAsSARIF formats the report diff as SARIF 2.1.0, marking every result as
+// new, unchanged or absent (resolved) compared to the old report
+func (f *Formatter) formatReportDiffAsSARIF(diff *models.ReportDiff) (string, error) {
+	var issues []models.ValidationIssue
+	var states []string
+	for _, issue := range diffIssues(diff) {
+		issues = append(issues, issue.ValidationIssue)
+		switch issue.Status {
+		case DiffStatusResolved:
+			states = append(states, "absent")
+		default:
+			states = append(states, issue.Status)
+		}
+	}
+
+	properties := map[string]interface{}{
+		"connection":         diff.New.ConnectionName,
+		"timestamp":          diff.New.Timestamp,
+		"baseline_timestamp": diff.Old.Timestamp,
+		"trend":              diff.Summary.Trend,
+	}
+	data, err := sarifReport(issues, states, properties)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal report diff to SARIF: %w", err)
+	}
+	return data, nil
+}
+
+// ndjsonDiffSummary is the trailing summary line of a report diff in the NDJSON format
+type ndjsonDiffSummary struct {
+	Record  string                   `json:"record"`
+	Old     models.ReportDiffSide    `json:"old"`
+	New     models.ReportDiffSide    `json:"new"`
+	Summary models.ReportDiffSummary `json:"summary"`
+	ByType  []models.CountDelta      `json:"by_type"`
+	ByTable []models.CountDelta      `json:"by_table"`
+}
+
+// formatReportDiffAsNDJSON formats the report diff as newline-delimited JSON: one issue
+// line with its status per issue, then a summary line with the deltas
+func (f *Formatter) formatReportDiffAsNDJSON(diff *models.ReportDiff) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, issue := range diffIssues(diff) {
+		line := struct {
+			Record string `json:"record"`
+			diffIssue
+		}{Record: NDJSONRecordIssue, diffIssue: issue}
+		if err := encoder.Encode(line); err != nil {
+			return "", fmt.Errorf("failed to marshal report diff issue to NDJSON: %w", err)
+		}
+	}
+	summary := ndjsonDiffSummary{
+		Record:  NDJSONRecordSummary,
+		Old:     diff.Old,
+		New:     diff.New,
+		Summary: diff.Summary,
+		ByType:  diff.ByType,
+		ByTable: diff.ByTable,
+	}
+	if err := encoder.Encode(summary); err != nil {
+		return "", fmt.Errorf("failed to marshal report diff summary to NDJSON: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// htmlReportDiff is the data of the HTML report diff template
+type htmlReportDiff struct {
+	Title  string
+	Trend  string
+	Diff   *models.ReportDiff
+	Issues []diffIssue
+}
+
+// formatReportDiffAsHTML formats the report diff as a self-contained HTML page
+func (f *Formatter) formatReportDiffAsHTML(diff *models.ReportDiff) (string, error) {
+	data := htmlReportDiff{
+		Title:  "Validation Trend",
+		Trend:  trendLabel(diff.Summary.Trend),
+		Diff:   diff,
+		Issues: diffIssues(diff),
+	}
+
+	var buf bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&buf, "report-diff", data); err != nil {
+		return "", fmt.Errorf("failed to render report diff as HTML: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// htmlReportDiffTemplate renders a report diff
+const htmlReportDiffTemplate = `{{define "report-diff"}}{{template "head" .}}{{$diff := .Diff}}
+<header>
+<h1>Validation Trend: {{.Trend}}</h1>
+<p>{{$diff.Old.File}} ({{$diff.Old.Timestamp}}) compared with {{$diff.New.File}} ({{$diff.New.Timestamp}})</p>
+</header>
+<main>
+<h2>Summary</h2>
+<div class="cards">
+<div class="card error"><div class="value">{{$diff.Summary.NewIssues}}</div><div class="label">New issues</div></div>
+<div class="card ok"><div class="value">{{$diff.Summary.ResolvedIssues}}</div><div class="label">Resolved issues</div></div>
+<div class="card"><div class="value">{{$diff.Summary.UnchangedIssues}}</div><div class="label">Unchanged issues</div></div>
+<div class="card"><div class="value">{{delta $diff.Summary.TotalDelta}}</div><div class="label">Issues ({{$diff.Old.Summary.TotalIssues}} → {{$diff.New.Summary.TotalIssues}})</div></div>
+<div class="card"><div class="value">{{delta $diff.Summary.ErrorDelta}}</div><div class="label">Errors ({{$diff.Old.Summary.ErrorCount}} → {{$diff.New.Summary.ErrorCount}})</div></div>
+<div class="card"><div class="value">{{delta $diff.Summary.WarningDelta}}</div><div class="label">Warnings ({{$diff.Old.Summary.WarningCount}} → {{$diff.New.Summary.WarningCount}})</div></div>
+</div>
+{{if $diff.ByType}}
+<h2>Changes by Type</h2>
+<table>
+<thead><tr><th class="sortable">Type</th><th class="sortable">Old</th><th class="sortable">New</th><th class="sortable">Delta</th></tr></thead>
+<tbody>
+{{range $diff.ByType}}<tr><td>{{.Name}}</td><td>{{.Old}}</td><td>{{.New}}</td><td>{{delta .Delta}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}{{if $diff.ByTable}}
+<h2>Changes by Table</h2>
+<table>
+<thead><tr><th class="sortable">Table</th><th class="sortable">Old</th><th class="sortable">New</th><th class="sortable">Delta</th></tr></thead>
+<tbody>
+{{range $diff.ByTable}}<tr><td>{{.Name}}</td><td>{{.Old}}</td><td>{{.New}}</td><td>{{delta .Delta}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}{{if .Issues}}
+<h2>Issues</h2>
+<div class="controls">
+<input type="search" placeholder="Filter issues..." data-filter="issues">
+<select data-filter="issues" data-field="status"><option value="">All statuses</option><option value="new">New</option><option value="resolved">Resolved</option><option value="unchanged">Unchanged</option></select>
+<span class="shown" id="issues-shown"></span>
+</div>
+<table id="issues">
+<thead><tr><th class="sortable">Status</th><th class="sortable">Severity</th><th class="sortable">Type</th><th class="sortable">Table</th><th class="sortable">Column</th><th>Message</th><th class="sortable">Identifier</th></tr></thead>
+<tbody>
+{{range .Issues}}<tr data-status="{{.Status}}"><td>{{.Status}}</td><td class="severity {{.Severity}}">{{.Severity}}</td><td>{{.Type}}</td><td>{{.Table}}</td><td>{{.Column}}</td><td>{{.Message}}</td><td>{{.Identifier}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+</main>
+{{template "foot" .}}{{end}}`
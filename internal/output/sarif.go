@@ -0,0 +1,213 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// sarifLog is the root object of a SARIF report
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// sarifRun is one validation run
+type sarifRun struct {
+	Tool       sarifTool              `json:"tool"`
+	Results    []sarifResult          `json:"results"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// sarifTool describes the tool and the rules it checks
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+// sarifDriver describes the migrator and its rules
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+// sarifRule is one type of validation issue
+type sarifRule struct {
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name"`
+	ShortDescription     sarifMessage      `json:"shortDescription"`
+	DefaultConfiguration sarifRuleSettings `json:"defaultConfiguration"`
+}
+
+// sarifRuleSettings holds the default level of a rule
+type sarifRuleSettings struct {
+	Level string `json:"level"`
+}
+
+// sarifMessage is a plain-text message
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifResult is one validation issue
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	RuleIndex  int                    `json:"ruleIndex"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	// BaselineState tells whether the result is new, unchanged or absent compared to a
+	// previous run
+	BaselineState string `json:"baselineState,omitempty"`
+}
+
+// sarifLocation locates an issue in the database schema
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+// sarifLogicalLocation is a table or a column
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifLevel maps an issue severity to a SARIF result level
+func sarifLevel(severity string) string {
+	switch severity {
+	case "error":
+		return "error"
+	case "warning":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// sarifRuleName turns an issue type such as foreign_key_violation into a rule name such
+// as ForeignKeyViolation
+func sarifRuleName(issueType string) string {
+	var name strings.Builder
+	for _, word := range strings.Split(issueType, "_") {
+		if word != "" {
+			name.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return name.String()
+}
+
+// formatValidationReportAsSARIF formats the validation report as SARIF 2.1.0. Every issue
+// type is a rule, and the table and column of an issue are its logical locations.
+func (f *Formatter) formatValidationReportAsSARIF(report *models.ValidationReport) (string, error) {
+	properties := map[string]interface{}{
+		"connection": report.ConnectionName,
+		"timestamp":  report.Timestamp,
+	}
+	if len(report.Tables) > 0 {
+		properties["tables"] = report.Tables
+	}
+	data, err := sarifReport(report.Issues, nil, properties)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal validation report to SARIF: %w", err)
+	}
+	return data, nil
+}
+
+// sarifReport builds a SARIF log with one result per issue. baselineStates, when given,
+// holds the baseline state of every issue.
+func sarifReport(issues []models.ValidationIssue, baselineStates []string, properties map[string]interface{}) (string, error) {
+	var issueTypes []string
+	levels := make(map[string]string)
+	for _, issue := range issues {
+		level, seen := levels[issue.Type]
+		if !seen {
+			issueTypes = append(issueTypes, issue.Type)
+		}
+		// A rule defaults to the most severe level it is reported with
+		if !seen || level == "note" || (level == "warning" && sarifLevel(issue.Severity) == "error") {
+			levels[issue.Type] = sarifLevel(issue.Severity)
+		}
+	}
+	sort.Strings(issueTypes)
+
+	driver := sarifDriver{Name: "migrator", Rules: []sarifRule{}}
+	ruleIndex := make(map[string]int)
+	for i, issueType := range issueTypes {
+		ruleIndex[issueType] = i
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   issueType,
+			Name:                 sarifRuleName(issueType),
+			ShortDescription:     sarifMessage{Text: strings.ReplaceAll(issueType, "_", " ")},
+			DefaultConfiguration: sarifRuleSettings{Level: levels[issueType]},
+		})
+	}
+
+	results := make([]sarifResult, 0, len(issues))
+	for i, issue := range issues {
+		result := sarifResult{
+			RuleID:    issue.Type,
+			RuleIndex: ruleIndex[issue.Type],
+			Level:     sarifLevel(issue.Severity),
+			Message:   sarifMessage{Text: issue.Message},
+		}
+		if baselineStates != nil {
+			result.BaselineState = baselineStates[i]
+		}
+
+		var locations []sarifLogicalLocation
+		if issue.Table != "" {
+			locations = append(locations, sarifLogicalLocation{Name: issue.Table, FullyQualifiedName: issue.Table, Kind: "table"})
+			if issue.Column != "" {
+				locations = append(locations, sarifLogicalLocation{
+					Name:               issue.Column,
+					FullyQualifiedName: issue.Table + "." + issue.Column,
+					Kind:               "column",
+				})
+			}
+		}
+		if len(locations) > 0 {
+			result.Locations = []sarifLocation{{LogicalLocations: locations}}
+		}
+
+		properties := make(map[string]interface{})
+		if issue.Identifier != "" {
+			properties["identifier"] = issue.Identifier
+		}
+		if issue.PrimaryKey != "" {
+			properties["primary_key"] = issue.PrimaryKey
+		}
+		if len(issue.Details) > 0 {
+			properties["details"] = issue.Details
+		}
+		if len(properties) > 0 {
+			result.Properties = properties
+		}
+		results = append(results, result)
+	}
+
+	log := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool:       sarifTool{Driver: driver},
+			Results:    results,
+			Properties: properties,
+		}},
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
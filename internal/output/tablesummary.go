@@ -0,0 +1,296 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// rowViolationTypes are the issue types reported once per violating row
+var rowViolationTypes = map[string]bool{
+	"foreign_key_violation":     true,
+	"null_constraint_violation": true,
+	"polymorphic_violation":     true,
+}
+
+// violatingRows counts the violating rows of a table, once per row. Rows without an
+// identifier cannot be told apart and are all counted.
+type violatingRows struct {
+	identified map[string]bool
+	anonymous  int
+}
+
+// add counts the rows of a row-level issue
+func (r *violatingRows) add(issue models.ValidationIssue) {
+	if issue.Type == "polymorphic_unknown_type" {
+		// Reported once per unmapped type value, with the number of its rows
+		if count, ok := issue.Details["row_count"].(int64); ok {
+			r.anonymous += int(count)
+		}
+		return
+	}
+	if !rowViolationTypes[issue.Type] {
+		return
+	}
+	if issue.Identifier == "" {
+		r.anonymous++
+		return
+	}
+	if r.identified == nil {
+		r.identified = make(map[string]bool)
+	}
+	r.identified[issue.Identifier] = true
+}
+
+// count returns the number of violating rows
+func (r *violatingRows) count() int {
+	return len(r.identified) + r.anonymous
+}
+
+// tableProgress is the summary of one table while the checks run
+type tableProgress struct {
+	summary models.TableValidationSummary
+	rows    violatingRows
+	checks  map[string]*tableCheckProgress
+}
+
+// tableCheckProgress is the result of one check on one table while the checks run
+type tableCheckProgress struct {
+	elapsed time.Duration
+	rows    violatingRows
+	issues  int
+}
+
+// TableSummaryBuilder builds the summary of every table of a validation report while the
+// checks run. The zero value is ready to use.
+type TableSummaryBuilder struct {
+	tables      map[string]*tableProgress
+	tableOrder  []string
+	checkOrder  []string
+	knownChecks map[string]bool
+}
+
+// table returns the progress of a table, adding it on first use
+func (b *TableSummaryBuilder) table(name string) *tableProgress {
+	if b.tables == nil {
+		b.tables = make(map[string]*tableProgress)
+		b.knownChecks = make(map[string]bool)
+	}
+	progress, ok := b.tables[name]
+	if !ok {
+		progress = &tableProgress{
+			summary: models.TableValidationSummary{Table: name},
+			checks:  make(map[string]*tableCheckProgress),
+		}
+		b.tables[name] = progress
+		b.tableOrder = append(b.tableOrder, name)
+	}
+	return progress
+}
+
+// check returns the progress of a check on a table, adding it on first use
+func (b *TableSummaryBuilder) check(table, check string) *tableCheckProgress {
+	progress := b.table(table)
+	checkProgress, ok := progress.checks[check]
+	if !ok {
+		checkProgress = &tableCheckProgress{}
+		progress.checks[check] = checkProgress
+	}
+	if !b.knownChecks[check] {
+		b.knownChecks[check] = true
+		b.checkOrder = append(b.checkOrder, check)
+	}
+	return checkProgress
+}
+
+// AddTime adds the time a check spent on a table
+func (b *TableSummaryBuilder) AddTime(table, check string, elapsed time.Duration) {
+	b.check(table, check).elapsed += elapsed
+}
+
+// AddIssue counts an issue found by a check
+func (b *TableSummaryBuilder) AddIssue(check string, issue models.ValidationIssue) {
+	if issue.Table == "" {
+		return
+	}
+	progress := b.table(issue.Table)
+	progress.summary.Issues++
+	progress.rows.add(issue)
+	checkProgress := b.check(issue.Table, check)
+	checkProgress.rows.add(issue)
+	checkProgress.issues++
+}
+
+// ViolationCounter returns the exact number of rows of a table that violate any of the
+// given checks. ok is false when the checks cannot be counted with a query.
+type ViolationCounter func(table string, checks []string) (count int64, ok bool, err error)
+
+// Build returns the summary of every table, in the order the tables were checked. The
+// rows of each table are counted with countRows; tables that cannot be counted, such as
+// missing ones, have no total and no violation percentage. Violating rows are counted
+// with countViolations, per check and per table. Where that fails, they are counted from
+// the issues, as a lower bound once a check reported issueLimit issues on the table.
+func (b *TableSummaryBuilder) Build(countRows func(table string) (int64, error), countViolations ViolationCounter, issueLimit int) []models.TableValidationSummary {
+	exactCount := func(table string, checks []string) (int, bool) {
+		if countViolations == nil {
+			return 0, false
+		}
+		count, ok, err := countViolations(table, checks)
+		if err != nil || !ok {
+			return 0, false
+		}
+		return int(count), true
+	}
+
+	summaries := make([]models.TableValidationSummary, 0, len(b.tableOrder))
+	for _, name := range b.tableOrder {
+		progress := b.tables[name]
+		summary := progress.summary
+		summary.ViolatingRows = progress.rows.count()
+
+		var checks []string
+		var maxCheckRows int
+		undercounted := false
+		for _, check := range b.checkOrder {
+			checkProgress, ok := progress.checks[check]
+			if !ok {
+				continue
+			}
+			checks = append(checks, check)
+			result := models.TableCheck{
+				Check:         check,
+				ViolatingRows: checkProgress.rows.count(),
+				Seconds:       checkProgress.elapsed.Seconds(),
+			}
+			if count, ok := exactCount(name, []string{check}); ok {
+				undercounted = undercounted || count > result.ViolatingRows
+				result.ViolatingRows = count
+			} else if issueLimit > 0 && checkProgress.issues >= issueLimit {
+				result.ViolatingRowsLowerBound = true
+				summary.ViolatingRowsLowerBound = true
+			}
+			if result.ViolatingRows > maxCheckRows {
+				maxCheckRows = result.ViolatingRows
+			}
+			summary.Seconds += checkProgress.elapsed.Seconds()
+			summary.Checks = append(summary.Checks, result)
+		}
+
+		if count, ok := exactCount(name, checks); ok {
+			summary.ViolatingRows = count
+			summary.ViolatingRowsLowerBound = false
+		} else if undercounted {
+			// The checks counted exactly found more rows than their issues show
+			summary.ViolatingRowsLowerBound = true
+			if summary.ViolatingRows < maxCheckRows {
+				summary.ViolatingRows = maxCheckRows
+			}
+		}
+
+		if countRows != nil {
+			if total, err := countRows(name); err == nil {
+				summary.TotalRows = &total
+				if total > 0 {
+					summary.ViolationPercent = float64(summary.ViolatingRows) * 100 / float64(total)
+				}
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// tableSummaryHeader is the header of the table summary in the table, markdown and HTML
+// formats
+var tableSummaryHeader = []string{"Table", "Rows", "Violating Rows", "Violation Rate", "By Check", "Time"}
+
+// formatRowCount formats the row count of a table, which is unknown for missing tables
+func formatRowCount(summary models.TableValidationSummary) string {
+	if summary.TotalRows == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *summary.TotalRows)
+}
+
+// formatViolatingRows formats a number of violating rows, marking lower bounds with a
+// trailing plus
+func formatViolatingRows(count int, lowerBound bool) string {
+	if lowerBound {
+		return fmt.Sprintf("%d+", count)
+	}
+	return fmt.Sprintf("%d", count)
+}
+
+// formatViolationPercent formats the share of violating rows, keeping very small shares
+// distinguishable from none
+func formatViolationPercent(summary models.TableValidationSummary) string {
+	prefix := ""
+	if summary.ViolatingRowsLowerBound {
+		prefix = ">="
+	}
+	switch {
+	case summary.TotalRows == nil:
+		return "-"
+	case summary.ViolatingRows == 0:
+		return "0%"
+	case summary.ViolationPercent < 0.01 && !summary.ViolatingRowsLowerBound:
+		return "<0.01%"
+	case summary.ViolationPercent < 0.01:
+		return ">0%"
+	default:
+		return fmt.Sprintf("%s%.2f%%", prefix, summary.ViolationPercent)
+	}
+}
+
+// formatChecks lists the violating rows found by every check of a table
+func formatChecks(summary models.TableValidationSummary) string {
+	var parts []string
+	for _, check := range summary.Checks {
+		if check.ViolatingRows > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %s", check.Check, formatViolatingRows(check.ViolatingRows, check.ViolatingRowsLowerBound)))
+		}
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// tableSummaryRows formats the tables with issues, highest share of violating rows first.
+// It also returns the number of tables without issues, which are left out.
+func tableSummaryRows(tables []models.TableValidationSummary) (rows [][]string, clean int) {
+	var shown []models.TableValidationSummary
+	for _, summary := range tables {
+		if summary.Issues == 0 {
+			clean++
+			continue
+		}
+		shown = append(shown, summary)
+	}
+	sort.SliceStable(shown, func(i, j int) bool {
+		if shown[i].ViolationPercent != shown[j].ViolationPercent {
+			return shown[i].ViolationPercent > shown[j].ViolationPercent
+		}
+		return shown[i].ViolatingRows > shown[j].ViolatingRows
+	})
+
+	for _, summary := range shown {
+		rows = append(rows, []string{
+			summary.Table,
+			formatRowCount(summary),
+			formatViolatingRows(summary.ViolatingRows, summary.ViolatingRowsLowerBound),
+			formatViolationPercent(summary),
+			formatChecks(summary),
+			fmt.Sprintf("%.2fs", summary.Seconds),
+		})
+	}
+	return rows, clean
+}
+
+// tableSummaryTitle describes how many of the checked tables have issues
+func tableSummaryTitle(rows [][]string, tables []models.TableValidationSummary) string {
+	return fmt.Sprintf("Table Summary (%d of %d tables with issues)", len(rows), len(tables))
+}
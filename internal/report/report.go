@@ -0,0 +1,223 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// ndjsonLine is any line of a report in the NDJSON format
+type ndjsonLine struct {
+	Record         string                          `json:"record"`
+	ConnectionName string                          `json:"connection_name"`
+	Timestamp      string                          `json:"timestamp"`
+	Summary        models.ReportSummary            `json:"summary"`
+	Tables         []models.TableValidationSummary `json:"tables"`
+	models.ValidationIssue
+}
+
+// Load loads a validation report written with --format json, yaml or ndjson. The format
+// is taken from the file extension; files with any other extension are read as JSON.
+func Load(filePath string) (*models.ValidationReport, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report file: %w", err)
+	}
+
+	var report models.ValidationReport
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &report); err != nil {
+			return nil, fmt.Errorf("failed to parse report YAML: %w", err)
+		}
+	case ".ndjson", ".jsonl":
+		if err := parseNDJSON(data, &report); err != nil {
+			return nil, err
+		}
+	default:
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, fmt.Errorf("failed to parse report JSON: %w", err)
+		}
+	}
+	return &report, nil
+}
+
+// parseNDJSON reads the issue and summary lines of an NDJSON report
+func parseNDJSON(data []byte, report *models.ValidationReport) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line ndjsonLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return fmt.Errorf("failed to parse report NDJSON line %d: %w", lineNumber, err)
+		}
+		switch line.Record {
+		case "issue":
+			report.Issues = append(report.Issues, line.ValidationIssue)
+		case "summary":
+			report.ConnectionName = line.ConnectionName
+			report.Timestamp = line.Timestamp
+			report.Summary = line.Summary
+			report.Tables = line.Tables
+		case "error":
+			return fmt.Errorf("report is incomplete: the run that wrote it failed (line %d)", lineNumber)
+		}
+	}
+	return scanner.Err()
+}
+
+// issueKey identifies an issue across reports: the same check failing for the same row.
+// Issues without a row identifier are told apart by their message.
+func issueKey(issue models.ValidationIssue) string {
+	parts := []string{issue.Type, issue.Table, issue.Column, issue.Identifier, issue.PrimaryKey}
+	if issue.Identifier == "" && issue.PrimaryKey == "" {
+		parts = append(parts, issue.Message)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// Compare compares an older validation report with a newer one. Issues found in both are
+// unchanged, issues found only in the newer report are new and issues found only in the
+// older one are resolved.
+func Compare(oldFile string, oldReport *models.ValidationReport, newFile string, newReport *models.ValidationReport) *models.ReportDiff {
+	diff := &models.ReportDiff{
+		Old:             side(oldFile, oldReport),
+		New:             side(newFile, newReport),
+		NewIssues:       []models.ValidationIssue{},
+		ResolvedIssues:  []models.ValidationIssue{},
+		UnchangedIssues: []models.ValidationIssue{},
+	}
+
+	// The same key can occur more than once, so old issues are matched one by one
+	remaining := make(map[string][]models.ValidationIssue)
+	for _, issue := range oldReport.Issues {
+		key := issueKey(issue)
+		remaining[key] = append(remaining[key], issue)
+	}
+	for _, issue := range newReport.Issues {
+		key := issueKey(issue)
+		if len(remaining[key]) > 0 {
+			remaining[key] = remaining[key][1:]
+			diff.UnchangedIssues = append(diff.UnchangedIssues, issue)
+		} else {
+			diff.NewIssues = append(diff.NewIssues, issue)
+		}
+	}
+	for _, issue := range oldReport.Issues {
+		key := issueKey(issue)
+		if len(remaining[key]) > 0 {
+			remaining[key] = remaining[key][1:]
+			diff.ResolvedIssues = append(diff.ResolvedIssues, issue)
+		}
+	}
+
+	diff.ByType = countDeltas(oldReport.Issues, newReport.Issues, func(issue models.ValidationIssue) string { return issue.Type })
+	diff.ByTable = countDeltas(oldReport.Issues, newReport.Issues, func(issue models.ValidationIssue) string { return issue.Table })
+
+	oldErrors, oldWarnings := countSeverities(oldReport.Issues)
+	newErrors, newWarnings := countSeverities(newReport.Issues)
+	diff.Summary = models.ReportDiffSummary{
+		NewIssues:       len(diff.NewIssues),
+		ResolvedIssues:  len(diff.ResolvedIssues),
+		UnchangedIssues: len(diff.UnchangedIssues),
+		TotalDelta:      len(newReport.Issues) - len(oldReport.Issues),
+		ErrorDelta:      newErrors - oldErrors,
+		WarningDelta:    newWarnings - oldWarnings,
+	}
+	diff.Summary.Trend = trend(diff.Summary)
+	return diff
+}
+
+// side describes a compared report
+func side(file string, report *models.ValidationReport) models.ReportDiffSide {
+	return models.ReportDiffSide{
+		File:           file,
+		ConnectionName: report.ConnectionName,
+		Timestamp:      report.Timestamp,
+		Summary:        report.Summary,
+	}
+}
+
+// countDeltas counts the issues of both reports by the name returned by nameOf, largest
+// changes first
+func countDeltas(oldIssues, newIssues []models.ValidationIssue, nameOf func(models.ValidationIssue) string) []models.CountDelta {
+	counts := make(map[string]*models.CountDelta)
+	count := func(issue models.ValidationIssue) *models.CountDelta {
+		name := nameOf(issue)
+		delta, ok := counts[name]
+		if !ok {
+			delta = &models.CountDelta{Name: name}
+			counts[name] = delta
+		}
+		return delta
+	}
+	for _, issue := range oldIssues {
+		count(issue).Old++
+	}
+	for _, issue := range newIssues {
+		count(issue).New++
+	}
+
+	deltas := make([]models.CountDelta, 0, len(counts))
+	for _, delta := range counts {
+		delta.Delta = delta.New - delta.Old
+		deltas = append(deltas, *delta)
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		a, b := abs(deltas[i].Delta), abs(deltas[j].Delta)
+		if a != b {
+			return a > b
+		}
+		return deltas[i].Name < deltas[j].Name
+	})
+	return deltas
+}
+
+// countSeverities counts the errors and warnings among issues
+func countSeverities(issues []models.ValidationIssue) (errors, warnings int) {
+	for _, issue := range issues {
+		switch issue.Severity {
+		case "error":
+			errors++
+		case "warning":
+			warnings++
+		}
+	}
+	return errors, warnings
+}
+
+// trend tells whether things got better or worse: errors weigh more than the total number
+// of issues
+func trend(summary models.ReportDiffSummary) string {
+	switch {
+	case summary.ErrorDelta < 0:
+		return models.TrendImproving
+	case summary.ErrorDelta > 0:
+		return models.TrendWorsening
+	case summary.TotalDelta < 0:
+		return models.TrendImproving
+	case summary.TotalDelta > 0:
+		return models.TrendWorsening
+	default:
+		return models.TrendUnchanged
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
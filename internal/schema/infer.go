@@ -0,0 +1,270 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nkamuo/go-db-migration/internal/models"
+)
+
+// ForeignKeyCandidate is a possible relationship found by naming convention and type
+// compatibility, before it has been checked against the data
+type ForeignKeyCandidate struct {
+	ForeignKey models.ForeignKey
+	NameScore  float64
+	TypeScore  float64
+	Reason     string
+}
+
+// selfReferenceNames are column prefixes that conventionally point back at the same table
+var selfReferenceNames = map[string]bool{
+	"parent":   true,
+	"root":     true,
+	"ancestor": true,
+}
+
+// FindForeignKeyCandidates returns candidate foreign keys for *_id style columns that have
+// no declared constraint and whose name and type match a key column of another table
+func FindForeignKeyCandidates(schema models.Schema) []ForeignKeyCandidate {
+	tablesByName := make(map[string]*models.Table, len(schema))
+	for i := range schema {
+		tablesByName[strings.ToLower(schema[i].TableName)] = &schema[i]
+	}
+
+	var candidates []ForeignKeyCandidate
+	for i := range schema {
+		table := &schema[i]
+
+		declared := make(map[string]bool, len(table.ForeignKeys))
+		for _, fk := range table.ForeignKeys {
+			declared[fk.ColumnName] = true
+		}
+		ownKeys := make(map[string]bool)
+		for _, pk := range table.GetPrimaryKeyColumns() {
+			ownKeys[pk.ColumnName] = true
+		}
+
+		for _, column := range table.Columns {
+			if declared[column.ColumnName] || ownKeys[column.ColumnName] {
+				continue
+			}
+
+			baseName, ok := referenceBaseName(column.ColumnName)
+			if !ok {
+				continue
+			}
+
+			for _, match := range matchReferencedTables(table, baseName, tablesByName) {
+				refColumn := referencedKeyColumn(match.table, baseName)
+				if refColumn == nil || (match.table == table && refColumn.ColumnName == column.ColumnName) {
+					continue
+				}
+
+				typeScore := typeCompatibility(column, *refColumn)
+				if typeScore == 0 {
+					continue
+				}
+
+				candidates = append(candidates, ForeignKeyCandidate{
+					ForeignKey: models.ForeignKey{
+						ConstraintName:   fmt.Sprintf("%s_%s_fkey", table.TableName, column.ColumnName),
+						TableName:        table.TableName,
+						ColumnName:       column.ColumnName,
+						ReferencedTable:  match.table.TableName,
+						ReferencedColumn: refColumn.ColumnName,
+						UpdateRule:       "NO ACTION",
+						DeleteRule:       "NO ACTION",
+					},
+					NameScore: match.score,
+					TypeScore: typeScore,
+					Reason:    match.reason,
+				})
+			}
+		}
+	}
+
+	return candidates
+}
+
+// tableMatch is a table whose name matches a column's base name
+type tableMatch struct {
+	table  *models.Table
+	score  float64
+	reason string
+}
+
+// referenceBaseName strips the key suffix from a column name ("author_id" -> "author",
+// "authorId" -> "author"); ok is false for columns that do not look like references
+func referenceBaseName(columnName string) (string, bool) {
+	lower := strings.ToLower(columnName)
+	for _, suffix := range []string{"_id", "_uuid", "_guid"} {
+		if strings.HasSuffix(lower, suffix) && len(lower) > len(suffix) {
+			return lower[:len(lower)-len(suffix)], true
+		}
+	}
+
+	// camelCase: authorId
+	if strings.HasSuffix(columnName, "Id") && len(columnName) > 2 {
+		return strings.ToLower(columnName[:len(columnName)-2]), true
+	}
+
+	return "", false
+}
+
+// matchReferencedTables finds the tables a base name may refer to. The full base name is
+// preferred; otherwise trailing segments are tried ("created_by_user" -> "user").
+func matchReferencedTables(source *models.Table, baseName string, tablesByName map[string]*models.Table) []tableMatch {
+	if selfReferenceNames[baseName] {
+		return []tableMatch{{table: source, score: 0.7, reason: fmt.Sprintf("'%s' conventionally references its own table", baseName)}}
+	}
+
+	parts := strings.Split(baseName, "_")
+	for start := 0; start < len(parts); start++ {
+		name := strings.Join(parts[start:], "_")
+		if name == "" {
+			continue
+		}
+
+		score := 1.0
+		reason := fmt.Sprintf("column name matches table name '%s'", name)
+		if start > 0 {
+			score = 0.8
+			reason = fmt.Sprintf("column name ends with table name '%s'", name)
+		}
+
+		var matches []tableMatch
+		for _, tableName := range tableNameVariants(name) {
+			if table, ok := tablesByName[tableName]; ok {
+				matches = append(matches, tableMatch{table: table, score: score, reason: reason})
+			}
+		}
+		if len(matches) > 0 {
+			return matches
+		}
+	}
+
+	return nil
+}
+
+// tableNameVariants returns the singular and common plural forms of a name
+func tableNameVariants(name string) []string {
+	variants := []string{name, name + "s", name + "es"}
+	if strings.HasSuffix(name, "y") {
+		variants = append(variants, name[:len(name)-1]+"ies")
+	}
+	return variants
+}
+
+// referencedKeyColumn picks the key column of the referenced table
+func referencedKeyColumn(table *models.Table, baseName string) *models.Column {
+	for _, name := range []string{"id", baseName + "_id", table.TableName + "_id", "uuid", "guid"} {
+		if column := table.GetColumn(name); column != nil {
+			return column
+		}
+	}
+	if pkColumns := table.GetPrimaryKeyColumns(); len(pkColumns) == 1 {
+		return &pkColumns[0]
+	}
+	return nil
+}
+
+// typeFamily groups data types whose values can be compared with each other
+func typeFamily(dataType string) string {
+	dataType = strings.ToLower(dataType)
+	switch {
+	case strings.Contains(dataType, "uuid"), strings.Contains(dataType, "uniqueidentifier"):
+		return "uuid"
+	case strings.Contains(dataType, "int"), strings.Contains(dataType, "serial"):
+		return "integer"
+	case strings.Contains(dataType, "numeric"), strings.Contains(dataType, "decimal"):
+		return "numeric"
+	case strings.Contains(dataType, "char"), strings.Contains(dataType, "text"):
+		return "string"
+	default:
+		return dataType
+	}
+}
+
+// typeCompatibility scores how well two column types match: 1 for the same type,
+// 0.8 for the same family, 0.5 for integer/numeric and 0 when incompatible
+func typeCompatibility(column, referenced models.Column) float64 {
+	if strings.EqualFold(column.DataType, referenced.DataType) {
+		return 1.0
+	}
+
+	columnFamily := typeFamily(column.DataType)
+	referencedFamily := typeFamily(referenced.DataType)
+	if columnFamily == referencedFamily {
+		return 0.8
+	}
+	if (columnFamily == "integer" && referencedFamily == "numeric") ||
+		(columnFamily == "numeric" && referencedFamily == "integer") {
+		return 0.5
+	}
+	return 0
+}
+
+// NewForeignKeySuggestion scores a candidate using the share of its distinct values that
+// exist in the referenced column. Candidates without data only get a weak score.
+func NewForeignKeySuggestion(candidate ForeignKeyCandidate, distinct, matched int64) models.ForeignKeySuggestion {
+	suggestion := models.ForeignKeySuggestion{
+		ForeignKey:     candidate.ForeignKey,
+		NameScore:      candidate.NameScore,
+		TypeScore:      candidate.TypeScore,
+		DistinctValues: distinct,
+		MatchedValues:  matched,
+		Reason:         candidate.Reason,
+	}
+
+	if distinct == 0 {
+		suggestion.Confidence = 0.3 * candidate.NameScore * candidate.TypeScore
+		suggestion.Reason += "; no data to verify"
+		return suggestion
+	}
+
+	suggestion.MatchRate = float64(matched) / float64(distinct)
+	suggestion.Confidence = 0.25*candidate.NameScore + 0.15*candidate.TypeScore + 0.6*suggestion.MatchRate
+	return suggestion
+}
+
+// SortForeignKeySuggestions orders suggestions by confidence, highest first
+func SortForeignKeySuggestions(suggestions []models.ForeignKeySuggestion) {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+		if suggestions[i].ForeignKey.TableName != suggestions[j].ForeignKey.TableName {
+			return suggestions[i].ForeignKey.TableName < suggestions[j].ForeignKey.TableName
+		}
+		return suggestions[i].ForeignKey.ColumnName < suggestions[j].ForeignKey.ColumnName
+	})
+}
+
+// MergeForeignKeySuggestions adds the suggested foreign keys to the matching tables of the
+// schema, skipping columns that already declare a foreign key. It returns the number of
+// foreign keys added.
+func MergeForeignKeySuggestions(schema models.Schema, suggestions []models.ForeignKeySuggestion) int {
+	added := 0
+	for _, suggestion := range suggestions {
+		fk := suggestion.ForeignKey
+		for i := range schema {
+			if schema[i].TableName != fk.TableName {
+				continue
+			}
+
+			exists := false
+			for _, existing := range schema[i].ForeignKeys {
+				if existing.ColumnName == fk.ColumnName {
+					exists = true
+					break
+				}
+			}
+			if !exists {
+				schema[i].ForeignKeys = append(schema[i].ForeignKeys, fk)
+				added++
+			}
+		}
+	}
+	return added
+}
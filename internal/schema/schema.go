@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/nkamuo/go-db-migration/internal/config"
 	"github.com/nkamuo/go-db-migration/internal/models"
 )
 
@@ -278,7 +279,162 @@ func ValidateSchema(schema models.Schema) []models.ValidationIssue {
 				})
 			}
 		}
+
+		// Validate polymorphic relations
+		issues = append(issues, validatePolymorphicRelations(schema, table)...)
+
+		// Validate cross-connection references (the referenced side lives in another
+		// database, so only the source side can be checked here)
+		for _, ref := range table.CrossConnectionReferences {
+			if ref.Connection == "" || ref.ReferencedTable == "" || ref.ReferencedColumn == "" {
+				issues = append(issues, models.ValidationIssue{
+					Type:     "invalid_cross_connection_reference",
+					Severity: "error",
+					Table:    table.TableName,
+					Column:   ref.ColumnName,
+					Message:  fmt.Sprintf("Cross-connection reference '%s' must declare Connection, ReferencedTable and ReferencedColumn", ref.ConstraintName),
+					Details: map[string]interface{}{
+						"constraint_name": ref.ConstraintName,
+					},
+				})
+			}
+			if table.GetColumn(ref.ColumnName) == nil {
+				issues = append(issues, models.ValidationIssue{
+					Type:     "invalid_cross_connection_reference",
+					Severity: "error",
+					Table:    table.TableName,
+					Column:   ref.ColumnName,
+					Message:  fmt.Sprintf("Cross-connection reference '%s' references non-existent source column: %s", ref.ConstraintName, ref.ColumnName),
+					Details: map[string]interface{}{
+						"constraint_name":       ref.ConstraintName,
+						"referenced_connection": ref.Connection,
+					},
+				})
+			}
+		}
+	}
+
+	return issues
+}
+
+// validatePolymorphicRelations checks that polymorphic relation declarations are complete
+// and reference tables and columns defined in the schema
+func validatePolymorphicRelations(schema models.Schema, table models.Table) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+
+	for _, rel := range table.PolymorphicRelations {
+		for _, columnName := range []string{rel.TypeColumn, rel.IDColumn} {
+			if columnName == "" {
+				issues = append(issues, models.ValidationIssue{
+					Type:     "invalid_polymorphic_relation",
+					Severity: "error",
+					Table:    table.TableName,
+					Message:  fmt.Sprintf("Polymorphic relation '%s' must declare both TypeColumn and IdColumn", rel.RelationName),
+					Details: map[string]interface{}{
+						"relation_name": rel.RelationName,
+					},
+				})
+				continue
+			}
+			if table.GetColumn(columnName) == nil {
+				issues = append(issues, models.ValidationIssue{
+					Type:     "invalid_polymorphic_relation",
+					Severity: "error",
+					Table:    table.TableName,
+					Column:   columnName,
+					Message:  fmt.Sprintf("Polymorphic relation '%s' references non-existent source column: %s", rel.RelationName, columnName),
+					Details: map[string]interface{}{
+						"relation_name": rel.RelationName,
+					},
+				})
+			}
+		}
+
+		if len(rel.Targets) == 0 {
+			issues = append(issues, models.ValidationIssue{
+				Type:     "invalid_polymorphic_relation",
+				Severity: "warning",
+				Table:    table.TableName,
+				Column:   rel.TypeColumn,
+				Message:  fmt.Sprintf("Polymorphic relation '%s' does not map any type values", rel.RelationName),
+				Details: map[string]interface{}{
+					"relation_name": rel.RelationName,
+				},
+			})
+		}
+
+		typeValues := make(map[string]bool)
+		for _, target := range rel.Targets {
+			if typeValues[target.TypeValue] {
+				issues = append(issues, models.ValidationIssue{
+					Type:     "invalid_polymorphic_relation",
+					Severity: "error",
+					Table:    table.TableName,
+					Column:   rel.TypeColumn,
+					Message:  fmt.Sprintf("Polymorphic relation '%s' maps type value '%s' more than once", rel.RelationName, target.TypeValue),
+					Details: map[string]interface{}{
+						"relation_name": rel.RelationName,
+						"type_value":    target.TypeValue,
+					},
+				})
+			}
+			typeValues[target.TypeValue] = true
+
+			referencedTable := schema.GetTable(target.ReferencedTable)
+			if referencedTable == nil {
+				issues = append(issues, models.ValidationIssue{
+					Type:     "invalid_polymorphic_relation",
+					Severity: "warning",
+					Table:    table.TableName,
+					Column:   rel.IDColumn,
+					Message:  fmt.Sprintf("Polymorphic relation '%s' type '%s' references non-existent table: %s", rel.RelationName, target.TypeValue, target.ReferencedTable),
+					Details: map[string]interface{}{
+						"relation_name":    rel.RelationName,
+						"type_value":       target.TypeValue,
+						"referenced_table": target.ReferencedTable,
+					},
+				})
+			} else if referencedTable.GetColumn(target.GetReferencedColumn()) == nil {
+				issues = append(issues, models.ValidationIssue{
+					Type:     "invalid_polymorphic_relation",
+					Severity: "warning",
+					Table:    table.TableName,
+					Column:   rel.IDColumn,
+					Message:  fmt.Sprintf("Polymorphic relation '%s' type '%s' references non-existent column: %s.%s", rel.RelationName, target.TypeValue, target.ReferencedTable, target.GetReferencedColumn()),
+					Details: map[string]interface{}{
+						"relation_name":     rel.RelationName,
+						"type_value":        target.TypeValue,
+						"referenced_table":  target.ReferencedTable,
+						"referenced_column": target.GetReferencedColumn(),
+					},
+				})
+			}
+		}
 	}
 
 	return issues
 }
+
+// ApplyRowFilters merges the configured row filters into the schema tables.
+// A filter configured for a table that already declares a RowFilter in the
+// schema file is combined with it, so both predicates must match.
+func ApplyRowFilters(schema models.Schema, filters []config.RowFilter) error {
+	for _, filter := range filters {
+		found := false
+		for i := range schema {
+			if schema[i].TableName != filter.Table {
+				continue
+			}
+			found = true
+			if schema[i].RowFilter == "" {
+				schema[i].RowFilter = filter.Where
+			} else {
+				schema[i].RowFilter = fmt.Sprintf("(%s) AND (%s)", schema[i].RowFilter, filter.Where)
+			}
+		}
+		if !found {
+			return fmt.Errorf("row filter references table '%s' which is not in the schema", filter.Table)
+		}
+	}
+	return nil
+}